@@ -3,8 +3,8 @@ package config
 import (
 	"fmt"
 	"log"
-	"otp-backend/models"
 
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -48,10 +48,35 @@ func ConnectDatabase() {
 	log.Println("Database connected successfully!")
 
 	// Auto migrate models
-	err = DB.AutoMigrate(&models.OTP{}, &models.User{})
+	err = Migrate(DB)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
 	log.Println("Database migration completed!")
 }
+
+// Migrate creates or updates the tables for all models
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&models.OTP{},
+		&models.User{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.Authenticator{},
+		&models.RecoveryCode{},
+		&models.RecoveryAttempt{},
+		&models.OAuthClient{},
+		&models.AuthorizationRequest{},
+		&models.AuthorizationCode{},
+		&models.TrustedDevice{},
+		&models.RiskDecisionLog{},
+		&models.PushDevice{},
+		&models.PushChallenge{},
+		&models.Passkey{},
+		&models.WebAuthnCeremony{},
+		&models.IdempotencyRecord{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+	)
+}
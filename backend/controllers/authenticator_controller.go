@@ -0,0 +1,436 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Failed authenticator codes allowed before the factor is locked
+const (
+	maxAuthenticatorFailures = 5
+	authenticatorLockout     = 15 * time.Minute
+)
+
+// EnrollAuthenticatorRequest represents the request body for enrolling an authenticator app
+type EnrollAuthenticatorRequest struct {
+	Type string `json:"type" binding:"omitempty,oneof=totp hotp"`
+}
+
+// AuthenticatorCodeRequest represents a request carrying one authenticator code
+type AuthenticatorCodeRequest struct {
+	Code string `json:"code" binding:"required,numeric,min=6,max=8"`
+}
+
+// VerifyAuthenticatorRequest represents the request body for verifying an authenticator code
+type VerifyAuthenticatorRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Code   string `json:"code" binding:"required,numeric,min=6,max=8"`
+}
+
+// ResyncHOTPRequest carries two consecutive codes from an HOTP token
+type ResyncHOTPRequest struct {
+	Code1 string `json:"code1" binding:"required,numeric,min=6,max=8"`
+	Code2 string `json:"code2" binding:"required,numeric,min=6,max=8"`
+}
+
+// EnrollAuthenticator starts enrollment of an authenticator app for the
+// authenticated user and returns the secret as text, URI and QR code
+func EnrollAuthenticator(c *gin.Context) {
+	var req EnrollAuthenticatorRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if req.Type == "" {
+		req.Type = "totp"
+	}
+
+	userID := c.GetString("user_id")
+	var user models.User
+	if err := config.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	var existing models.Authenticator
+	if config.DB.Where("user_id = ?", userID).First(&existing).Error == nil {
+		if existing.ConfirmedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "An authenticator is already enrolled. Remove it first",
+			})
+			return
+		}
+		// Restarting enrollment replaces the unconfirmed secret
+		config.DB.Delete(&existing)
+	}
+
+	secret, err := utils.GenerateAuthenticatorSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to generate secret",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	cfg := utils.GetAuthenticatorConfig()
+	authenticator := models.Authenticator{
+		ID:     uuid.New().String(),
+		UserID: userID,
+		Type:   req.Type,
+		Secret: secret,
+		Digits: cfg.Digits,
+		Period: cfg.Period,
+	}
+
+	if err := config.DB.Create(&authenticator).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to save authenticator",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	uri := authenticatorURI(&authenticator, &user, cfg)
+	png, err := utils.QRCodePNG(uri, 256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to render QR code",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("🔑 Authenticator enrollment started for user %s (%s)\n", userID, req.Type)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Scan the QR code and confirm with a code from your app",
+		"data": gin.H{
+			"id":          authenticator.ID,
+			"type":        authenticator.Type,
+			"secret":      secret,
+			"otpauth_uri": uri,
+			"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		},
+	})
+}
+
+// GetAuthenticatorQR renders the pending enrollment as a PNG QR code.
+// Confirmed secrets are never shown again.
+func GetAuthenticatorQR(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var authenticator models.Authenticator
+	if err := config.DB.Where("user_id = ? AND confirmed_at IS NULL", userID).First(&authenticator).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "No pending authenticator enrollment",
+		})
+		return
+	}
+
+	var user models.User
+	config.DB.Where("id = ?", userID).First(&user)
+
+	png, err := utils.QRCodePNG(authenticatorURI(&authenticator, &user, utils.GetAuthenticatorConfig()), 256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to render QR code",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// ConfirmAuthenticator completes enrollment with a code from the app
+func ConfirmAuthenticator(c *gin.Context) {
+	var req AuthenticatorCodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var authenticator models.Authenticator
+	if err := config.DB.Where("user_id = ? AND confirmed_at IS NULL", c.GetString("user_id")).First(&authenticator).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "No pending authenticator enrollment",
+		})
+		return
+	}
+
+	if ok, message := checkAuthenticatorCode(&authenticator, req.Code); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": message,
+		})
+		return
+	}
+
+	now := time.Now()
+	authenticator.ConfirmedAt = &now
+	config.DB.Model(&models.Authenticator{}).
+		Where("id = ? AND confirmed_at IS NULL", authenticator.ID).
+		Update("confirmed_at", now)
+
+	fmt.Printf("✅ Authenticator confirmed for user %s\n", authenticator.UserID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Authenticator enrolled successfully",
+		"data":    authenticator,
+	})
+}
+
+// VerifyAuthenticator checks a code from an enrolled authenticator app and
+// issues a signed verification token
+func VerifyAuthenticator(c *gin.Context) {
+	var req VerifyAuthenticatorRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var authenticator models.Authenticator
+	if err := config.DB.Where("user_id = ? AND confirmed_at IS NOT NULL", req.UserID).First(&authenticator).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "No authenticator enrolled",
+		})
+		return
+	}
+
+	if ok, message := checkAuthenticatorCode(&authenticator, req.Code); !ok {
+		fmt.Printf("❌ Authenticator verification failed for user %s: %s\n", req.UserID, message)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": message,
+		})
+		return
+	}
+
+	now := time.Now()
+	responseData := gin.H{
+		"verified":  true,
+		"user_id":   req.UserID,
+		"timestamp": now,
+	}
+
+	addVerificationToken(responseData, req.UserID, utils.VerificationClaims{
+		Channel: authenticator.Type,
+		Purpose: engine.PurposeLogin,
+	})
+
+	fmt.Printf("✅ Authenticator code verified for user %s\n", req.UserID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Authenticator code verified successfully",
+		"data":    responseData,
+	})
+}
+
+// ResyncHOTP realigns a drifted HOTP counter using two consecutive codes
+func ResyncHOTP(c *gin.Context) {
+	var req ResyncHOTPRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var authenticator models.Authenticator
+	if err := config.DB.Where("user_id = ? AND type = ?", c.GetString("user_id"), "hotp").First(&authenticator).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "No HOTP authenticator enrolled",
+		})
+		return
+	}
+
+	// A resync searches a wide window, so failures count toward the same
+	// lockout as wrong codes
+	now := time.Now()
+	if authenticatorLocked(&authenticator, now) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Too many failed attempts. Please try again later",
+		})
+		return
+	}
+
+	secret, err := utils.DecodeAuthenticatorSecret(authenticator.Secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Stored secret is invalid",
+		})
+		return
+	}
+
+	cfg := utils.GetAuthenticatorConfig()
+	counter, ok := utils.ResyncHOTP(secret, req.Code1, req.Code2, authenticator.Counter, authenticator.Digits, cfg.ResyncWindow)
+	if ok {
+		ok = config.DB.Model(&models.Authenticator{}).
+			Where("id = ? AND counter = ?", authenticator.ID, authenticator.Counter).
+			Updates(map[string]interface{}{
+				"counter":         counter,
+				"failed_attempts": 0,
+				"locked_until":    nil,
+			}).RowsAffected == 1
+	}
+	if !ok {
+		recordAuthenticatorFailure(&authenticator, now)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Codes could not be matched. Generate two new consecutive codes and try again",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "HOTP counter resynchronized",
+	})
+}
+
+// RemoveAuthenticator deletes the authenticated user's authenticator
+func RemoveAuthenticator(c *gin.Context) {
+	result := config.DB.Where("user_id = ?", c.GetString("user_id")).Delete(&models.Authenticator{})
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "No authenticator enrolled",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Authenticator removed",
+	})
+}
+
+// checkAuthenticatorCode validates a code, advancing the HOTP counter or
+// recording the used TOTP step so the same code cannot be replayed.
+// Failures are counted and lock the factor temporarily.
+func checkAuthenticatorCode(a *models.Authenticator, code string) (bool, string) {
+	now := time.Now()
+	if authenticatorLocked(a, now) {
+		return false, "Too many failed attempts. Please try again later"
+	}
+
+	secret, err := utils.DecodeAuthenticatorSecret(a.Secret)
+	if err != nil {
+		return false, "Stored secret is invalid"
+	}
+
+	cfg := utils.GetAuthenticatorConfig()
+	cfg.Digits = a.Digits
+	cfg.Period = a.Period
+
+	// The conditional updates make concurrent use of the same code fail
+	var ok bool
+	if a.Type == "hotp" {
+		var counter uint64
+		counter, ok = utils.MatchHOTP(secret, code, a.Counter, a.Digits, cfg.HOTPLookahead)
+		if ok {
+			ok = config.DB.Model(&models.Authenticator{}).
+				Where("id = ? AND counter = ?", a.ID, a.Counter).
+				Update("counter", counter).RowsAffected == 1
+			a.Counter = counter
+		}
+	} else {
+		var step int64
+		step, ok = utils.MatchTOTP(secret, code, now, cfg, a.LastUsedStep)
+		if ok {
+			ok = config.DB.Model(&models.Authenticator{}).
+				Where("id = ? AND last_used_step < ?", a.ID, step).
+				Update("last_used_step", step).RowsAffected == 1
+			a.LastUsedStep = step
+		}
+	}
+
+	if !ok {
+		recordAuthenticatorFailure(a, now)
+		return false, "Invalid authenticator code"
+	}
+
+	a.FailedAttempts = 0
+	a.LockedUntil = nil
+	config.DB.Model(&models.Authenticator{}).Where("id = ?", a.ID).Updates(map[string]interface{}{
+		"failed_attempts": 0,
+		"locked_until":    nil,
+	})
+	return true, ""
+}
+
+func authenticatorLocked(a *models.Authenticator, now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+// recordAuthenticatorFailure counts a failed code or resync and locks the
+// factor once there are too many. Only the failure columns are written,
+// in place, so concurrent requests neither lose failures nor undo a
+// counter update.
+func recordAuthenticatorFailure(a *models.Authenticator, now time.Time) {
+	config.DB.Model(&models.Authenticator{}).Where("id = ?", a.ID).
+		Update("failed_attempts", gorm.Expr("failed_attempts + 1"))
+
+	lockedUntil := now.Add(authenticatorLockout)
+	config.DB.Model(&models.Authenticator{}).
+		Where("id = ? AND failed_attempts >= ?", a.ID, maxAuthenticatorFailures).
+		Updates(map[string]interface{}{
+			"failed_attempts": 0,
+			"locked_until":    lockedUntil,
+		})
+}
+
+func authenticatorURI(a *models.Authenticator, user *models.User, cfg *utils.AuthenticatorConfig) string {
+	account := user.Email
+	if account == "" {
+		account = user.Phone
+	}
+	return utils.OTPAuthURI(a.Type, cfg.Issuer, account, a.Secret, a.Digits, a.Period, a.Counter)
+}
@@ -0,0 +1,117 @@
+package controllers_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// enrollHOTP enrolls and confirms an HOTP authenticator and returns its
+// secret. Confirming uses the code for counter 0.
+func enrollHOTP(t *testing.T, r *gin.Engine, token string) []byte {
+	t.Helper()
+	status, out := doJSON(t, r, http.MethodPost, "/api/auth/totp/enroll", map[string]string{"type": "hotp"}, bearer(token))
+	if status != http.StatusOK {
+		t.Fatalf("enroll: %d %v", status, out)
+	}
+	secret, err := utils.DecodeAuthenticatorSecret(data(t, out)["secret"].(string))
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	status, out = doJSON(t, r, http.MethodPost, "/api/auth/totp/confirm", map[string]string{"code": utils.HOTP(secret, 0, 6)}, bearer(token))
+	if status != http.StatusOK {
+		t.Fatalf("confirm: %d %v", status, out)
+	}
+	return secret
+}
+
+func authenticatorFor(userID string) models.Authenticator {
+	var authenticator models.Authenticator
+	config.DB.Where("user_id = ?", userID).First(&authenticator)
+	return authenticator
+}
+
+func TestFailedResyncsLockTheAuthenticator(t *testing.T) {
+	r := newTestRouter(t)
+	token, userID := login(t, r, "hotp@example.com")
+	secret := enrollHOTP(t, r, token)
+
+	for i := 0; i < 5; i++ {
+		if status, out := doJSON(t, r, http.MethodPost, "/api/auth/totp/resync", map[string]string{"code1": "000000", "code2": "000000"}, bearer(token)); status != http.StatusBadRequest {
+			t.Fatalf("wrong resync %d: %d %v", i+1, status, out)
+		}
+	}
+	if authenticatorFor(userID).LockedUntil == nil {
+		t.Fatal("authenticator not locked after 5 failed resyncs")
+	}
+
+	// Neither a correct resync nor a correct code gets past the lock
+	status, out := doJSON(t, r, http.MethodPost, "/api/auth/totp/resync", map[string]string{
+		"code1": utils.HOTP(secret, 20, 6),
+		"code2": utils.HOTP(secret, 21, 6),
+	}, bearer(token))
+	if status != http.StatusBadRequest || out["message"] != "Too many failed attempts. Please try again later" {
+		t.Errorf("resync while locked: %d %v", status, out)
+	}
+	status, out = doJSON(t, r, http.MethodPost, "/api/auth/totp/verify", map[string]string{"user_id": userID, "code": utils.HOTP(secret, 1, 6)}, nil)
+	if status != http.StatusBadRequest {
+		t.Errorf("code while locked: %d %v", status, out)
+	}
+	if counter := authenticatorFor(userID).Counter; counter != 1 {
+		t.Errorf("counter %d, want 1", counter)
+	}
+}
+
+func TestResyncAdvancesCounter(t *testing.T) {
+	r := newTestRouter(t)
+	token, userID := login(t, r, "drift@example.com")
+	secret := enrollHOTP(t, r, token)
+
+	doJSON(t, r, http.MethodPost, "/api/auth/totp/verify", map[string]string{"user_id": userID, "code": "000000"}, nil)
+	status, out := doJSON(t, r, http.MethodPost, "/api/auth/totp/resync", map[string]string{
+		"code1": utils.HOTP(secret, 40, 6),
+		"code2": utils.HOTP(secret, 41, 6),
+	}, bearer(token))
+	if status != http.StatusOK {
+		t.Fatalf("resync: %d %v", status, out)
+	}
+
+	if authenticator := authenticatorFor(userID); authenticator.Counter != 42 || authenticator.FailedAttempts != 0 {
+		t.Errorf("counter %d with %d failures, want 42 and 0", authenticator.Counter, authenticator.FailedAttempts)
+	}
+}
+
+// Failures are counted in place, so concurrent wrong codes are all counted
+// and do not undo a counter advanced by a correct code
+func TestConcurrentAuthenticatorFailures(t *testing.T) {
+	r := newTestRouter(t)
+	token, userID := login(t, r, "race@example.com")
+	secret := enrollHOTP(t, r, token)
+
+	verify := func(codes ...string) {
+		var wg sync.WaitGroup
+		for _, code := range codes {
+			wg.Add(1)
+			go func(code string) {
+				defer wg.Done()
+				doJSON(t, r, http.MethodPost, "/api/auth/totp/verify", map[string]string{"user_id": userID, "code": code}, nil)
+			}(code)
+		}
+		wg.Wait()
+	}
+
+	verify("000000", "000000", "000000")
+	if failures := authenticatorFor(userID).FailedAttempts; failures != 3 {
+		t.Errorf("%d failures recorded, want 3", failures)
+	}
+
+	verify(utils.HOTP(secret, 1, 6), "000000")
+	if counter := authenticatorFor(userID).Counter; counter != 2 {
+		t.Errorf("counter %d, want 2", counter)
+	}
+}
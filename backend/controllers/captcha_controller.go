@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	captchaConfig     = &utils.CaptchaConfig{Provider: "none", Mode: "off"}
+	challengeVerifier utils.ChallengeVerifier
+)
+
+// InitBotProtection loads the CAPTCHA configuration and builds the verifier
+func InitBotProtection() {
+	cfg := utils.GetCaptchaConfig()
+
+	verifier, err := utils.NewChallengeVerifier(cfg)
+	if err != nil {
+		log.Fatal("Failed to configure bot protection:", err)
+	}
+
+	SetChallengeVerifier(cfg, verifier)
+
+	if cfg.Enabled() {
+		fmt.Printf("✅ Bot protection enabled (provider: %s, mode: %s)\n", cfg.Provider, cfg.Mode)
+	} else {
+		fmt.Println("⚠️  Bot protection disabled")
+	}
+}
+
+// SetChallengeVerifier replaces the active verifier, e.g. with a
+// utils.FakeChallengeVerifier in tests
+func SetChallengeVerifier(cfg *utils.CaptchaConfig, verifier utils.ChallengeVerifier) {
+	captchaConfig = cfg
+	challengeVerifier = verifier
+}
+
+// ChallengeResponse is the data returned by GetChallenge. Provider "none"
+// means no challenge is needed.
+type ChallengeResponse struct {
+	Provider  string              `json:"provider"`
+	Mode      string              `json:"mode,omitempty"`
+	Challenge *utils.PowChallenge `json:"challenge,omitempty"`
+	SiteKey   string              `json:"site_key,omitempty"`
+}
+
+// GetChallenge tells the client which challenge to solve before generating an OTP
+func GetChallenge(c *gin.Context) {
+	if !captchaConfig.Enabled() || challengeVerifier == nil {
+		respond(c, "", ChallengeResponse{Provider: "none"})
+		return
+	}
+
+	data := ChallengeResponse{
+		Provider: challengeVerifier.Provider(),
+		Mode:     captchaConfig.Mode,
+	}
+
+	if pow, ok := challengeVerifier.(*utils.ProofOfWorkVerifier); ok {
+		challenge, err := pow.NewChallenge()
+		if err != nil {
+			respondInternalError(c, "Failed to create challenge", err)
+			return
+		}
+		data.Challenge = challenge
+	} else if captchaConfig.SiteKey != "" {
+		data.SiteKey = captchaConfig.SiteKey
+	}
+
+	respond(c, "", data)
+}
+
+// challengeRequired decides whether the request must carry a solved challenge
+func challengeRequired(client ClientInfo, email, phone string) bool {
+	if !captchaConfig.Enabled() || challengeVerifier == nil {
+		return false
+	}
+	if captchaConfig.Mode == "always" {
+		return true
+	}
+
+	// Risk signals: no user agent, or repeated requests from the same
+	// identifier or client IP within the last hour
+	if client.UserAgent == "" {
+		return true
+	}
+
+	if recentOTPCount(email, phone) >= int64(captchaConfig.RiskThreshold) {
+		return true
+	}
+
+	oneHourAgo := time.Now().Add(-1 * time.Hour)
+	var ipCount int64
+	config.DB.Model(&models.OTP{}).
+		Where("created_at > ? AND client_ip = ?", oneHourAgo, client.IP).
+		Count(&ipCount)
+
+	return ipCount >= int64(captchaConfig.RiskThreshold)
+}
+
+// checkChallenge verifies the CAPTCHA token when one is required
+func checkChallenge(client ClientInfo, email, phone, token string) *OTPError {
+	if !challengeRequired(client, email, phone) {
+		return nil
+	}
+
+	if token == "" {
+		fmt.Printf("🤖 Challenge required for %s%s (IP %s)\n", email, phone, client.IP)
+		return otpError(http.StatusForbidden, engine.ErrorCaptchaRequired, "Please complete the verification challenge", gin.H{
+			"captcha_required": true,
+			"captcha_provider": challengeVerifier.Provider(),
+		})
+	}
+
+	if err := challengeVerifier.Verify(token, client.IP); err != nil {
+		fmt.Printf("🤖 Challenge failed for %s%s: %v\n", email, phone, err)
+		return otpError(http.StatusForbidden, engine.ErrorCaptchaFailed, "Verification challenge failed", gin.H{
+			"captcha_required": true,
+			"captcha_provider": challengeVerifier.Provider(),
+		})
+	}
+
+	return nil
+}
+
+// enforceChallenge verifies the CAPTCHA token when one is required.
+// It writes the error response and returns false if the request must stop.
+func enforceChallenge(c *gin.Context, email, phone, token string) bool {
+	if err := checkChallenge(clientInfo(c), email, phone, token); err != nil {
+		respondOTPError(c, err)
+		return false
+	}
+	return true
+}
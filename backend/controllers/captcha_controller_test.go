@@ -0,0 +1,94 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+)
+
+// useVerifier installs a challenge verifier for the rest of the test
+func useVerifier(t *testing.T, cfg *utils.CaptchaConfig, verifier utils.ChallengeVerifier) {
+	t.Helper()
+	controllers.SetChallengeVerifier(cfg, verifier)
+	t.Cleanup(func() { controllers.SetChallengeVerifier(&utils.CaptchaConfig{Provider: "none", Mode: "off"}, nil) })
+}
+
+// errorCode returns the code of a versioned error response
+func errorCode(out map[string]interface{}) string {
+	e, _ := out["error"].(map[string]interface{})
+	code, _ := e["code"].(string)
+	return code
+}
+
+func TestGenerateEnforcesChallenge(t *testing.T) {
+	r := newTestRouter(t)
+	fake := &utils.FakeChallengeVerifier{AcceptToken: "pass"}
+	useVerifier(t, &utils.CaptchaConfig{Provider: "fake", Mode: "always"}, fake)
+
+	for _, tc := range []struct {
+		token  string
+		status int
+		code   string
+	}{
+		{"", http.StatusForbidden, "CAPTCHA_REQUIRED"},
+		{"wrong", http.StatusForbidden, "CAPTCHA_FAILED"},
+		{"pass", http.StatusOK, ""},
+	} {
+		status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "bot@example.com", "captcha_token": tc.token}, nil)
+		if status != tc.status || errorCode(out) != tc.code {
+			t.Errorf("token %q: %d %s, want %d %s", tc.token, status, errorCode(out), tc.status, tc.code)
+		}
+	}
+	if fake.Calls() != 2 {
+		t.Errorf("verifier called %d times, want 2", fake.Calls())
+	}
+}
+
+func TestRiskModeChallengesRepeatRequests(t *testing.T) {
+	r := newTestRouter(t)
+	useVerifier(t, &utils.CaptchaConfig{Provider: "fake", Mode: "risk", RiskThreshold: 1}, &utils.FakeChallengeVerifier{AcceptToken: "pass"})
+
+	status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "user@example.com"}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("first request: %d %v", status, out)
+	}
+	otpID := data(t, out)["otp_id"].(string)
+
+	// Resend counts as a repeat request for the same address
+	status, out = doJSON(t, r, http.MethodPost, "/api/v1/otp/resend", map[string]string{"otp_id": otpID}, nil)
+	if status != http.StatusForbidden || errorCode(out) != "CAPTCHA_REQUIRED" {
+		t.Errorf("resend without a token: %d %v", status, out)
+	}
+
+	// So does a request for another address from the same client IP
+	status, out = doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "other@example.com"}, nil)
+	if status != http.StatusForbidden || errorCode(out) != "CAPTCHA_REQUIRED" {
+		t.Errorf("second address without a token: %d %v", status, out)
+	}
+
+	status, out = doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "fresh@example.com"}, map[string]string{"X-Forwarded-For": "203.0.113.7"})
+	if status != http.StatusOK {
+		t.Errorf("new client: %d %v", status, out)
+	}
+}
+
+func TestProofOfWorkChallengeIsSingleUse(t *testing.T) {
+	r := newTestRouter(t)
+	useVerifier(t, &utils.CaptchaConfig{Provider: "pow", Mode: "always"}, utils.NewProofOfWorkVerifier([]byte("test-key"), 8))
+
+	status, out := doJSON(t, r, http.MethodGet, "/api/v1/otp/challenge", nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("challenge: %d %v", status, out)
+	}
+	challenge := data(t, out)["challenge"].(map[string]interface{})
+	token := utils.SolveProofOfWork(challenge["challenge"].(string), int(challenge["difficulty"].(float64)))
+
+	for i, want := range []int{http.StatusOK, http.StatusForbidden} {
+		status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "pow@example.com", "captcha_token": token}, nil)
+		if status != want {
+			t.Errorf("use %d: %d %v, want %d", i+1, status, out, want)
+		}
+	}
+}
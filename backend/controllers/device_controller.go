@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/securetoken"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RememberDeviceOptions can be sent with a verification to trust the device
+type RememberDeviceOptions struct {
+	RememberDevice bool   `json:"remember_device"`
+	DeviceName     string `json:"device_name" binding:"omitempty,max=255"`
+}
+
+// CheckDeviceRequest represents the request body for checking a device token
+type CheckDeviceRequest struct {
+	DeviceToken string `json:"device_token" binding:"required"`
+	// Email or Phone, when given, must belong to the device's user
+	Email string `json:"email" binding:"omitempty,email"`
+	Phone string `json:"phone" binding:"omitempty,min=10,max=15"`
+}
+
+// TrustedDeviceToken is returned when a device is remembered
+type TrustedDeviceToken struct {
+	DeviceID    string    `json:"device_id"`
+	DeviceToken string    `json:"device_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// addTrustedDevice mints a device token when the client asked to remember
+// the device and adds it to the response data
+func addTrustedDevice(c *gin.Context, data gin.H, userID string, opts RememberDeviceOptions) {
+	if device := trustDevice(clientInfo(c), userID, opts); device != nil {
+		data["device"] = device
+	}
+}
+
+// trustDevice mints a device token when the client asked to remember the
+// device. Failures are logged and nil returned.
+func trustDevice(client ClientInfo, userID string, opts RememberDeviceOptions) *TrustedDeviceToken {
+	if !opts.RememberDevice {
+		return nil
+	}
+
+	cfg := utils.GetDeviceConfig()
+	token, err := securetoken.New(32)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to create device token: %v\n", err)
+		return nil
+	}
+
+	name := opts.DeviceName
+	if name == "" {
+		name = client.UserAgent
+	}
+
+	now := time.Now()
+	device := models.TrustedDevice{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		TokenHash:   securetoken.Hash(token),
+		Fingerprint: utils.DeviceFingerprint(client.UserAgent),
+		Name:        models.Truncate(name, 255),
+		ClientIP:    client.IP,
+		LastUsedAt:  now,
+		ExpiresAt:   now.Add(cfg.TTL),
+	}
+	if err := config.DB.Create(&device).Error; err != nil {
+		fmt.Printf("⚠️  Failed to store trusted device: %v\n", err)
+		return nil
+	}
+
+	// Keep only the most recent devices
+	var stale []string
+	config.DB.Model(&models.TrustedDevice{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Offset(cfg.MaxPerUser).
+		Pluck("id", &stale)
+	if len(stale) > 0 {
+		config.DB.Model(&models.TrustedDevice{}).Where("id IN ?", stale).Update("revoked_at", now)
+	}
+
+	fmt.Printf("💻 Device %s trusted for user %s until %s\n", device.ID, userID, device.ExpiresAt.Format("2006-01-02"))
+
+	return &TrustedDeviceToken{
+		DeviceID:    device.ID,
+		DeviceToken: token,
+		ExpiresAt:   device.ExpiresAt,
+	}
+}
+
+// CheckTrustedDevice lets a client skip OTP on a remembered device. A valid
+// token presented from the same user agent starts a new session.
+func CheckTrustedDevice(c *gin.Context) {
+	var req CheckDeviceRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	untrusted := gin.H{
+		"success": false,
+		"message": "Device is not trusted. Please verify with an OTP",
+		"data": gin.H{
+			"trusted": false,
+		},
+	}
+
+	cfg := utils.GetDeviceConfig()
+	now := time.Now()
+
+	var device models.TrustedDevice
+	if err := config.DB.Where("token_hash = ?", securetoken.Hash(req.DeviceToken)).First(&device).Error; err != nil ||
+		!device.IsActive(now, cfg.IdleTTL) {
+		c.JSON(http.StatusUnauthorized, untrusted)
+		return
+	}
+
+	// A token presented from a different browser was copied; stop trusting it
+	userAgent := c.GetHeader("User-Agent")
+	if !utils.DeviceFingerprintMatches(device.Fingerprint, userAgent) {
+		fmt.Printf("🚨 Device token %s presented from a different browser, revoking\n", device.ID)
+		config.DB.Model(&device).Update("revoked_at", now)
+		c.JSON(http.StatusUnauthorized, untrusted)
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("id = ?", device.UserID).First(&user).Error; err != nil ||
+		(req.Email != "" && req.Email != user.Email) ||
+		(req.Phone != "" && req.Phone != user.Phone) {
+		c.JSON(http.StatusUnauthorized, untrusted)
+		return
+	}
+
+	config.DB.Model(&device).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"fingerprint":  utils.DeviceFingerprint(userAgent),
+	})
+
+	session, err := createSession(clientInfo(c), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to create session",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("💻 Trusted device %s used by user %s\n", device.ID, user.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Device is trusted",
+		"data": gin.H{
+			"trusted":   true,
+			"device_id": device.ID,
+			"user":      user,
+			"session":   session,
+		},
+	})
+}
+
+// ListTrustedDevices returns the authenticated user's trusted devices
+func ListTrustedDevices(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cfg := utils.GetDeviceConfig()
+	now := time.Now()
+
+	var devices []models.TrustedDevice
+	config.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&devices)
+
+	list := make([]models.TrustedDevice, 0, len(devices))
+	for _, d := range devices {
+		if d.IsActive(now, cfg.IdleTTL) {
+			list = append(list, d)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+	})
+}
+
+// RevokeTrustedDevice stops trusting one of the authenticated user's devices
+func RevokeTrustedDevice(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result := config.DB.Model(&models.TrustedDevice{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), userID).
+		Update("revoked_at", time.Now())
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Device not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Device revoked",
+	})
+}
+
+// RevokeAllTrustedDevices stops trusting every device of the authenticated user
+func RevokeAllTrustedDevices(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result := config.DB.Model(&models.TrustedDevice{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("%d devices revoked", result.RowsAffected),
+	})
+}
@@ -0,0 +1,62 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	chrome129 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36"
+	chrome130 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.6723.59 Safari/537.36"
+	firefox   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0"
+)
+
+// rememberDevice signs up from userAgent with remember_device set and
+// returns the device token
+func rememberDevice(t *testing.T, r *gin.Engine, email, userAgent string) string {
+	t.Helper()
+	headers := map[string]string{"User-Agent": userAgent}
+	_, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": email, "purpose": "signup"}, headers)
+	issued := data(t, out)
+	status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/verify", map[string]interface{}{
+		"otp_id":          issued["otp_id"],
+		"otp_code":        issued["otp_code"],
+		"purpose":         "signup",
+		"remember_device": true,
+	}, headers)
+	device, ok := data(t, out)["device"].(map[string]interface{})
+	if status != http.StatusOK || !ok {
+		t.Fatalf("verify: %d %v", status, out)
+	}
+	return device["device_token"].(string)
+}
+
+func checkDevice(t *testing.T, r *gin.Engine, token, userAgent string) int {
+	t.Helper()
+	status, _ := doJSON(t, r, http.MethodPost, "/api/auth/devices/check", map[string]string{"device_token": token}, map[string]string{"User-Agent": userAgent})
+	return status
+}
+
+func TestTrustedDeviceSurvivesBrowserUpdate(t *testing.T) {
+	r := newTestRouter(t)
+	token := rememberDevice(t, r, "device@example.com", chrome129)
+
+	if status := checkDevice(t, r, token, chrome130); status != http.StatusOK {
+		t.Errorf("check after a browser update: %d", status)
+	}
+}
+
+func TestTrustedDeviceRevokedInAnotherBrowser(t *testing.T) {
+	r := newTestRouter(t)
+	token := rememberDevice(t, r, "copied@example.com", chrome129)
+
+	if status := checkDevice(t, r, token, firefox); status != http.StatusUnauthorized {
+		t.Errorf("check from another browser: %d", status)
+	}
+	// The copied token is revoked, so it no longer works anywhere
+	if status := checkDevice(t, r, token, chrome129); status != http.StatusUnauthorized {
+		t.Errorf("check after revocation: %d", status)
+	}
+}
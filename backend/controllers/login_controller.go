@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// loginStartedMessage is returned whether or not the account exists so
+// the login endpoint cannot be used to discover registered users
+const loginStartedMessage = "If an account exists for this address, a login code has been sent"
+
+// invalidLoginCodeMessage is returned for every failed completion
+const invalidLoginCodeMessage = "Invalid or expired code"
+
+// StartAuthRequest represents the request body for starting a login or signup
+type StartAuthRequest struct {
+	Email        string `json:"email" binding:"omitempty,email"`
+	Phone        string `json:"phone" binding:"omitempty,min=10,max=15"`
+	CaptchaToken string `json:"captcha_token"`
+}
+
+// CompleteLoginRequest represents the request body for completing a login
+type CompleteLoginRequest struct {
+	LoginID string `json:"login_id" binding:"required"`
+	Code    string `json:"code" binding:"required,min=4,max=32"`
+	RememberDeviceOptions
+}
+
+// CompleteSignupRequest represents the request body for completing a signup
+type CompleteSignupRequest struct {
+	SignupID string `json:"signup_id" binding:"required"`
+	Code     string `json:"code" binding:"required,min=4,max=32"`
+	RememberDeviceOptions
+}
+
+// StartLogin sends a login code to an existing user. Unknown addresses get
+// the same response and a decoy OTP that can never succeed, so bot checks
+// and rate limits count them alike. Codes are sent in the background, so
+// the response time does not depend on the SMS or email provider either.
+func StartLogin(c *gin.Context) {
+	var req StartAuthRequest
+	if !bindStartAuthRequest(c, &req) {
+		return
+	}
+
+	if !enforceChallenge(c, req.Email, req.Phone, req.CaptchaToken) {
+		return
+	}
+
+	policy, _ := utils.GetOTPPolicy(engine.PurposeLogin)
+	loginID := uuid.New().String()
+	expiresAt := time.Now().Add(policy.Expiry)
+
+	var user models.User
+	query := config.DB
+	if req.Email != "" {
+		query = query.Where("email = ? AND is_email_verified = ?", req.Email, true)
+	} else {
+		query = query.Where("phone = ? AND is_phone_verified = ?", req.Phone, true)
+	}
+	userExists := query.First(&user).Error == nil
+
+	if !userExists {
+		fmt.Printf("🔒 Login requested for unknown account %s%s - nothing sent\n", req.Email, req.Phone)
+	}
+
+	issued, err := otpEngine().Generate(c.Request.Context(), engine.GenerateRequest{
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Purpose:  engine.PurposeLogin,
+		ClientIP: c.ClientIP(),
+		Async:    true,
+		Decoy:    !userExists,
+	})
+	if err != nil {
+		// Rate limits are not revealed either
+		fmt.Printf("🔒 Login code for %s%s not sent: %v\n", req.Email, req.Phone, err)
+	} else {
+		loginID = issued.OTP.ID
+		expiresAt = issued.OTP.ExpiresAt
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": loginStartedMessage,
+		"data": gin.H{
+			"login_id":    loginID,
+			"expires_at":  expiresAt,
+			"code_format": codeFormat(policy),
+		},
+	})
+}
+
+// CompleteLogin verifies a login code and starts a session. It never
+// creates users; every failure returns the same response.
+func CompleteLogin(c *gin.Context) {
+	var req CompleteLoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	otp, ok := consumeOTP(c.Request.Context(), req.LoginID, engine.PurposeLogin, req.Code)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": invalidLoginCodeMessage,
+		})
+		return
+	}
+
+	var user models.User
+	query := config.DB
+	if otp.Email != "" {
+		query = query.Where("email = ?", otp.Email)
+	} else {
+		query = query.Where("phone = ?", otp.Phone)
+	}
+	if err := query.First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": invalidLoginCodeMessage,
+		})
+		return
+	}
+
+	fmt.Printf("✅ Login completed for user %s\n", user.ID)
+	respondWithSession(c, "Logged in successfully", otp, &user, req.RememberDeviceOptions)
+}
+
+// StartSignup sends a signup code. Whether the address is already
+// registered is only revealed after the code has been verified.
+func StartSignup(c *gin.Context) {
+	var req StartAuthRequest
+	if !bindStartAuthRequest(c, &req) {
+		return
+	}
+
+	if !enforceChallenge(c, req.Email, req.Phone, req.CaptchaToken) {
+		return
+	}
+
+	issued, err := createLoginOTP(c, engine.PurposeSignup, req.Email, req.Phone)
+	if err != nil {
+		otpErr := engine.AsError(err)
+		response := gin.H{
+			"success": false,
+			"message": otpErr.Message,
+		}
+		if otpErr.Code == engine.ErrorInternal {
+			response["message"] = "Failed to start signup"
+			response["error"] = err.Error()
+		}
+		c.JSON(otpErr.Status, response)
+		return
+	}
+
+	responseData := gin.H{
+		"signup_id":   issued.OTP.ID,
+		"expires_at":  issued.OTP.ExpiresAt,
+		"code_format": codeFormat(issued.Policy),
+	}
+
+	// Only include OTP code in development mode
+	if os.Getenv("ENVIRONMENT") != "production" {
+		responseData["otp_code"] = issued.Code
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "A signup code has been sent",
+		"data":    responseData,
+	})
+}
+
+// CompleteSignup verifies a signup code, creates the user and starts a session
+func CompleteSignup(c *gin.Context) {
+	var req CompleteSignupRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	otp, ok := consumeOTP(c.Request.Context(), req.SignupID, engine.PurposeSignup, req.Code)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": invalidLoginCodeMessage,
+		})
+		return
+	}
+
+	var existing int64
+	query := config.DB.Model(&models.User{})
+	if otp.Email != "" {
+		query = query.Where("email = ?", otp.Email)
+	} else {
+		query = query.Where("phone = ?", otp.Phone)
+	}
+	query.Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "An account already exists for this address. Please log in",
+		})
+		return
+	}
+
+	user := upsertVerifiedUser(otp)
+
+	fmt.Printf("🎉 Signup completed, user %s created\n", user.ID)
+	respondWithSession(c, "Account created successfully", otp, &user, req.RememberDeviceOptions)
+}
+
+func bindStartAuthRequest(c *gin.Context, req *StartAuthRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return false
+	}
+
+	if (req.Email == "") == (req.Phone == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Provide either an email or a phone number",
+		})
+		return false
+	}
+
+	return true
+}
+
+// createLoginOTP issues and delivers an OTP for the login or signup flow
+func createLoginOTP(c *gin.Context, purpose, email, phone string) (*engine.Issued, error) {
+	return otpEngine().Generate(c.Request.Context(), engine.GenerateRequest{
+		Email:    email,
+		Phone:    phone,
+		Purpose:  purpose,
+		ClientIP: c.ClientIP(),
+	})
+}
+
+// consumeOTP checks a code for the given purpose and marks the OTP
+// verified. Every failure is reported the same way.
+func consumeOTP(ctx context.Context, id, purpose, code string) (*models.OTP, bool) {
+	otp, err := otpEngine().Verify(ctx, engine.VerifyRequest{
+		OTPID:   id,
+		Code:    code,
+		Purpose: purpose,
+	})
+	if err != nil {
+		return nil, false
+	}
+	return otp, true
+}
+
+// respondWithSession answers a completed login or signup with a
+// verification token and a new session, trusting the device if asked
+func respondWithSession(c *gin.Context, message string, otp *models.OTP, user *models.User, device RememberDeviceOptions) {
+	responseData := gin.H{
+		"verified": true,
+		"user":     user,
+	}
+
+	channel, identifier := otpChannel(otp.Email, otp.Phone)
+	addVerificationToken(responseData, user.ID, utils.VerificationClaims{
+		Channel:    channel,
+		Identifier: identifier,
+		Purpose:    otp.Purpose,
+	})
+
+	session, err := createSession(clientInfo(c), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to create session",
+			"error":   err.Error(),
+		})
+		return
+	}
+	responseData["session"] = session
+	addTrustedDevice(c, responseData, user.ID, device)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    responseData,
+	})
+}
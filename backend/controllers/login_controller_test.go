@@ -0,0 +1,131 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+)
+
+// Starting a login must not reveal whether the account exists, including
+// through when a bot check kicks in
+func TestStartLoginSameForUnknownAccount(t *testing.T) {
+	r := newTestRouter(t)
+	useVerifier(t, &utils.CaptchaConfig{Provider: "fake", Mode: "risk", RiskThreshold: 2}, &utils.FakeChallengeVerifier{AcceptToken: "pass"})
+	config.DB.Create(&models.User{ID: "user-1", Email: "known@example.com", IsEmailVerified: true})
+
+	type attempt struct {
+		status int
+		keys   int
+	}
+	start := func(email, ip string) attempt {
+		status, out := doJSON(t, r, http.MethodPost, "/api/auth/login/start", map[string]string{"email": email}, map[string]string{"X-Forwarded-For": ip})
+		d, _ := out["data"].(map[string]interface{})
+		return attempt{status, len(d)}
+	}
+
+	// The third request from an address crosses the risk threshold
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusForbidden} {
+		known := start("known@example.com", "203.0.113.1")
+		unknown := start("unknown@example.com", "203.0.113.2")
+		if known.status != want || known != unknown {
+			t.Errorf("attempt %d: known account %+v, unknown account %+v, want status %d", i+1, known, unknown, want)
+		}
+	}
+}
+
+func TestCompleteLoginRejectsDecoy(t *testing.T) {
+	r := newTestRouter(t)
+
+	_, out := doJSON(t, r, http.MethodPost, "/api/auth/login/start", map[string]string{"email": "unknown@example.com"}, nil)
+	loginID := data(t, out)["login_id"].(string)
+
+	var otp models.OTP
+	if err := config.DB.Where("id = ?", loginID).First(&otp).Error; err != nil {
+		t.Fatalf("no decoy was recorded: %v", err)
+	}
+	status, _ := doJSON(t, r, http.MethodPost, "/api/auth/login/complete", map[string]string{"login_id": loginID, "code": otp.OTPCode}, nil)
+	if status != http.StatusBadRequest {
+		t.Errorf("decoy code: %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+// Only signup codes create accounts; a login code proves the address but
+// does not open an account the login flow would refuse
+func TestVerifyCreatesAccountsOnlyForSignup(t *testing.T) {
+	r := newTestRouter(t)
+
+	verify := func(purpose string) map[string]interface{} {
+		_, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "new@example.com", "purpose": purpose}, nil)
+		issued := data(t, out)
+		status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/verify", map[string]string{
+			"otp_id":   issued["otp_id"].(string),
+			"otp_code": issued["otp_code"].(string),
+			"purpose":  purpose,
+		}, nil)
+		if status != http.StatusOK {
+			t.Fatalf("verify %s: %d %v", purpose, status, out)
+		}
+		return data(t, out)
+	}
+
+	result := verify("login")
+	var users int64
+	config.DB.Model(&models.User{}).Count(&users)
+	if users != 0 || result["user_id"] != "" || result["session"] != nil {
+		t.Errorf("login code opened an account: %d users, %v", users, result)
+	}
+	if result["token"] != nil {
+		t.Errorf("verification token issued without an account: %v", result["token"])
+	}
+
+	signedUp := verify("signup")
+	if signedUp["user_id"] == "" || signedUp["session"] == nil {
+		t.Fatalf("signup did not create the account: %v", signedUp)
+	}
+	if loggedIn := verify("login"); loggedIn["user_id"] != signedUp["user_id"] || loggedIn["session"] == nil {
+		t.Errorf("login after signup: %v", loggedIn)
+	}
+}
+
+// Password reset and transaction codes authorize one action. They neither
+// open an account nor change the user.
+func TestActionCodesLeaveUsersUnchanged(t *testing.T) {
+	r := newTestRouter(t)
+	_, userID := login(t, r, "member@example.com")
+	config.DB.Model(&models.User{}).Where("id = ?", userID).Update("is_email_verified", false)
+
+	transaction := map[string]string{"amount": "10.00", "currency": "EUR", "payee": "Example Shop"}
+	for _, email := range []string{"member@example.com", "stranger@example.com"} {
+		for _, purpose := range []string{"password_reset", "transaction"} {
+			request := map[string]interface{}{"email": email, "purpose": purpose}
+			if purpose == "transaction" {
+				request["transaction"] = transaction
+			}
+			status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", request, nil)
+			if status != http.StatusOK {
+				t.Fatalf("generate %s for %s: %d %v", purpose, email, status, out)
+			}
+			issued := data(t, out)
+			request = map[string]interface{}{"otp_id": issued["otp_id"], "otp_code": issued["otp_code"], "purpose": purpose}
+			if purpose == "transaction" {
+				request["transaction"] = transaction
+			}
+			status, out = doJSON(t, r, http.MethodPost, "/api/v1/otp/verify", request, nil)
+			if status != http.StatusOK {
+				t.Fatalf("verify %s for %s: %d %v", purpose, email, status, out)
+			}
+			if hasToken := data(t, out)["token"] != nil; hasToken != (email == "member@example.com") {
+				t.Errorf("verify %s for %s: verification token issued %v", purpose, email, hasToken)
+			}
+		}
+	}
+
+	var users []models.User
+	config.DB.Find(&users)
+	if len(users) != 1 || users[0].IsEmailVerified {
+		t.Errorf("users after action codes: %+v", users)
+	}
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// buildMagicLink signs the magic link for an OTP that requested one.
+// It returns an empty string when the OTP has no magic link.
+func buildMagicLink(otp *models.OTP) (string, error) {
+	if otp.MagicLinkID == "" {
+		return "", nil
+	}
+
+	signer := utils.GetTokenSigner()
+	if signer == nil {
+		return "", fmt.Errorf("token signing not configured")
+	}
+
+	token, err := signer.IssueMagicLinkToken(otp.ID, otp.MagicLinkID, otp.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.GetMagicLinkConfig().LinkURL(token), nil
+}
+
+// ConsumeMagicLink verifies an OTP through its emailed link and redirects
+// the browser to the allowed return URL with the outcome
+func ConsumeMagicLink(c *gin.Context) {
+	cfg := utils.GetMagicLinkConfig()
+
+	fail := func(status string, otp *models.OTP) {
+		fmt.Printf("❌ Magic link rejected: %s\n\n", status)
+		params := map[string]string{"status": status}
+		returnURL := cfg.DefaultReturnURL
+		if otp != nil {
+			params["otp_id"] = otp.ID
+			if otp.ReturnURL != "" && cfg.IsAllowedReturnURL(otp.ReturnURL) {
+				returnURL = otp.ReturnURL
+			}
+		}
+		c.Redirect(http.StatusFound, utils.ReturnURLWith(returnURL, params))
+	}
+
+	signer := utils.GetTokenSigner()
+	if signer == nil {
+		fail("invalid", nil)
+		return
+	}
+
+	claims, err := signer.ParseMagicLinkToken(c.Query("token"))
+	if err != nil {
+		fail("invalid", nil)
+		return
+	}
+
+	otp, err := otpEngine().VerifyMagicLink(c.Request.Context(), claims.OTPID, claims.ID)
+	if err != nil {
+		fail(magicLinkStatus(engine.AsError(err).Code), otp)
+		return
+	}
+
+	// Like a typed code, the link only opens an account for signup
+	var userID string
+	if user := verifiedUser(otp); user != nil {
+		userID = user.ID
+	}
+
+	fmt.Printf("\n✅ MAGIC LINK VERIFIED!\n")
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("OTP ID: %s\n", otp.ID)
+	fmt.Printf("User ID: %s\n", userID)
+	fmt.Printf("Email: %s\n", otp.Email)
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	returnURL := cfg.DefaultReturnURL
+	if otp.ReturnURL != "" && cfg.IsAllowedReturnURL(otp.ReturnURL) {
+		returnURL = otp.ReturnURL
+	}
+
+	c.Redirect(http.StatusFound, utils.ReturnURLWith(returnURL, map[string]string{
+		"status": "verified",
+		"otp_id": otp.ID,
+	}))
+}
+
+// magicLinkStatus is the status a failed magic link reports to the return URL
+func magicLinkStatus(code engine.ErrorCode) string {
+	switch code {
+	case engine.ErrorOTPAlreadyVerified:
+		return "already_verified"
+	case engine.ErrorOTPRevoked:
+		return "revoked"
+	case engine.ErrorOTPExpired:
+		return "expired"
+	case engine.ErrorOTPLocked:
+		return "locked"
+	default:
+		return "invalid"
+	}
+}
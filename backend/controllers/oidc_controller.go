@@ -0,0 +1,561 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/securetoken"
+	"github.com/Avinashkr000/otp-verification-system/backend/templates"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RegisterClientRequest represents the request body for registering an OIDC client
+type RegisterClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=128"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,dive,url,max=512"`
+	// Public clients (SPAs, mobile apps) get no secret and rely on PKCE alone
+	Public bool `json:"public"`
+}
+
+// RegisterClient registers an application that signs users in through this
+// service. The client secret is only returned once.
+func RegisterClient(c *gin.Context) {
+	var req RegisterClientRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	client := models.OAuthClient{
+		ID:           uuid.New().String(),
+		Name:         req.Name,
+		RedirectURIs: strings.Join(req.RedirectURIs, "\n"),
+		Public:       req.Public,
+	}
+
+	var secret string
+	if !req.Public {
+		var err error
+		secret, err = securetoken.New(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to generate client secret",
+				"error":   err.Error(),
+			})
+			return
+		}
+		client.SecretHash = securetoken.Hash(secret)
+	}
+
+	if err := config.DB.Create(&client).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to register client",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("🔑 OIDC client %s registered (%s)\n", client.ID, client.Name)
+
+	data := gin.H{
+		"client_id":     client.ID,
+		"name":          client.Name,
+		"redirect_uris": req.RedirectURIs,
+		"public":        client.Public,
+	}
+	if secret != "" {
+		data["client_secret"] = secret
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Client registered. Store the client secret now, it cannot be shown again",
+		"data":    data,
+	})
+}
+
+// OpenIDConfiguration serves the OpenID Connect discovery document
+func OpenIDConfiguration(c *gin.Context) {
+	cfg := utils.GetOIDCConfig()
+
+	algorithm := "EdDSA"
+	if signer := utils.GetTokenSigner(); signer != nil {
+		algorithm = signer.Config.Algorithm
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"issuer":                                cfg.Issuer,
+		"authorization_endpoint":                cfg.Issuer + "/oauth/authorize",
+		"token_endpoint":                        cfg.Issuer + "/oauth/token",
+		"userinfo_endpoint":                     cfg.Issuer + "/oauth/userinfo",
+		"jwks_uri":                              cfg.Issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{algorithm},
+		"scopes_supported":                      utils.SupportedScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported":                      []string{"sub", "email", "email_verified", "phone_number", "phone_number_verified"},
+	})
+}
+
+// Authorize validates an authorization request and shows the sign-in page.
+// Errors about the client or redirect URI are shown to the user; anything
+// else is reported back to the client's redirect URI.
+func Authorize(c *gin.Context) {
+	clientID := c.Query("client_id")
+	redirectURI := c.Query("redirect_uri")
+	state := c.Query("state")
+
+	var client models.OAuthClient
+	if err := config.DB.Where("id = ?", clientID).First(&client).Error; err != nil {
+		renderAuthorizeError(c, http.StatusBadRequest, "Unknown client")
+		return
+	}
+	if !client.AllowsRedirectURI(redirectURI) {
+		renderAuthorizeError(c, http.StatusBadRequest, "The redirect URI is not registered for this client")
+		return
+	}
+
+	if c.Query("response_type") != "code" {
+		redirectWithError(c, redirectURI, state, "unsupported_response_type", "Only the code response type is supported")
+		return
+	}
+	scope := utils.FilterScopes(c.Query("scope"))
+	if !utils.HasScope(scope, "openid") {
+		redirectWithError(c, redirectURI, state, "invalid_scope", "The openid scope is required")
+		return
+	}
+	if c.Query("code_challenge") == "" || c.Query("code_challenge_method") != "S256" {
+		redirectWithError(c, redirectURI, state, "invalid_request", "PKCE with code_challenge_method S256 is required")
+		return
+	}
+
+	authRequest := models.AuthorizationRequest{
+		ID:            uuid.New().String(),
+		ClientID:      client.ID,
+		RedirectURI:   redirectURI,
+		Scope:         scope,
+		State:         state,
+		Nonce:         c.Query("nonce"),
+		CodeChallenge: c.Query("code_challenge"),
+		ExpiresAt:     time.Now().Add(utils.GetOIDCConfig().RequestTTL),
+	}
+	if err := config.DB.Create(&authRequest).Error; err != nil {
+		renderAuthorizeError(c, http.StatusInternalServerError, "Failed to start sign-in")
+		return
+	}
+
+	page := templates.AuthorizePage{
+		Step:       "identify",
+		ClientName: client.Name,
+		RequestID:  authRequest.ID,
+	}
+	if captchaConfig.Enabled() && captchaConfig.Mode == "always" {
+		page.Captcha = authorizeCaptcha()
+	}
+	renderAuthorize(c, http.StatusOK, page)
+}
+
+// AuthorizeSendCode sends an OTP for a pending authorization request
+func AuthorizeSendCode(c *gin.Context) {
+	authRequest, client, ok := loadAuthorizationRequest(c)
+	if !ok {
+		return
+	}
+
+	email := strings.TrimSpace(c.PostForm("email"))
+	phone := strings.TrimSpace(c.PostForm("phone"))
+	page := templates.AuthorizePage{
+		Step:       "identify",
+		ClientName: client.Name,
+		RequestID:  authRequest.ID,
+		Email:      email,
+		Phone:      phone,
+	}
+	if (email == "") == (phone == "") {
+		page.Error = "Enter either an email or a phone number"
+		renderAuthorize(c, http.StatusBadRequest, page)
+		return
+	}
+
+	if err := checkChallenge(clientInfo(c), email, phone, formCaptchaToken(c)); err != nil {
+		page.Error = err.Message
+		page.Captcha = authorizeCaptcha()
+		renderAuthorize(c, err.Status, page)
+		return
+	}
+
+	issued, err := createLoginOTP(c, engine.PurposeLogin, email, phone)
+	if err != nil {
+		if otpErr := engine.AsError(err); otpErr.Code == engine.ErrorRateLimited {
+			page.Error = "Too many codes requested. Please try again after an hour"
+			renderAuthorize(c, http.StatusTooManyRequests, page)
+			return
+		}
+		page.Error = "Failed to send the code. Please try again"
+		renderAuthorize(c, http.StatusInternalServerError, page)
+		return
+	}
+
+	config.DB.Model(authRequest).Update("otp_id", issued.OTP.ID)
+
+	page.Step = "code"
+	page.SentTo = email + phone
+	page.CodeFormat = engine.FormatCode(strings.Repeat("0", issued.Policy.Length), issued.Policy.GroupSize)
+	renderAuthorize(c, http.StatusOK, page)
+}
+
+// AuthorizeVerifyCode checks the OTP and redirects back to the client with
+// an authorization code
+func AuthorizeVerifyCode(c *gin.Context) {
+	authRequest, client, ok := loadAuthorizationRequest(c)
+	if !ok {
+		return
+	}
+
+	if authRequest.OTPID == "" {
+		renderAuthorizeError(c, http.StatusBadRequest, "No code has been sent yet")
+		return
+	}
+
+	otp, ok := consumeOTP(c.Request.Context(), authRequest.OTPID, engine.PurposeLogin, c.PostForm("code"))
+	if !ok {
+		policy, _ := utils.GetOTPPolicy(engine.PurposeLogin)
+		var sent models.OTP
+		config.DB.Where("id = ?", authRequest.OTPID).First(&sent)
+		renderAuthorize(c, http.StatusBadRequest, templates.AuthorizePage{
+			Step:       "code",
+			ClientName: client.Name,
+			RequestID:  authRequest.ID,
+			SentTo:     sent.Email + sent.Phone,
+			CodeFormat: engine.FormatCode(strings.Repeat("0", policy.Length), policy.GroupSize),
+			Error:      invalidLoginCodeMessage,
+		})
+		return
+	}
+
+	user := upsertVerifiedUser(otp)
+
+	code, err := securetoken.New(32)
+	if err != nil {
+		renderAuthorizeError(c, http.StatusInternalServerError, "Failed to complete sign-in")
+		return
+	}
+
+	authCode := models.AuthorizationCode{
+		ID:            uuid.New().String(),
+		CodeHash:      securetoken.Hash(code),
+		ClientID:      client.ID,
+		UserID:        user.ID,
+		RedirectURI:   authRequest.RedirectURI,
+		Scope:         authRequest.Scope,
+		Nonce:         authRequest.Nonce,
+		CodeChallenge: authRequest.CodeChallenge,
+		AuthTime:      *otp.VerifiedAt,
+		ExpiresAt:     time.Now().Add(utils.GetOIDCConfig().CodeTTL),
+	}
+	if err := config.DB.Create(&authCode).Error; err != nil {
+		renderAuthorizeError(c, http.StatusInternalServerError, "Failed to complete sign-in")
+		return
+	}
+	config.DB.Delete(authRequest)
+
+	fmt.Printf("✅ OIDC sign-in for user %s to client %s\n", user.ID, client.ID)
+
+	params := url.Values{}
+	params.Set("code", code)
+	if authRequest.State != "" {
+		params.Set("state", authRequest.State)
+	}
+	c.Redirect(http.StatusFound, appendQuery(authRequest.RedirectURI, params))
+}
+
+// Token exchanges an authorization code for an id_token and access token
+func Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	if c.PostForm("grant_type") != "authorization_code" {
+		tokenError(c, http.StatusBadRequest, "unsupported_grant_type", "Only authorization_code is supported")
+		return
+	}
+
+	clientID, clientSecret, hasBasic := c.Request.BasicAuth()
+	if !hasBasic {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+
+	var client models.OAuthClient
+	if err := config.DB.Where("id = ?", clientID).First(&client).Error; err != nil {
+		tokenError(c, http.StatusUnauthorized, "invalid_client", "Unknown client")
+		return
+	}
+	if !client.Public {
+		hash := securetoken.Hash(clientSecret)
+		if clientSecret == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) != 1 {
+			tokenError(c, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+			return
+		}
+	}
+
+	var authCode models.AuthorizationCode
+	if err := config.DB.Where("code_hash = ?", securetoken.Hash(c.PostForm("code"))).First(&authCode).Error; err != nil {
+		tokenError(c, http.StatusBadRequest, "invalid_grant", "Invalid authorization code")
+		return
+	}
+
+	// Mark the code used before anything else so it can only be redeemed once
+	now := time.Now()
+	result := config.DB.Model(&models.AuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", authCode.ID).
+		Update("used_at", now)
+	if result.Error != nil || result.RowsAffected != 1 {
+		revokeAuthorizationCode(&authCode)
+		tokenError(c, http.StatusBadRequest, "invalid_grant", "Authorization code already used")
+		return
+	}
+
+	switch {
+	case now.After(authCode.ExpiresAt):
+		tokenError(c, http.StatusBadRequest, "invalid_grant", "Authorization code expired")
+		return
+	case authCode.ClientID != client.ID:
+		tokenError(c, http.StatusBadRequest, "invalid_grant", "Authorization code was issued to another client")
+		return
+	case authCode.RedirectURI != c.PostForm("redirect_uri"):
+		tokenError(c, http.StatusBadRequest, "invalid_grant", "redirect_uri does not match the authorization request")
+		return
+	case !utils.VerifyPKCE(c.PostForm("code_verifier"), authCode.CodeChallenge):
+		tokenError(c, http.StatusBadRequest, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("id = ?", authCode.UserID).First(&user).Error; err != nil {
+		tokenError(c, http.StatusBadRequest, "invalid_grant", "User no longer exists")
+		return
+	}
+
+	signer := utils.GetTokenSigner()
+	if signer == nil {
+		tokenError(c, http.StatusInternalServerError, "server_error", "Token signing not configured")
+		return
+	}
+
+	idToken, err := signer.IssueIDToken(utils.GetOIDCConfig().Issuer, user.ID, client.ID, idTokenClaims(&user, &authCode))
+	if err != nil {
+		tokenError(c, http.StatusInternalServerError, "server_error", "Failed to sign id_token")
+		return
+	}
+
+	// The access token is a regular session token, so it works with
+	// /oauth/userinfo and can be revoked like any other session
+	session, err := createSession(clientInfo(c), user.ID)
+	if err != nil {
+		tokenError(c, http.StatusInternalServerError, "server_error", "Failed to create session")
+		return
+	}
+
+	// Record the session so a replayed code can revoke it. If the replay
+	// arrived first, the code is already revoked and so is this session.
+	result = config.DB.Model(&models.AuthorizationCode{}).
+		Where("id = ? AND revoked_at IS NULL", authCode.ID).
+		Update("session_id", session.SessionID)
+	if result.Error != nil || result.RowsAffected != 1 {
+		config.DB.Model(&models.Session{}).Where("id = ?", session.SessionID).Update("revoked_at", time.Now())
+		tokenError(c, http.StatusBadRequest, "invalid_grant", "Authorization code already used")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": session.AccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(time.Until(session.AccessExpiresAt).Seconds()),
+		"id_token":     idToken,
+		"scope":        authCode.Scope,
+	})
+}
+
+// UserInfo returns the verified claims of the user the access token belongs to
+func UserInfo(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Where("id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "invalid_token",
+			"error_description": "User no longer exists",
+		})
+		return
+	}
+
+	info := gin.H{"sub": user.ID}
+	if user.Email != "" {
+		info["email"] = user.Email
+		info["email_verified"] = user.IsEmailVerified
+	}
+	if user.Phone != "" {
+		info["phone_number"] = user.Phone
+		info["phone_number_verified"] = user.IsPhoneVerified
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// idTokenClaims builds the id_token claims the granted scopes allow
+func idTokenClaims(user *models.User, authCode *models.AuthorizationCode) utils.IDTokenClaims {
+	claims := utils.IDTokenClaims{
+		Nonce:    authCode.Nonce,
+		AuthTime: authCode.AuthTime.Unix(),
+	}
+
+	if utils.HasScope(authCode.Scope, "email") && user.Email != "" {
+		claims.Email = user.Email
+		claims.EmailVerified = &user.IsEmailVerified
+	}
+	if utils.HasScope(authCode.Scope, "phone") && user.Phone != "" {
+		claims.PhoneNumber = user.Phone
+		claims.PhoneNumberVerified = &user.IsPhoneVerified
+	}
+
+	return claims
+}
+
+// revokeAuthorizationCode handles a code that was presented again after
+// being redeemed. It must have leaked, so the session issued for it is
+// revoked (RFC 6749 section 4.1.2).
+func revokeAuthorizationCode(authCode *models.AuthorizationCode) {
+	now := time.Now()
+	config.DB.Model(&models.AuthorizationCode{}).
+		Where("id = ? AND revoked_at IS NULL", authCode.ID).
+		Update("revoked_at", now)
+
+	var redeemed models.AuthorizationCode
+	if err := config.DB.Where("id = ?", authCode.ID).First(&redeemed).Error; err != nil || redeemed.SessionID == "" {
+		return
+	}
+	fmt.Printf("🚨 Authorization code reuse detected! Revoking session %s (user %s)\n", redeemed.SessionID, redeemed.UserID)
+	config.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", redeemed.SessionID).
+		Update("revoked_at", now)
+}
+
+// authorizeCaptcha describes the challenge to embed in the sign-in page
+func authorizeCaptcha() *templates.AuthorizeCaptcha {
+	if challengeVerifier == nil {
+		return nil
+	}
+
+	captcha := &templates.AuthorizeCaptcha{
+		Provider: challengeVerifier.Provider(),
+		SiteKey:  captchaConfig.SiteKey,
+	}
+	if pow, ok := challengeVerifier.(*utils.ProofOfWorkVerifier); ok {
+		challenge, err := pow.NewChallenge()
+		if err != nil {
+			fmt.Printf("⚠️  Failed to create challenge: %v\n", err)
+			return nil
+		}
+		captcha.Challenge = challenge.Challenge
+		captcha.Difficulty = challenge.Difficulty
+	}
+	return captcha
+}
+
+// formCaptchaToken returns the solved challenge posted with the sign-in
+// form. Hosted widgets post it under their own field names.
+func formCaptchaToken(c *gin.Context) string {
+	for _, field := range []string{"captcha_token", "h-captcha-response", "g-recaptcha-response", "cf-turnstile-response"} {
+		if token := c.PostForm(field); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// loadAuthorizationRequest loads the pending request named in the form and
+// handles the cancel button. It renders the response when it returns false.
+func loadAuthorizationRequest(c *gin.Context) (*models.AuthorizationRequest, *models.OAuthClient, bool) {
+	var authRequest models.AuthorizationRequest
+	if err := config.DB.Where("id = ?", c.PostForm("request_id")).First(&authRequest).Error; err != nil ||
+		time.Now().After(authRequest.ExpiresAt) {
+		renderAuthorizeError(c, http.StatusBadRequest, "This sign-in request has expired. Please start again")
+		return nil, nil, false
+	}
+
+	var client models.OAuthClient
+	if err := config.DB.Where("id = ?", authRequest.ClientID).First(&client).Error; err != nil {
+		renderAuthorizeError(c, http.StatusBadRequest, "Unknown client")
+		return nil, nil, false
+	}
+
+	if c.PostForm("action") == "cancel" {
+		config.DB.Delete(&authRequest)
+		redirectWithError(c, authRequest.RedirectURI, authRequest.State, "access_denied", "The user cancelled sign-in")
+		return nil, nil, false
+	}
+
+	return &authRequest, &client, true
+}
+
+func renderAuthorize(c *gin.Context, status int, page templates.AuthorizePage) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Status(status)
+	if err := templates.Authorize.Execute(c.Writer, page); err != nil {
+		fmt.Printf("⚠️  Failed to render sign-in page: %v\n", err)
+	}
+}
+
+func renderAuthorizeError(c *gin.Context, status int, message string) {
+	renderAuthorize(c, status, templates.AuthorizePage{Step: "error", Error: message})
+}
+
+// redirectWithError reports an authorization error to the client (RFC 6749 section 4.1.2.1)
+func redirectWithError(c *gin.Context, redirectURI, state, code, description string) {
+	params := url.Values{}
+	params.Set("error", code)
+	params.Set("error_description", description)
+	if state != "" {
+		params.Set("state", state)
+	}
+	c.Redirect(http.StatusFound, appendQuery(redirectURI, params))
+}
+
+// tokenError writes an RFC 6749 section 5.2 error response
+func tokenError(c *gin.Context, status int, code, description string) {
+	if code == "invalid_client" {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.JSON(status, gin.H{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + params.Encode()
+}
@@ -0,0 +1,166 @@
+package controllers_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testRedirectURI  = "https://app.example.com/callback"
+	testCodeVerifier = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFG"
+)
+
+var requestIDField = regexp.MustCompile(`name="request_id" value="([^"]+)"`)
+
+// doForm posts an HTML form
+func doForm(r http.Handler, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "controllers-test")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// registerClient registers a confidential client and returns its ID and secret
+func registerClient(t *testing.T, r *gin.Engine) (string, string) {
+	t.Helper()
+	t.Setenv("ADMIN_API_KEY", "admin-key")
+	status, out := doJSON(t, r, http.MethodPost, "/oauth/clients", map[string]interface{}{
+		"name":          "Example App",
+		"redirect_uris": []string{testRedirectURI},
+	}, map[string]string{"X-Admin-Key": "admin-key"})
+	if status != http.StatusCreated {
+		t.Fatalf("register client: %d %v", status, out)
+	}
+	client := data(t, out)
+	return client["client_id"].(string), client["client_secret"].(string)
+}
+
+// startAuthorization opens the sign-in page and returns its request ID
+func startAuthorization(t *testing.T, r *gin.Engine, clientID string) (string, string) {
+	t.Helper()
+	challenge := sha256.Sum256([]byte(testCodeVerifier))
+	query := url.Values{
+		"client_id":             {clientID},
+		"redirect_uri":          {testRedirectURI},
+		"response_type":         {"code"},
+		"scope":                 {"openid email"},
+		"state":                 {"xyz"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+query.Encode(), nil))
+	match := requestIDField.FindStringSubmatch(w.Body.String())
+	if w.Code != http.StatusOK || match == nil {
+		t.Fatalf("authorize: %d %s", w.Code, w.Body.String())
+	}
+	return match[1], w.Body.String()
+}
+
+// authorizationCode signs in on the sign-in page and returns the code
+// handed to the client's redirect URI
+func authorizationCode(t *testing.T, r *gin.Engine, clientID, email string) string {
+	t.Helper()
+	requestID, _ := startAuthorization(t, r, clientID)
+	if w := doForm(r, "/oauth/authorize", url.Values{"request_id": {requestID}, "email": {email}}); w.Code != http.StatusOK {
+		t.Fatalf("send code: %d %s", w.Code, w.Body.String())
+	}
+
+	var otp models.OTP
+	config.DB.Where("email = ?", email).Order("created_at DESC").First(&otp)
+	w := doForm(r, "/oauth/authorize/verify", url.Values{"request_id": {requestID}, "code": {otp.OTPCode}})
+	location, err := url.Parse(w.Header().Get("Location"))
+	if w.Code != http.StatusFound || err != nil || location.Query().Get("code") == "" {
+		t.Fatalf("verify code: %d %s", w.Code, w.Header().Get("Location"))
+	}
+	return location.Query().Get("code")
+}
+
+func redeem(r *gin.Engine, clientID, secret, code string) (int, map[string]interface{}) {
+	w := doForm(r, "/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {testCodeVerifier},
+		"client_id":     {clientID},
+		"client_secret": {secret},
+	})
+	var out map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
+
+func TestAuthorizeSendCodeEnforcesChallenge(t *testing.T) {
+	r := newTestRouter(t)
+	useVerifier(t, &utils.CaptchaConfig{Provider: "fake", Mode: "always"}, &utils.FakeChallengeVerifier{AcceptToken: "pass"})
+	clientID, _ := registerClient(t, r)
+	requestID, _ := startAuthorization(t, r, clientID)
+
+	for _, tc := range []struct {
+		token  string
+		status int
+	}{
+		{"", http.StatusForbidden},
+		{"wrong", http.StatusForbidden},
+		{"pass", http.StatusOK},
+	} {
+		w := doForm(r, "/oauth/authorize", url.Values{"request_id": {requestID}, "email": {"oidc@example.com"}, "captcha_token": {tc.token}})
+		if w.Code != tc.status {
+			t.Errorf("token %q: %d, want %d", tc.token, w.Code, tc.status)
+		}
+	}
+
+	var sent int64
+	config.DB.Model(&models.OTP{}).Count(&sent)
+	if sent != 1 {
+		t.Errorf("%d codes sent, want 1", sent)
+	}
+}
+
+func TestSignInPageEmbedsProofOfWork(t *testing.T) {
+	r := newTestRouter(t)
+	useVerifier(t, &utils.CaptchaConfig{Provider: "pow", Mode: "always"}, utils.NewProofOfWorkVerifier([]byte("test-key"), 8))
+	clientID, _ := registerClient(t, r)
+
+	if _, page := startAuthorization(t, r, clientID); !strings.Contains(page, `id="captcha_token"`) {
+		t.Errorf("sign-in page has no proof-of-work challenge:\n%s", page)
+	}
+}
+
+// A replayed authorization code must have leaked, so the tokens already
+// issued for it stop working
+func TestReusedAuthorizationCodeRevokesTokens(t *testing.T) {
+	r := newTestRouter(t)
+	clientID, secret := registerClient(t, r)
+	code := authorizationCode(t, r, clientID, "oidc@example.com")
+
+	status, out := redeem(r, clientID, secret, code)
+	if status != http.StatusOK {
+		t.Fatalf("redeem: %d %v", status, out)
+	}
+	accessToken := out["access_token"].(string)
+	if status, _ := doJSON(t, r, http.MethodGet, "/oauth/userinfo", nil, bearer(accessToken)); status != http.StatusOK {
+		t.Fatalf("userinfo: %d", status)
+	}
+
+	if status, out := redeem(r, clientID, secret, code); status != http.StatusBadRequest || out["error"] != "invalid_grant" {
+		t.Errorf("replayed code: %d %v", status, out)
+	}
+	if status, _ := doJSON(t, r, http.MethodGet, "/oauth/userinfo", nil, bearer(accessToken)); status != http.StatusUnauthorized {
+		t.Errorf("userinfo after replay: %d, want %d", status, http.StatusUnauthorized)
+	}
+}
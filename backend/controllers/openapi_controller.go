@@ -0,0 +1,448 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/middleware"
+	"github.com/Avinashkr000/otp-verification-system/backend/templates"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const apiTitle = "OTP Verification API"
+
+// apiOperation describes one endpoint of the versioned OTP API. Request
+// and response schemas are generated from the Go types the handler binds
+// and returns, so the document cannot drift from the code.
+type apiOperation struct {
+	Method      string
+	Path        string // relative to /api/v1/otp, in OpenAPI template syntax
+	ID          string
+	Summary     string
+	Description string
+	Request     interface{} // request body type, nil for none
+	Response    interface{} // type of "data" in the success envelope
+	Errors      map[int][]engine.ErrorCode
+	StatusToken bool // requires the X-OTP-Status-Token header
+	Admin       bool // requires the X-Admin-Key header
+	Redirect    bool // answers with a redirect instead of JSON
+	Stream      bool // answers with Server-Sent Events instead of JSON
+	Idempotent  bool // honors the Idempotency-Key header
+}
+
+var otpOperations = []apiOperation{
+	{
+		Method:     http.MethodPost,
+		Path:       "/generate",
+		ID:         "generateOTP",
+		Summary:    "Generate and send an OTP",
+		Request:    GenerateOTPRequest{},
+		Response:   OTPIssuedResponse{},
+		Idempotent: true,
+		Errors: map[int][]engine.ErrorCode{
+			http.StatusBadRequest: {engine.ErrorValidationFailed, engine.ErrorIdentifierRequired, engine.ErrorPurposeInvalid,
+				engine.ErrorTransactionRequired, engine.ErrorMagicLinkUnavailable, engine.ErrorReturnURLNotAllowed},
+			http.StatusForbidden:           {engine.ErrorCaptchaRequired, engine.ErrorCaptchaFailed},
+			http.StatusTooManyRequests:     {engine.ErrorRateLimited},
+			http.StatusInternalServerError: {engine.ErrorInternal},
+		},
+	},
+	{
+		Method:  http.MethodPost,
+		Path:    "/verify",
+		ID:      "verifyOTP",
+		Summary: "Verify an OTP",
+		Description: "When no account exists for the address, the code is still verified but the response has an empty `user_id` " +
+			"and carries neither a verification token nor a session.",
+		Request:  VerifyOTPRequest{},
+		Response: VerifyOTPResponse{},
+		Errors: map[int][]engine.ErrorCode{
+			http.StatusBadRequest: {engine.ErrorValidationFailed, engine.ErrorOTPAlreadyVerified, engine.ErrorOTPSuperseded,
+				engine.ErrorOTPExpired, engine.ErrorOTPLocked, engine.ErrorPurposeMismatch, engine.ErrorTransactionMismatch, engine.ErrorOTPInvalid},
+			http.StatusNotFound: {engine.ErrorOTPNotFound},
+			http.StatusGone:     {engine.ErrorOTPRevoked},
+		},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/resend",
+		ID:          "resendOTP",
+		Summary:     "Replace an OTP with a new code",
+		Description: "The previous code stops working. Resends are limited by a per-purpose cooldown.",
+		Request:     ResendOTPRequest{},
+		Response:    OTPIssuedResponse{},
+		Idempotent:  true,
+		Errors: map[int][]engine.ErrorCode{
+			http.StatusBadRequest:          {engine.ErrorValidationFailed, engine.ErrorOTPAlreadyVerified, engine.ErrorOTPSuperseded, engine.ErrorPurposeInvalid},
+			http.StatusForbidden:           {engine.ErrorCaptchaRequired, engine.ErrorCaptchaFailed},
+			http.StatusNotFound:            {engine.ErrorOTPNotFound},
+			http.StatusGone:                {engine.ErrorOTPRevoked},
+			http.StatusTooManyRequests:     {engine.ErrorResendCooldown},
+			http.StatusInternalServerError: {engine.ErrorInternal},
+		},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     "/challenge",
+		ID:       "getChallenge",
+		Summary:  "Get the bot protection challenge to solve before generating an OTP",
+		Response: ChallengeResponse{},
+		Errors: map[int][]engine.ErrorCode{
+			http.StatusInternalServerError: {engine.ErrorInternal},
+		},
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/magic",
+		ID:          "consumeMagicLink",
+		Summary:     "Open a magic link",
+		Description: "Verifies the OTP and redirects to its return URL with `status` and `otp_id` query parameters.",
+		Redirect:    true,
+	},
+	{
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+		ID:          "getOTPStatus",
+		Summary:     "Look up the state of an OTP",
+		Description: "Unknown IDs and wrong status tokens both return 404.",
+		Response:    OTPStatusResponse{},
+		StatusToken: true,
+		Errors: map[int][]engine.ErrorCode{
+			http.StatusNotFound: {engine.ErrorOTPNotFound},
+		},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    "/{id}/events",
+		ID:      "streamOTPEvents",
+		Summary: "Stream the delivery and verification of an OTP",
+		Description: "Server-Sent Events. A `status` event carries the OTPStatusResponse when the stream opens and after every change; " +
+			"lifecycle events such as `otp.delivered` and `otp.verified` are sent as they happen. The stream ends when the OTP is no longer pending.",
+		StatusToken: true,
+		Stream:      true,
+		Errors: map[int][]engine.ErrorCode{
+			http.StatusNotFound: {engine.ErrorOTPNotFound},
+		},
+	},
+	{
+		Method:      http.MethodPost,
+		Path:        "/{id}/cancel",
+		ID:          "cancelOTP",
+		Summary:     "Cancel a pending OTP",
+		Description: "Cancelling an OTP twice is not an error.",
+		Response:    OTPCancelledResponse{},
+		StatusToken: true,
+		Errors: map[int][]engine.ErrorCode{
+			http.StatusNotFound:            {engine.ErrorOTPNotFound},
+			http.StatusConflict:            {engine.ErrorOTPAlreadyVerified},
+			http.StatusInternalServerError: {engine.ErrorInternal},
+		},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     "/revoke",
+		ID:       "revokeOTPs",
+		Summary:  "Revoke all pending OTPs of an email or phone number",
+		Request:  RevokeOTPsRequest{},
+		Response: RevokeOTPsResponse{},
+		Admin:    true,
+		Errors: map[int][]engine.ErrorCode{
+			http.StatusBadRequest:          {engine.ErrorValidationFailed, engine.ErrorIdentifierRequired},
+			http.StatusUnauthorized:        {engine.ErrorUnauthorized},
+			http.StatusForbidden:           {engine.ErrorAdminDisabled},
+			http.StatusInternalServerError: {engine.ErrorInternal},
+		},
+	},
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+	openAPIErr  error
+)
+
+// openAPIDocument returns the OpenAPI document, built on first use
+func openAPIDocument() ([]byte, error) {
+	openAPIOnce.Do(func() {
+		openAPIJSON, openAPIErr = json.MarshalIndent(buildOpenAPISpec(), "", "  ")
+	})
+	return openAPIJSON, openAPIErr
+}
+
+// InitAPIDocs builds the OpenAPI document and checks the docs page
+// settings, so a broken document fails at startup instead of on the first
+// request
+func InitAPIDocs() error {
+	if _, err := openAPIDocument(); err != nil {
+		return fmt.Errorf("openapi: %w", err)
+	}
+	return utils.GetDocsConfig().Validate()
+}
+
+// OpenAPISpec serves the OpenAPI 3 document of the versioned OTP API
+func OpenAPISpec(c *gin.Context) {
+	spec, err := openAPIDocument()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, engine.ErrorInternal, "Failed to build the API document", gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+}
+
+// APIDocs serves a browsable API reference rendered from /openapi.json
+func APIDocs(c *gin.Context) {
+	cfg := utils.GetDocsConfig()
+	page := templates.DocsPage{
+		Title:           apiTitle,
+		SpecURL:         "/openapi.json",
+		ScriptURL:       cfg.ScriptURL,
+		ScriptIntegrity: cfg.ScriptIntegrity,
+	}
+	// Without a local bundle, link to the spec instead of loading a 404
+	if cfg.ScriptURL == utils.LocalRedocURL {
+		if _, err := os.Stat(cfg.RedocFile); err != nil {
+			page.ScriptURL = ""
+		}
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := templates.Docs.Execute(c.Writer, page); err != nil {
+		fmt.Printf("⚠️  Failed to render API docs: %v\n", err)
+	}
+}
+
+// APIDocsScript serves the Redoc bundle from API_DOCS_REDOC_FILE, so the
+// docs page does not load code from a third-party CDN
+func APIDocsScript(c *gin.Context) {
+	cfg := utils.GetDocsConfig()
+	if _, err := os.Stat(cfg.RedocFile); err != nil {
+		c.String(http.StatusNotFound, "Redoc bundle not installed")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.File(cfg.RedocFile)
+}
+
+func buildOpenAPISpec() utils.Schema {
+	registry := utils.NewSchemaRegistry()
+
+	// Shared envelope, error code and problem schemas
+	registry.Ref(engine.APIResponse{})
+	codes := make([]string, 0, len(engine.ErrorCatalog))
+	descriptions := make([]string, 0, len(engine.ErrorCatalog))
+	for _, info := range engine.ErrorCatalog {
+		codes = append(codes, string(info.Code))
+		descriptions = append(descriptions, fmt.Sprintf("- `%s`: %s", info.Code, info.Title))
+	}
+	registry.Schemas["ErrorCode"] = utils.Schema{
+		"type":        "string",
+		"enum":        codes,
+		"description": strings.Join(descriptions, "\n"),
+	}
+	registry.Ref(engine.APIError{})
+	registry.Schemas["APIError"]["properties"].(utils.Schema)["code"] = utils.Schema{"$ref": "#/components/schemas/ErrorCode"}
+	registry.Schemas["Problem"] = utils.Schema{
+		"type":        "object",
+		"description": "RFC 7807 problem details. Error details are added as extension members.",
+		"properties": utils.Schema{
+			"type":     utils.Schema{"type": "string"},
+			"title":    utils.Schema{"type": "string"},
+			"status":   utils.Schema{"type": "integer"},
+			"detail":   utils.Schema{"type": "string"},
+			"instance": utils.Schema{"type": "string"},
+			"code":     utils.Schema{"$ref": "#/components/schemas/ErrorCode"},
+		},
+		"additionalProperties": true,
+	}
+
+	paths := utils.Schema{}
+	for _, op := range otpOperations {
+		path := "/api/v1/otp" + op.Path
+		item, ok := paths[path].(utils.Schema)
+		if !ok {
+			item = utils.Schema{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = openAPIOperation(registry, op)
+	}
+	paths["/api/v1/errors"] = utils.Schema{
+		"get": utils.Schema{
+			"operationId": "listErrorCodes",
+			"summary":     "List the error codes of the versioned API",
+			"tags":        []string{"errors"},
+			"responses": utils.Schema{
+				"200": successResponse(registry, []engine.ErrorInfo{}),
+			},
+		},
+	}
+
+	return utils.Schema{
+		"openapi": "3.0.3",
+		"info": utils.Schema{
+			"title":   apiTitle,
+			"version": "v1",
+			"description": "Errors carry a stable `code` from the ErrorCode catalog. Send `Accept: application/problem+json` " +
+				"to receive errors as RFC 7807 problem details. The same endpoints are served under `/api/otp` " +
+				"as a compatibility alias with the legacy error shape.",
+		},
+		"servers": []utils.Schema{{"url": utils.GetOIDCConfig().Issuer}},
+		"tags":    []utils.Schema{{"name": "otp"}, {"name": "errors"}},
+		"paths":   paths,
+		"components": utils.Schema{
+			"schemas": registry.Schemas,
+			"securitySchemes": utils.Schema{
+				"adminKey": utils.Schema{"type": "apiKey", "in": "header", "name": "X-Admin-Key"},
+			},
+		},
+	}
+}
+
+func openAPIOperation(registry *utils.SchemaRegistry, op apiOperation) utils.Schema {
+	operation := utils.Schema{
+		"operationId": op.ID,
+		"summary":     op.Summary,
+		"tags":        []string{"otp"},
+	}
+	if op.Description != "" {
+		operation["description"] = op.Description
+	}
+
+	var parameters []utils.Schema
+	if strings.Contains(op.Path, "{id}") {
+		parameters = append(parameters, utils.Schema{
+			"name": "id", "in": "path", "required": true, "schema": utils.Schema{"type": "string"},
+		})
+	}
+	if op.StatusToken {
+		parameters = append(parameters, utils.Schema{
+			"name": "X-OTP-Status-Token", "in": "header", "required": !op.Stream, "schema": utils.Schema{"type": "string"},
+			"description": "The status_token returned when the OTP was generated or resent",
+		})
+	}
+	if op.Stream {
+		parameters = append(parameters, utils.Schema{
+			"name": "status_token", "in": "query", "required": false, "schema": utils.Schema{"type": "string"},
+			"description": "The status token, for clients such as EventSource that cannot set headers",
+		})
+	}
+	if op.Redirect {
+		parameters = append(parameters, utils.Schema{
+			"name": "token", "in": "query", "required": true, "schema": utils.Schema{"type": "string"},
+		})
+	}
+	errors := op.Errors
+	if op.Idempotent {
+		parameters = append(parameters, utils.Schema{
+			"name": middleware.IdempotencyKeyHeader, "in": "header", "required": false,
+			"schema":      utils.Schema{"type": "string", "maxLength": utils.GetIdempotencyConfig().MaxKeyLength},
+			"description": "Retries with the same key replay the first successful response instead of sending another code",
+		})
+
+		// Copy so the shared operation table is not modified
+		errors = map[int][]engine.ErrorCode{}
+		for status, codes := range op.Errors {
+			errors[status] = codes
+		}
+		errors[http.StatusBadRequest] = append(errors[http.StatusBadRequest], engine.ErrorIdempotencyKeyInvalid)
+		errors[http.StatusConflict] = append(errors[http.StatusConflict], engine.ErrorIdempotencyInProgress)
+		errors[http.StatusUnprocessableEntity] = append(errors[http.StatusUnprocessableEntity], engine.ErrorIdempotencyKeyReused)
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	if op.Admin {
+		operation["security"] = []utils.Schema{{"adminKey": []string{}}}
+	}
+
+	if op.Request != nil {
+		operation["requestBody"] = utils.Schema{
+			"required": true,
+			"content": utils.Schema{
+				"application/json": utils.Schema{"schema": registry.Ref(op.Request)},
+			},
+		}
+	}
+
+	responses := utils.Schema{}
+	if op.Redirect {
+		responses["302"] = utils.Schema{"description": "Redirect to the return URL"}
+	} else if op.Stream {
+		responses["200"] = utils.Schema{
+			"description": "Event stream",
+			"content": utils.Schema{
+				"text/event-stream": utils.Schema{"schema": utils.Schema{"type": "string"}},
+			},
+		}
+	} else {
+		responses["200"] = successResponse(registry, op.Response)
+	}
+
+	statuses := make([]int, 0, len(errors))
+	for status := range errors {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		responses[strconv.Itoa(status)] = errorResponse(errors[status])
+	}
+	if op.Idempotent {
+		responses["200"].(utils.Schema)["headers"] = utils.Schema{
+			"Idempotent-Replayed": utils.Schema{"schema": utils.Schema{"type": "boolean"}, "description": "Set when the response is a replay"},
+		}
+	}
+	if codes := errors[http.StatusTooManyRequests]; len(codes) == 1 && codes[0] == engine.ErrorResendCooldown {
+		responses["429"].(utils.Schema)["headers"] = utils.Schema{
+			"Retry-After": utils.Schema{"schema": utils.Schema{"type": "integer"}, "description": "Seconds until a resend is allowed"},
+		}
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// successResponse wraps the data schema in the response envelope
+func successResponse(registry *utils.SchemaRegistry, data interface{}) utils.Schema {
+	return utils.Schema{
+		"description": "Success",
+		"content": utils.Schema{
+			"application/json": utils.Schema{
+				"schema": utils.Schema{
+					"allOf": []utils.Schema{
+						{"$ref": "#/components/schemas/APIResponse"},
+						{"type": "object", "properties": utils.Schema{"data": registry.Ref(data)}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func errorResponse(codes []engine.ErrorCode) utils.Schema {
+	names := make([]string, len(codes))
+	for i, code := range codes {
+		names[i] = "`" + string(code) + "`"
+	}
+
+	return utils.Schema{
+		"description": "Error codes: " + strings.Join(names, ", "),
+		"content": utils.Schema{
+			"application/json": utils.Schema{
+				"schema": utils.Schema{"$ref": "#/components/schemas/APIResponse"},
+			},
+			middleware.ProblemContentType: utils.Schema{
+				"schema": utils.Schema{"$ref": "#/components/schemas/Problem"},
+			},
+		},
+	}
+}
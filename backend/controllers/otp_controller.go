@@ -1,16 +1,16 @@
 package controllers
 
 import (
-	"crypto/rand"
+	"context"
 	"fmt"
-	"math/big"
-	"net/http"
-	"otp-backend/config"
-	"otp-backend/models"
-	"otp-backend/utils"
 	"os"
 	"time"
 
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/Avinashkr000/otp-verification-system/backend/webhooks"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -19,17 +19,76 @@ import (
 type GenerateOTPRequest struct {
 	Email string `json:"email" binding:"omitempty,email"`
 	Phone string `json:"phone" binding:"omitempty,min=10,max=15"`
+	// Purpose is what the code authorizes; defaults to "login"
+	Purpose string `json:"purpose" binding:"omitempty,oneof=login signup password_reset transaction"`
+	// Transaction is required for purpose "transaction"; the OTP is bound to it
+	Transaction *engine.TransactionContext `json:"transaction"`
+	// MagicLink also emails a single-use link that verifies without typing the code
+	MagicLink bool   `json:"magic_link"`
+	ReturnURL string `json:"return_url" binding:"omitempty,url,max=512"`
+	// Push also asks the user's enrolled devices to approve the request
+	Push bool `json:"push"`
+	// CaptchaToken is required when bot protection decides to challenge
+	CaptchaToken string `json:"captcha_token"`
 }
 
 // VerifyOTPRequest represents the request body for OTP verification
 type VerifyOTPRequest struct {
-	OTPID   string `json:"otp_id" binding:"required"`
-	OTPCode string `json:"otp_code" binding:"required,len=6"`
+	OTPID string `json:"otp_id" binding:"required"`
+	// OTPCode is matched ignoring case and separators such as "123-456"
+	OTPCode string `json:"otp_code" binding:"required,min=4,max=32"`
+	// Purpose must match the purpose the OTP was generated for
+	Purpose string `json:"purpose" binding:"omitempty,oneof=login signup password_reset transaction"`
+	// TransactionHash must match the transaction the OTP was issued for.
+	// Clients may send Transaction instead and let the server hash it.
+	TransactionHash string                     `json:"transaction_hash"`
+	Transaction     *engine.TransactionContext `json:"transaction"`
+	// RememberDeviceOptions asks for a device token to skip OTP next time
+	RememberDeviceOptions
 }
 
 // ResendOTPRequest represents the request body for resending OTP
 type ResendOTPRequest struct {
-	OTPID string `json:"otp_id" binding:"required"`
+	OTPID        string `json:"otp_id" binding:"required"`
+	CaptchaToken string `json:"captcha_token"`
+}
+
+// OTPIssuedResponse is the data returned by GenerateOTP and ResendOTP
+type OTPIssuedResponse struct {
+	OTPID string `json:"otp_id"`
+	// StatusToken authorizes status lookups and cancellation of this OTP
+	StatusToken     string     `json:"status_token"`
+	Purpose         string     `json:"purpose"`
+	CodeFormat      CodeFormat `json:"code_format"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	SMSStatus       string     `json:"sms_status"`
+	TransactionHash string     `json:"transaction_hash,omitempty"`
+	MagicLinkSent   bool       `json:"magic_link_sent,omitempty"`
+	PushStatus      string     `json:"push_status,omitempty"`
+	// OTPCode and MagicLink are only returned outside production
+	OTPCode   string `json:"otp_code,omitempty"`
+	MagicLink string `json:"magic_link,omitempty"`
+}
+
+// VerifyOTPResponse is the data returned by VerifyOTP. Session and Device
+// are only set for login and signup codes.
+type VerifyOTPResponse struct {
+	Verified  bool      `json:"verified"`
+	Purpose   string    `json:"purpose"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Timestamp time.Time `json:"timestamp"`
+	*VerificationToken
+	Session *SessionTokens      `json:"session,omitempty"`
+	Device  *TrustedDeviceToken `json:"device,omitempty"`
+}
+
+// CodeFormat describes the code so clients can render a matching input
+type CodeFormat struct {
+	Format    string `json:"format"`
+	Length    int    `json:"length"`
+	GroupSize int    `json:"group_size"`
 }
 
 // GenerateOTP generates a new OTP and sends it to the user
@@ -37,249 +96,213 @@ func GenerateOTP(c *gin.Context) {
 	var req GenerateOTPRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
-		})
+		respondBindError(c, &req, err)
 		return
 	}
 
-	// Validate that at least email or phone is provided
-	if req.Email == "" && req.Phone == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Either email or phone number is required",
-		})
+	data, err := IssueOTP(c.Request.Context(), clientInfo(c), req)
+	if err != nil {
+		respondOTPError(c, err)
 		return
 	}
 
-	// Check rate limiting (max 3 OTP requests per hour)
-	var count int64
-	oneHourAgo := time.Now().Add(-1 * time.Hour)
+	respond(c, "OTP sent successfully", data)
+}
 
-	query := config.DB.Model(&models.OTP{}).Where("created_at > ?", oneHourAgo)
+// IssueOTP generates a new OTP for a validated request and delivers it.
+// It backs GenerateOTP and the gRPC Generate call.
+func IssueOTP(ctx context.Context, client ClientInfo, req GenerateOTPRequest) (*OTPIssuedResponse, *OTPError) {
+	issued, err := otpEngine().Generate(ctx, engine.GenerateRequest{
+		Email:       req.Email,
+		Phone:       req.Phone,
+		Purpose:     req.Purpose,
+		Transaction: req.Transaction,
+		MagicLink:   req.MagicLink,
+		ReturnURL:   req.ReturnURL,
+		ClientIP:    client.IP,
+		Precheck:    challengePrecheck(client, req.CaptchaToken),
+	})
+	if err != nil {
+		return nil, engine.AsError(err)
+	}
 
-	if req.Email != "" {
-		query = query.Where("email = ?", req.Email)
-	} else {
-		query = query.Where("phone = ?", req.Phone)
+	responseData := issuedResponse(issued)
+	if req.Push {
+		responseData.PushStatus = createPushChallenge(issued.OTP)
 	}
+	return responseData, nil
+}
 
-	query.Count(&count)
+// VerifyOTP verifies the provided OTP code
+func VerifyOTP(c *gin.Context) {
+	var req VerifyOTPRequest
 
-	if count >= 3 {
-		c.JSON(http.StatusTooManyRequests, gin.H{
-			"success": false,
-			"message": "Too many OTP requests. Please try again after an hour",
-		})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
 		return
 	}
 
-	// Generate 6-digit OTP
-	otpCode, err := generateSecureOTP(6)
+	data, err := CheckOTP(c.Request.Context(), clientInfo(c), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to generate OTP",
-			"error":   err.Error(),
-		})
+		respondOTPError(c, err)
 		return
 	}
 
-	// Create OTP record
-	otp := models.OTP{
-		ID:           uuid.New().String(),
-		Email:        req.Email,
-		Phone:        req.Phone,
-		OTPCode:      otpCode,
-		IsVerified:   false,
-		AttemptCount: 0,
-		ExpiresAt:    time.Now().Add(5 * time.Minute), // OTP valid for 5 minutes
-	}
+	respond(c, "OTP verified successfully", data)
+}
 
-	if err := config.DB.Create(&otp).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to save OTP",
-			"error":   err.Error(),
-		})
-		return
+// CheckOTP verifies a code for a validated request. Login and signup codes
+// also start a session. It backs VerifyOTP and the gRPC Verify call.
+func CheckOTP(ctx context.Context, client ClientInfo, req VerifyOTPRequest) (*VerifyOTPResponse, *OTPError) {
+	otp, err := otpEngine().Verify(ctx, engine.VerifyRequest{
+		OTPID:           req.OTPID,
+		Code:            req.OTPCode,
+		Purpose:         req.Purpose,
+		TransactionHash: req.TransactionHash,
+		Transaction:     req.Transaction,
+	})
+	if err != nil {
+		return nil, engine.AsError(err)
 	}
 
-	// Send OTP via SMS if phone number is provided
-	var smsStatus string = "not_sent"
-	if req.Phone != "" {
-		// Check if Twilio is configured
-		if os.Getenv("TWILIO_ACCOUNT_SID") != "" && os.Getenv("TWILIO_AUTH_TOKEN") != "" && os.Getenv("TWILIO_PHONE_NUMBER") != "" {
-			fmt.Printf("\n📱 SMS Sending Process Started...\n")
-			fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-			fmt.Printf("📤 Destination: %s\n", req.Phone)
-			fmt.Printf("🔐 OTP Code: %s\n", otpCode)
-			fmt.Printf("🔑 Twilio SID: %s...\n", os.Getenv("TWILIO_ACCOUNT_SID")[:10])
-			fmt.Printf("📞 From Number: %s\n", os.Getenv("TWILIO_PHONE_NUMBER"))
-			fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-			// Send via Twilio
-			if err := utils.SendOTPSMS(req.Phone, otpCode); err != nil {
-				fmt.Printf("\n❌ SMS Delivery Failed!\n")
-				fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-				fmt.Printf("Error: %v\n", err)
-				fmt.Printf("\n💡 Possible Reasons:\n")
-				fmt.Printf("   1. Phone number not verified (Trial Account)\n")
-				fmt.Printf("   2. Invalid Twilio credentials\n")
-				fmt.Printf("   3. Insufficient Twilio credits\n")
-				fmt.Printf("   4. Wrong phone number format\n")
-				fmt.Printf("\n🔧 Solutions:\n")
-				fmt.Printf("   1. Verify phone at: https://console.twilio.com/\n")
-				fmt.Printf("   2. Check .env Twilio credentials\n")
-				fmt.Printf("   3. Ensure phone format: +919876543210\n")
-				fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
-				smsStatus = "failed"
-			} else {
-				fmt.Printf("\n✅ SMS Sent Successfully!\n")
-				fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-				fmt.Printf("✓ Message queued for delivery\n")
-				fmt.Printf("✓ User will receive SMS shortly\n")
-				fmt.Printf("✓ Check Twilio Console for delivery status\n")
-				fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
-				smsStatus = "sent"
-			}
-		} else {
-			fmt.Printf("\n⚠️  Twilio Configuration Missing!\n")
-			fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-			fmt.Printf("Required in .env file:\n")
-			fmt.Printf("  TWILIO_ACCOUNT_SID=ACxxxxxxxxxx\n")
-			fmt.Printf("  TWILIO_AUTH_TOKEN=your_token\n")
-			fmt.Printf("  TWILIO_PHONE_NUMBER=+1234567890\n")
-			fmt.Printf("\n📚 Setup Guide: TWILIO_SETUP.md\n")
-			fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
-			smsStatus = "twilio_not_configured"
-		}
+	// Only signup codes open accounts, so an address without one stays
+	// unknown, as it does for /api/auth/login
+	user := verifiedUser(otp)
+	if user == nil {
+		user = &models.User{Email: otp.Email, Phone: otp.Phone}
 	}
 
-	// Send OTP via Email if email is provided
-	if req.Email != "" {
-		fmt.Printf("\n📧 Email OTP Feature\n")
-		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-		fmt.Printf("Status: Not yet implemented\n")
-		fmt.Printf("TODO: Integrate SendGrid/AWS SES/Gmail\n")
-		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	fmt.Printf("\n✅ OTP VERIFIED SUCCESSFULLY!\n")
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("User ID: %s\n", user.ID)
+	fmt.Printf("Email: %s\n", user.Email)
+	fmt.Printf("Phone: %s\n", user.Phone)
+	fmt.Printf("Verified At: %s\n", otp.VerifiedAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+
+	responseData := VerifyOTPResponse{
+		Verified:  true,
+		Purpose:   otp.Purpose,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		Timestamp: *otp.VerifiedAt,
 	}
 
-	// Log for development
-	fmt.Printf("\n═══════════════════════════════════════════\n")
-	fmt.Printf("         🔐 OTP GENERATED                 \n")
-	fmt.Printf("═══════════════════════════════════════════\n")
-	fmt.Printf("OTP ID:      %s\n", otp.ID)
-	fmt.Printf("OTP Code:    %s\n", otpCode)
-	fmt.Printf("Email:       %s\n", req.Email)
-	fmt.Printf("Phone:       %s\n", req.Phone)
-	fmt.Printf("SMS Status:  %s\n", smsStatus)
-	fmt.Printf("Expires At:  %s\n", otp.ExpiresAt.Format("2006-01-02 15:04:05"))
-	fmt.Printf("Valid For:   5 minutes\n")
-	fmt.Printf("═══════════════════════════════════════════\n\n")
-
-	// Response data
-	responseData := gin.H{
-		"otp_id":     otp.ID,
-		"expires_at": otp.ExpiresAt,
-		"sms_status": smsStatus,
+	// Issue a signed verification token other services can check offline.
+	// It names the user, so an address without an account gets none.
+	if user.ID != "" {
+		channel, identifier := otpChannel(otp.Email, otp.Phone)
+		responseData.VerificationToken = issueVerificationToken(user.ID, utils.VerificationClaims{
+			Channel:         channel,
+			Identifier:      identifier,
+			Purpose:         otp.Purpose,
+			TransactionHash: otp.TransactionHash,
+		})
 	}
 
-	// Only include OTP code in development mode
-	if os.Getenv("ENVIRONMENT") != "production" {
-		responseData["otp_code"] = otpCode
+	// Start a durable session so the user does not need a new OTP every time.
+	// Codes for password resets and transactions authorize only that action.
+	if user.ID != "" && (otp.Purpose == engine.PurposeLogin || otp.Purpose == engine.PurposeSignup) {
+		if session, err := createSession(client, user.ID); err != nil {
+			fmt.Printf("⚠️  Failed to create session: %v\n", err)
+		} else {
+			responseData.Session = session
+		}
+		responseData.Device = trustDevice(client, user.ID, req.RememberDeviceOptions)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "OTP sent successfully",
-		"data":    responseData,
-	})
+	return &responseData, nil
 }
 
-// VerifyOTP verifies the provided OTP code
-func VerifyOTP(c *gin.Context) {
-	var req VerifyOTPRequest
+// ResendOTP resends an OTP
+func ResendOTP(c *gin.Context) {
+	var req ResendOTPRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
-		})
+		respondBindError(c, &req, err)
 		return
 	}
 
-	fmt.Printf("\n🔍 OTP Verification Attempt\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("OTP ID: %s\n", req.OTPID)
-	fmt.Printf("Code Provided: %s\n", req.OTPCode)
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-	// Find OTP record
-	var otp models.OTP
-	if err := config.DB.Where("id = ?", req.OTPID).First(&otp).Error; err != nil {
-		fmt.Printf("❌ OTP not found in database\n\n")
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"message": "OTP not found",
-		})
+	data, err := ReissueOTP(c.Request.Context(), clientInfo(c), req)
+	if err != nil {
+		respondOTPError(c, err)
 		return
 	}
 
-	// Check if OTP is already verified
-	if otp.IsVerified {
-		fmt.Printf("❌ OTP already used\n\n")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "OTP already verified",
-		})
-		return
+	respond(c, "OTP resent successfully", data)
+}
+
+// ReissueOTP replaces an OTP with a new code sent to the same destination.
+// It backs ResendOTP and the gRPC Resend call.
+func ReissueOTP(ctx context.Context, client ClientInfo, req ResendOTPRequest) (*OTPIssuedResponse, *OTPError) {
+	issued, err := otpEngine().Resend(ctx, engine.ResendRequest{
+		OTPID:    req.OTPID,
+		ClientIP: client.IP,
+		Precheck: challengePrecheck(client, req.CaptchaToken),
+	})
+	if err != nil {
+		return nil, engine.AsError(err)
 	}
 
-	// Check if OTP has expired
-	if time.Now().After(otp.ExpiresAt) {
-		fmt.Printf("❌ OTP expired at: %s\n\n", otp.ExpiresAt.Format("2006-01-02 15:04:05"))
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "OTP has expired",
-		})
-		return
+	return issuedResponse(issued), nil
+}
+
+// issuedResponse describes a new OTP to the client that requested it
+func issuedResponse(issued *engine.Issued) *OTPIssuedResponse {
+	responseData := &OTPIssuedResponse{
+		OTPID:           issued.OTP.ID,
+		StatusToken:     issued.StatusToken,
+		Purpose:         issued.OTP.Purpose,
+		CodeFormat:      codeFormat(issued.Policy),
+		ExpiresAt:       issued.OTP.ExpiresAt,
+		SMSStatus:       issued.DeliveryStatus,
+		TransactionHash: issued.OTP.TransactionHash,
+		MagicLinkSent:   issued.MagicLinkSent,
 	}
 
-	// Check maximum attempts (3 attempts allowed)
-	if otp.AttemptCount >= 3 {
-		fmt.Printf("❌ Maximum attempts exceeded\n\n")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Maximum verification attempts exceeded",
-		})
-		return
+	// Only include OTP code in development mode
+	if os.Getenv("ENVIRONMENT") != "production" {
+		responseData.OTPCode = issued.Code
+		responseData.MagicLink = issued.MagicLink
 	}
 
-	// Increment attempt count
-	otp.AttemptCount++
-	config.DB.Save(&otp)
+	return responseData
+}
 
-	// Verify OTP code
-	if otp.OTPCode != req.OTPCode {
-		fmt.Printf("❌ Invalid OTP code. Attempts remaining: %d\n\n", 3-otp.AttemptCount)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": fmt.Sprintf("Invalid OTP code. %d attempts remaining", 3-otp.AttemptCount),
-		})
-		return
+// verifiedUser returns the user whose email or phone an OTP proves, or nil
+// when there is none. Only a signup code creates the user, and only signup
+// and login codes mark the address verified. Password reset and
+// transaction codes authorize one action and leave the user unchanged.
+func verifiedUser(otp *models.OTP) *models.User {
+	if otp.Purpose == engine.PurposeSignup {
+		user := upsertVerifiedUser(otp)
+		return &user
 	}
 
-	// Mark OTP as verified
-	now := time.Now()
-	otp.IsVerified = true
-	otp.VerifiedAt = &now
-	config.DB.Save(&otp)
+	var user models.User
+	query := config.DB
+	column := "is_email_verified"
+	if otp.Email != "" {
+		query = query.Where("email = ?", otp.Email)
+	} else {
+		query = query.Where("phone = ?", otp.Phone)
+		column = "is_phone_verified"
+	}
+	if err := query.First(&user).Error; err != nil {
+		return nil
+	}
+
+	if otp.Purpose == engine.PurposeLogin {
+		config.DB.Model(&user).Update(column, true)
+	}
+	return &user
+}
 
-	// Update or create user record
+// upsertVerifiedUser marks the OTP's email or phone as verified on the
+// matching user, creating the user if needed. Only flows that open
+// accounts, signup and OpenID Connect sign-in, may call it.
+func upsertVerifiedUser(otp *models.OTP) models.User {
 	var user models.User
 	var userExists bool
 
@@ -308,156 +331,21 @@ func VerifyOTP(c *gin.Context) {
 			IsPhoneVerified: otp.Phone != "",
 		}
 		config.DB.Create(&user)
-	}
-
-	fmt.Printf("\n✅ OTP VERIFIED SUCCESSFULLY!\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("User ID: %s\n", user.ID)
-	fmt.Printf("Email: %s\n", user.Email)
-	fmt.Printf("Phone: %s\n", user.Phone)
-	fmt.Printf("Verified At: %s\n", now.Format("2006-01-02 15:04:05"))
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "OTP verified successfully",
-		"data": gin.H{
-			"verified":  true,
-			"user_id":   user.ID,
-			"email":     user.Email,
-			"phone":     user.Phone,
-			"timestamp": now,
-		},
-	})
-}
-
-// ResendOTP resends an OTP
-func ResendOTP(c *gin.Context) {
-	var req ResendOTPRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
+		publishEvent(webhooks.EventUserCreated, map[string]interface{}{
+			"user_id": user.ID,
+			"email":   user.Email,
+			"phone":   user.Phone,
 		})
-		return
 	}
 
-	fmt.Printf("\n🔄 OTP Resend Request\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("OTP ID: %s\n", req.OTPID)
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-	// Find old OTP record
-	var oldOTP models.OTP
-	if err := config.DB.Where("id = ?", req.OTPID).First(&oldOTP).Error; err != nil {
-		fmt.Printf("❌ OTP not found\n\n")
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"message": "OTP not found",
-		})
-		return
-	}
-
-	// Check if already verified
-	if oldOTP.IsVerified {
-		fmt.Printf("❌ OTP already verified\n\n")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "OTP already verified",
-		})
-		return
-	}
-
-	// Generate new OTP
-	otpCode, err := generateSecureOTP(6)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to generate OTP",
-			"error":   err.Error(),
-		})
-		return
-	}
-
-	// Create new OTP record
-	newOTP := models.OTP{
-		ID:           uuid.New().String(),
-		Email:        oldOTP.Email,
-		Phone:        oldOTP.Phone,
-		OTPCode:      otpCode,
-		IsVerified:   false,
-		AttemptCount: 0,
-		ExpiresAt:    time.Now().Add(5 * time.Minute),
-	}
-
-	if err := config.DB.Create(&newOTP).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to save OTP",
-			"error":   err.Error(),
-		})
-		return
-	}
-
-	// Resend OTP via SMS if phone number is provided
-	var smsStatus string = "not_sent"
-	if oldOTP.Phone != "" {
-		if os.Getenv("TWILIO_ACCOUNT_SID") != "" {
-			fmt.Printf("📤 Resending SMS to: %s\n", oldOTP.Phone)
-			if err := utils.SendOTPSMS(oldOTP.Phone, otpCode); err != nil {
-				fmt.Printf("❌ Failed to resend SMS: %v\n\n", err)
-				smsStatus = "failed"
-			} else {
-				fmt.Printf("✅ SMS resent successfully\n\n")
-				smsStatus = "sent"
-			}
-		}
-	}
-
-	// Log for development
-	fmt.Printf("\n═══════════════════════════════════════════\n")
-	fmt.Printf("         🔁 OTP RESENT                    \n")
-	fmt.Printf("═══════════════════════════════════════════\n")
-	fmt.Printf("New OTP ID:  %s\n", newOTP.ID)
-	fmt.Printf("OTP Code:    %s\n", otpCode)
-	fmt.Printf("Phone:       %s\n", oldOTP.Phone)
-	fmt.Printf("SMS Status:  %s\n", smsStatus)
-	fmt.Printf("Expires At:  %s\n", newOTP.ExpiresAt.Format("2006-01-02 15:04:05"))
-	fmt.Printf("═══════════════════════════════════════════\n\n")
-
-	// Response data
-	responseData := gin.H{
-		"otp_id":     newOTP.ID,
-		"expires_at": newOTP.ExpiresAt,
-		"sms_status": smsStatus,
-	}
-
-	// Only include OTP code in development mode
-	if os.Getenv("ENVIRONMENT") != "production" {
-		responseData["otp_code"] = otpCode
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "OTP resent successfully",
-		"data":    responseData,
-	})
+	return user
 }
 
-// generateSecureOTP generates a cryptographically secure random OTP
-func generateSecureOTP(length int) (string, error) {
-	const digits = "0123456789"
-	otp := make([]byte, length)
-
-	for i := range otp {
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(digits))))
-		if err != nil {
-			return "", err
-		}
-		otp[i] = digits[num.Int64()]
+// codeFormat returns the code format of a policy
+func codeFormat(policy *engine.OTPPolicy) CodeFormat {
+	return CodeFormat{
+		Format:    policy.Format,
+		Length:    policy.Length,
+		GroupSize: policy.GroupSize,
 	}
-
-	return string(otp), nil
 }
@@ -0,0 +1,56 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+)
+
+// The bundled frontend signs up through the original /api routes. The
+// first verification creates the account and later ones sign in to it.
+func TestLegacySignupFlow(t *testing.T) {
+	r := newTestRouter(t)
+
+	verify := func() map[string]interface{} {
+		status, out := doJSON(t, r, http.MethodPost, "/api/otp/generate", map[string]string{"email": "legacy@example.com", "purpose": "signup"}, nil)
+		if status != http.StatusOK || out["success"] != true {
+			t.Fatalf("generate: %d %v", status, out)
+		}
+		issued := data(t, out)
+		status, out = doJSON(t, r, http.MethodPost, "/api/otp/verify", map[string]interface{}{
+			"otp_id":   issued["otp_id"],
+			"otp_code": issued["otp_code"],
+			"purpose":  issued["purpose"],
+		}, nil)
+		if status != http.StatusOK || out["success"] != true {
+			t.Fatalf("verify: %d %v", status, out)
+		}
+		return data(t, out)
+	}
+
+	first := verify()
+	userID, _ := first["user_id"].(string)
+	if userID == "" || first["session"] == nil {
+		t.Fatalf("first verification did not create the account: %v", first)
+	}
+	var user models.User
+	if err := config.DB.Where("id = ?", userID).First(&user).Error; err != nil || !user.IsEmailVerified {
+		t.Errorf("stored user: %+v, %v", user, err)
+	}
+
+	if again := verify(); again["user_id"] != userID {
+		t.Errorf("returning user got user_id %v, want %s", again["user_id"], userID)
+	}
+}
+
+// The default engine has no email sender, so it cannot send magic links
+func TestMagicLinkNeedsEmailSender(t *testing.T) {
+	r := newTestRouter(t)
+
+	status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]interface{}{"email": "user@example.com", "magic_link": true}, nil)
+	if status != http.StatusBadRequest || errorCode(out) != "MAGIC_LINK_UNAVAILABLE" {
+		t.Errorf("got %d %v, want MAGIC_LINK_UNAVAILABLE", status, out)
+	}
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/events"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// otpStreamRecheckInterval is how often an event stream rereads its OTP.
+// The reread catches changes made by other server instances, which the
+// in-process event bus does not see, and keeps the connection alive.
+const otpStreamRecheckInterval = 5 * time.Second
+
+// StreamOTPEvents streams the delivery and verification of an OTP as
+// Server-Sent Events, so a page can react as soon as the code is used on
+// another device. It sends a "status" event with the OTPStatusResponse
+// first and after every change, and each lifecycle event (otp.delivered,
+// otp.verified, ...) as it happens.
+//
+// A resend does not end the stream: it moves on to the new code, whose
+// status then carries the new otp_id. The stream ends once the newest code
+// is no longer pending.
+//
+// EventSource cannot set headers, so the status token may also be sent as
+// the status_token query parameter.
+func StreamOTPEvents(c *gin.Context) {
+	id := c.Param("id")
+	statusToken := c.GetHeader("X-OTP-Status-Token")
+	if statusToken == "" {
+		statusToken = c.Query("status_token")
+	}
+	ctx := c.Request.Context()
+
+	// Subscribe before reading the status so no change falls in between.
+	// Resends add the new code to the OTPs followed by this stream.
+	var mu sync.Mutex
+	followed := map[string]bool{id: true}
+	changes := make(chan events.Event, 16)
+	unsubscribe := eventBus.Subscribe(func(event events.Event) {
+		mu.Lock()
+		replaces, _ := event.Data["replaces"].(string)
+		if replaces != "" && followed[replaces] {
+			followed[event.OTPID] = true
+		}
+		match := event.OTPID != "" && followed[event.OTPID]
+		mu.Unlock()
+		if !match {
+			return
+		}
+		select {
+		case changes <- event:
+		default:
+			// The next recheck sends the current status instead
+		}
+	})
+	defer unsubscribe()
+
+	lookup := func() (*OTPStatusResponse, *OTPError) {
+		otps := otpEngine()
+		otp, err := otps.LookupLatest(ctx, id, statusToken)
+		if err != nil {
+			return nil, engine.AsError(err)
+		}
+		return otpStatus(otps, otp), nil
+	}
+
+	status, err := lookup()
+	if err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	send := func(name string, data interface{}) {
+		c.SSEvent(name, data)
+		c.Writer.Flush()
+	}
+	send("status", status)
+
+	ticker := time.NewTicker(otpStreamRecheckInterval)
+	defer ticker.Stop()
+
+	for status.State == models.OTPStatePending {
+		recheck := false
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-changes:
+			send(event.Type, event.Data)
+		case <-ticker.C:
+			recheck = true
+		}
+
+		latest, err := lookup()
+		if err != nil {
+			return
+		}
+		if statusChanged(status, latest) {
+			send("status", latest)
+		} else if recheck {
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+		status = latest
+	}
+}
+
+// statusChanged reports whether a client would see a difference between
+// two statuses of the same OTP
+func statusChanged(old, latest *OTPStatusResponse) bool {
+	return old.OTPID != latest.OTPID ||
+		old.State != latest.State ||
+		old.AttemptsRemaining != latest.AttemptsRemaining ||
+		old.Delivery != latest.Delivery ||
+		old.SupersededBy != latest.SupersededBy
+}
@@ -0,0 +1,132 @@
+package controllers_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sseEvent struct {
+	Name string
+	Data map[string]interface{}
+}
+
+// openStream opens an OTP's event stream and returns its events as they
+// arrive; the channel is closed when the stream ends
+func openStream(t *testing.T, srv *httptest.Server, otpID, statusToken string) <-chan sseEvent {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/api/v1/otp/" + otpID + "/events?status_token=" + statusToken)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("open stream: %d", resp.StatusCode)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	out := make(chan sseEvent, 32)
+	go func() {
+		defer close(out)
+		scanner := bufio.NewScanner(resp.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				event.Name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:"):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event.Data)
+			case line == "" && event.Name != "":
+				out <- event
+				event = sseEvent{}
+			}
+		}
+	}()
+	return out
+}
+
+// nextEvent waits for the next event with the given name
+func nextEvent(t *testing.T, stream <-chan sseEvent, name string) sseEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				t.Fatalf("stream ended while waiting for %s", name)
+			}
+			if event.Name == name {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", name)
+		}
+	}
+}
+
+func TestStreamFollowsResend(t *testing.T) {
+	t.Setenv("OTP_LOGIN_RESEND_COOLDOWN_SECONDS", "0")
+	r := newTestRouter(t)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	_, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "stream@example.com"}, nil)
+	issued := data(t, out)
+	otpID := issued["otp_id"].(string)
+
+	stream := openStream(t, srv, otpID, issued["status_token"].(string))
+	if status := nextEvent(t, stream, "status"); status.Data["state"] != "pending" {
+		t.Fatalf("initial status: %v", status.Data)
+	}
+
+	code, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/resend", map[string]string{"otp_id": otpID}, nil)
+	if code != http.StatusOK {
+		t.Fatalf("resend: %d %v", code, out)
+	}
+	resent := data(t, out)
+	newID := resent["otp_id"].(string)
+
+	if generated := nextEvent(t, stream, "otp.generated"); generated.Data["replaces"] != otpID {
+		t.Errorf("otp.generated: %v", generated.Data)
+	}
+	if status := nextEvent(t, stream, "status"); status.Data["otp_id"] != newID || status.Data["state"] != "pending" {
+		t.Errorf("status after resend: %v", status.Data)
+	}
+
+	code, out = doJSON(t, r, http.MethodPost, "/api/v1/otp/verify", map[string]string{"otp_id": newID, "otp_code": resent["otp_code"].(string)}, nil)
+	if code != http.StatusOK {
+		t.Fatalf("verify: %d %v", code, out)
+	}
+	nextEvent(t, stream, "otp.verified")
+	if status := nextEvent(t, stream, "status"); status.Data["otp_id"] != newID || status.Data["state"] != "verified" {
+		t.Errorf("final status: %v", status.Data)
+	}
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-stream:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("stream did not end after verification")
+		}
+	}
+}
+
+func TestStreamRequiresStatusToken(t *testing.T) {
+	r := newTestRouter(t)
+
+	_, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "stream@example.com"}, nil)
+	otpID := data(t, out)["otp_id"].(string)
+
+	code, _ := doJSON(t, r, http.MethodGet, "/api/v1/otp/"+otpID+"/events?status_token=wrong", nil, nil)
+	if code != http.StatusNotFound {
+		t.Errorf("got %d, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RevokeOTPsRequest represents the request body for an administrative revoke
+type RevokeOTPsRequest struct {
+	Email   string `json:"email" binding:"omitempty,email"`
+	Phone   string `json:"phone" binding:"omitempty,min=10,max=15"`
+	Purpose string `json:"purpose" binding:"omitempty,max=32"`
+	Reason  string `json:"reason" binding:"omitempty,max=64"`
+}
+
+// OTPCancelledResponse is the data returned by CancelOTP
+type OTPCancelledResponse struct {
+	OTPID     string     `json:"otp_id"`
+	State     string     `json:"state" enum:"revoked"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// RevokeOTPsResponse is the data returned by RevokeOTPs
+type RevokeOTPsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// CancelOTP revokes a pending OTP when the user backs out of the flow.
+// Like the status lookup it requires the X-OTP-Status-Token header.
+// Cancelling an OTP twice is not an error.
+func CancelOTP(c *gin.Context) {
+	data, err := CancelOwnedOTP(c.Request.Context(), c.Param("id"), c.GetHeader("X-OTP-Status-Token"))
+	if err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	respond(c, "OTP cancelled", data)
+}
+
+// CancelOwnedOTP revokes a pending OTP for the holder of its status token.
+// It backs CancelOTP and the gRPC Cancel call.
+func CancelOwnedOTP(ctx context.Context, id, statusToken string) (*OTPCancelledResponse, *OTPError) {
+	otp, err := otpEngine().Cancel(ctx, id, statusToken)
+	if err != nil {
+		return nil, engine.AsError(err)
+	}
+
+	return &OTPCancelledResponse{
+		OTPID:     otp.ID,
+		State:     models.OTPStateRevoked,
+		RevokedAt: otp.RevokedAt,
+	}, nil
+}
+
+// RevokeOTPs revokes every pending OTP for an email or phone number,
+// optionally limited to one purpose. Used by support staff, e.g. when an
+// account is reported as compromised.
+func RevokeOTPs(c *gin.Context) {
+	var req RevokeOTPsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, &req, err)
+		return
+	}
+
+	revoked, err := otpEngine().Revoke(c.Request.Context(), engine.RevokeRequest{
+		Email:   req.Email,
+		Phone:   req.Phone,
+		Purpose: req.Purpose,
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		respondOTPError(c, engine.AsError(err))
+		return
+	}
+
+	respond(c, "Pending OTPs revoked", RevokeOTPsResponse{
+		Revoked: revoked,
+	})
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/events"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// customEngine replaces the default engine when set with SetOTPEngine
+var customEngine *engine.Engine
+
+// eventBus carries OTP and user lifecycle events to webhooks and to
+// clients streaming an OTP's status
+var eventBus = events.NewBus()
+
+// SetOTPEngine replaces the engine behind the OTP endpoints, e.g. to plug
+// in other senders. nil restores the default.
+func SetOTPEngine(e *engine.Engine) {
+	customEngine = e
+}
+
+// otpEngine returns the engine set with SetOTPEngine, or one on the
+// server's database that sends SMS through Twilio
+func otpEngine() *engine.Engine {
+	if customEngine != nil {
+		return customEngine
+	}
+	return engine.New(engine.Config{
+		DB:        config.DB,
+		Policy:    utils.GetOTPPolicy,
+		SMS:       utils.TwilioSender{},
+		MagicLink: buildMagicLink,
+		AllowReturnURL: func(url string) bool {
+			return utils.GetMagicLinkConfig().IsAllowedReturnURL(url)
+		},
+		OnEvent: publishOTPEvent,
+		Logger:  logf,
+	})
+}
+
+// logf prints an engine log line to the console
+func logf(format string, args ...interface{}) {
+	fmt.Printf(format, args...)
+}
+
+// StartExpiryWatcher reports codes that expire unused, once a minute
+func StartExpiryWatcher() {
+	go func() {
+		for range time.Tick(time.Minute) {
+			if _, err := otpEngine().ReportExpired(context.Background()); err != nil {
+				fmt.Printf("⚠️  Failed to check for expired OTPs: %v\n", err)
+			}
+		}
+	}()
+}
+
+// ClientInfo identifies the client calling an OTP operation. The HTTP
+// handlers take it from the request; other transports fill it in from
+// their own connection metadata.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// OTPError is a failed OTP operation
+type OTPError = engine.Error
+
+func otpError(status int, code engine.ErrorCode, message string, details gin.H) *OTPError {
+	return engine.NewError(status, code, message, details)
+}
+
+// ValidateRequest applies the binding rules of a request struct, as
+// ShouldBindJSON does, for requests that were not decoded by gin
+func ValidateRequest(req interface{}) *OTPError {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return validationError(req, err)
+	}
+	return nil
+}
+
+// validationError lists the fields of req that failed validation by their
+// JSON names
+func validationError(req interface{}, err error) *OTPError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return otpError(http.StatusBadRequest, engine.ErrorValidationFailed, "Invalid request data", gin.H{"reason": err.Error()})
+	}
+
+	fields := make([]gin.H, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		field := gin.H{
+			"field": jsonFieldPath(reflect.TypeOf(req), fe.StructNamespace()),
+			"rule":  fe.Tag(),
+		}
+		if fe.Param() != "" {
+			field["param"] = fe.Param()
+		}
+		fields = append(fields, field)
+	}
+	return otpError(http.StatusBadRequest, engine.ErrorValidationFailed, "Invalid request data", gin.H{"fields": fields})
+}
+
+// clientInfo describes the client of an HTTP request
+func clientInfo(c *gin.Context) ClientInfo {
+	return ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+}
+
+// challengePrecheck runs bot protection once the engine knows the
+// destination of a code
+func challengePrecheck(client ClientInfo, token string) func(email, phone string) error {
+	return func(email, phone string) error {
+		if err := checkChallenge(client, email, phone, token); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// recentOTPCount returns how many OTPs were requested for the email or
+// phone within the last hour
+func recentOTPCount(email, phone string) int64 {
+	return otpEngine().RecentCount(context.Background(), email, phone)
+}
+
+// publishEvent publishes a user event on the event bus
+func publishEvent(eventType string, data map[string]interface{}) {
+	eventBus.Publish(events.Event{Type: eventType, Data: data})
+}
+
+// publishOTPEvent publishes an engine event on the event bus, adding the
+// OTP's purpose and destination to its data
+func publishOTPEvent(_ context.Context, event engine.Event) {
+	data := map[string]interface{}{
+		"otp_id":  event.OTP.ID,
+		"purpose": event.OTP.Purpose,
+		"email":   event.OTP.Email,
+		"phone":   event.OTP.Phone,
+	}
+	for key, value := range event.Data {
+		data[key] = value
+	}
+	eventBus.Publish(events.Event{Type: event.Type, OTPID: event.OTP.ID, Data: data, At: event.At})
+}
+
+// respondOTPError writes a failed OTP operation as an HTTP error response
+func respondOTPError(c *gin.Context, err *OTPError) {
+	if err.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(err.RetryAfter))
+	}
+	respondError(c, err.Status, err.Code, err.Message, err.Details)
+}
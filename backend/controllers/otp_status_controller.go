@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// OTPStatusResponse is the data returned by GetOTPStatus
+type OTPStatusResponse struct {
+	OTPID             string     `json:"otp_id"`
+	Purpose           string     `json:"purpose"`
+	State             string     `json:"state" enum:"pending,verified,expired,locked,superseded,revoked"`
+	AttemptsRemaining int        `json:"attempts_remaining"`
+	MaxAttempts       int        `json:"max_attempts"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+	// ResendAvailableAt is null when the OTP can no longer be resent
+	ResendAvailableAt *time.Time  `json:"resend_available_at"`
+	RevokedAt         *time.Time  `json:"revoked_at,omitempty"`
+	SupersededBy      string      `json:"superseded_by,omitempty"`
+	Delivery          OTPDelivery `json:"delivery"`
+}
+
+// OTPDelivery reports how an OTP was sent
+type OTPDelivery struct {
+	Channel string `json:"channel" enum:"email,sms"`
+	Status  string `json:"status"`
+}
+
+// GetOTPStatus reports the state of an OTP to the client that requested
+// it. The status token from /generate or /resend must be sent in the
+// X-OTP-Status-Token header. The code itself is never returned.
+func GetOTPStatus(c *gin.Context) {
+	data, err := LookupOTPStatus(c.Request.Context(), c.Param("id"), c.GetHeader("X-OTP-Status-Token"))
+	if err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	respond(c, "", data)
+}
+
+// LookupOTPStatus reports the state of an OTP to the holder of its status
+// token. It backs GetOTPStatus and the gRPC GetStatus call.
+func LookupOTPStatus(ctx context.Context, id, statusToken string) (*OTPStatusResponse, *OTPError) {
+	otps := otpEngine()
+	otp, err := otps.Lookup(ctx, id, statusToken)
+	if err != nil {
+		return nil, engine.AsError(err)
+	}
+	return otpStatus(otps, otp), nil
+}
+
+// otpStatus describes an OTP as GetOTPStatus reports it, with the resend
+// cooldown of the engine that issued it
+func otpStatus(otps *engine.Engine, otp *models.OTP) *OTPStatusResponse {
+	now := time.Now()
+	state := otp.State(now)
+	channel, _ := otpChannel(otp.Email, otp.Phone)
+
+	data := OTPStatusResponse{
+		OTPID:             otp.ID,
+		Purpose:           otp.Purpose,
+		State:             state,
+		AttemptsRemaining: max(otp.MaxAttempts-otp.AttemptCount, 0),
+		MaxAttempts:       otp.MaxAttempts,
+		CreatedAt:         otp.CreatedAt,
+		ExpiresAt:         otp.ExpiresAt,
+		VerifiedAt:        otp.VerifiedAt,
+		Delivery: OTPDelivery{
+			Channel: channel,
+			Status:  otp.DeliveryStatus,
+		},
+	}
+
+	// Verified, revoked and replaced codes cannot be resent
+	if state != models.OTPStateVerified && state != models.OTPStateRevoked && state != models.OTPStateSuperseded {
+		if policy, err := otps.Policy(otp.Purpose); err == nil {
+			resendAvailableAt := otp.CreatedAt.Add(policy.ResendCooldown)
+			data.ResendAvailableAt = &resendAvailableAt
+		}
+	}
+	if state == models.OTPStateRevoked {
+		data.RevokedAt = otp.RevokedAt
+	}
+	if state == models.OTPStateSuperseded {
+		data.SupersededBy = otp.SupersededBy
+	}
+
+	return &data
+}
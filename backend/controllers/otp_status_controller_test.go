@@ -0,0 +1,185 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// generateOTP requests a code for email and returns the issued data
+func generateOTP(t *testing.T, r *gin.Engine, email string) map[string]interface{} {
+	t.Helper()
+	status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": email}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("generate: %d %v", status, out)
+	}
+	return data(t, out)
+}
+
+func statusToken(issued map[string]interface{}) map[string]string {
+	return map[string]string{"X-OTP-Status-Token": issued["status_token"].(string)}
+}
+
+func storedOTP(t *testing.T, id interface{}) models.OTP {
+	t.Helper()
+	var otp models.OTP
+	if err := config.DB.Where("id = ?", id).First(&otp).Error; err != nil {
+		t.Fatalf("load OTP: %v", err)
+	}
+	return otp
+}
+
+func TestOTPStatusNeedsStatusToken(t *testing.T) {
+	r := newTestRouter(t)
+	issued := generateOTP(t, r, "status@example.com")
+	id := issued["otp_id"].(string)
+
+	for name, request := range map[string]struct {
+		method, path string
+		headers      map[string]string
+	}{
+		"unknown OTP":       {http.MethodGet, "/api/v1/otp/00000000-0000-0000-0000-000000000000", statusToken(issued)},
+		"wrong token":       {http.MethodGet, "/api/v1/otp/" + id, map[string]string{"X-OTP-Status-Token": "wrong"}},
+		"missing token":     {http.MethodGet, "/api/v1/otp/" + id, nil},
+		"cancel, no token":  {http.MethodPost, "/api/v1/otp/" + id + "/cancel", nil},
+		"cancel, bad token": {http.MethodPost, "/api/v1/otp/" + id + "/cancel", map[string]string{"X-OTP-Status-Token": "wrong"}},
+	} {
+		status, out := doJSON(t, r, request.method, request.path, nil, request.headers)
+		if status != http.StatusNotFound || errorCode(out) != string(engine.ErrorOTPNotFound) {
+			t.Errorf("%s: got %d %v, want 404 OTP_NOT_FOUND", name, status, out)
+		}
+	}
+	if otp := storedOTP(t, id); otp.RevokedAt != nil {
+		t.Error("OTP cancelled without its status token")
+	}
+
+	status, out := doJSON(t, r, http.MethodGet, "/api/v1/otp/"+id, nil, statusToken(issued))
+	if status != http.StatusOK || data(t, out)["state"] != models.OTPStatePending {
+		t.Errorf("status: %d %v", status, out)
+	}
+}
+
+// The resend cooldown comes from the engine serving the request, not
+// from the environment
+func TestOTPStatusUsesEnginePolicy(t *testing.T) {
+	r := newTestRouter(t)
+	controllers.SetOTPEngine(engine.New(engine.Config{
+		DB: config.DB,
+		Policy: func(purpose string) (*engine.OTPPolicy, error) {
+			policy, err := engine.DefaultPolicy(purpose)
+			if err != nil {
+				return nil, err
+			}
+			policy.ResendCooldown = 7 * time.Minute
+			return policy, nil
+		},
+	}))
+	t.Cleanup(func() { controllers.SetOTPEngine(nil) })
+
+	issued := generateOTP(t, r, "policy@example.com")
+	_, out := doJSON(t, r, http.MethodGet, "/api/v1/otp/"+issued["otp_id"].(string), nil, statusToken(issued))
+	otp := storedOTP(t, issued["otp_id"])
+	available, err := time.Parse(time.RFC3339Nano, data(t, out)["resend_available_at"].(string))
+	if err != nil {
+		t.Fatalf("resend_available_at: %v", err)
+	}
+	if cooldown := available.Sub(otp.CreatedAt); cooldown.Round(time.Second) != 7*time.Minute {
+		t.Errorf("resend available after %s, want 7m", cooldown)
+	}
+}
+
+func TestCancelOTP(t *testing.T) {
+	r := newTestRouter(t)
+	issued := generateOTP(t, r, "cancel@example.com")
+	path := "/api/v1/otp/" + issued["otp_id"].(string)
+
+	status, out := doJSON(t, r, http.MethodPost, path+"/cancel", nil, statusToken(issued))
+	if status != http.StatusOK || data(t, out)["state"] != models.OTPStateRevoked {
+		t.Fatalf("cancel: %d %v", status, out)
+	}
+	if otp := storedOTP(t, issued["otp_id"]); otp.RevokeReason != "cancelled" {
+		t.Errorf("revoke reason %q, want cancelled", otp.RevokeReason)
+	}
+
+	// Cancelling twice is not an error
+	if status, out := doJSON(t, r, http.MethodPost, path+"/cancel", nil, statusToken(issued)); status != http.StatusOK {
+		t.Errorf("second cancel: %d %v", status, out)
+	}
+
+	_, out = doJSON(t, r, http.MethodGet, path, nil, statusToken(issued))
+	if state := data(t, out); state["state"] != models.OTPStateRevoked || state["resend_available_at"] != nil {
+		t.Errorf("status after cancel: %v", state)
+	}
+	status, out = doJSON(t, r, http.MethodPost, "/api/v1/otp/verify", map[string]interface{}{
+		"otp_id":   issued["otp_id"],
+		"otp_code": issued["otp_code"],
+	}, nil)
+	if status != http.StatusGone || errorCode(out) != string(engine.ErrorOTPRevoked) {
+		t.Errorf("verify after cancel: %d %v", status, out)
+	}
+}
+
+func TestCancelVerifiedOTP(t *testing.T) {
+	r := newTestRouter(t)
+	issued := generateOTP(t, r, "verified@example.com")
+	path := "/api/v1/otp/" + issued["otp_id"].(string)
+	if status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/verify", map[string]interface{}{
+		"otp_id":   issued["otp_id"],
+		"otp_code": issued["otp_code"],
+	}, nil); status != http.StatusOK {
+		t.Fatalf("verify: %d %v", status, out)
+	}
+
+	status, out := doJSON(t, r, http.MethodPost, path+"/cancel", nil, statusToken(issued))
+	if status != http.StatusConflict || errorCode(out) != string(engine.ErrorOTPAlreadyVerified) {
+		t.Errorf("cancel: got %d %v, want 409 OTP_ALREADY_VERIFIED", status, out)
+	}
+
+	_, out = doJSON(t, r, http.MethodGet, path, nil, statusToken(issued))
+	if state := data(t, out); state["state"] != models.OTPStateVerified || state["resend_available_at"] != nil {
+		t.Errorf("status after verification: %v", state)
+	}
+}
+
+func TestRevokeOTPs(t *testing.T) {
+	r := newTestRouter(t)
+	t.Setenv("ADMIN_API_KEY", "admin-key")
+	admin := map[string]string{"X-Admin-Key": "admin-key"}
+
+	if status, _ := doJSON(t, r, http.MethodPost, "/api/v1/otp/revoke", map[string]string{"email": "a@example.com"}, nil); status != http.StatusUnauthorized {
+		t.Errorf("revoke without the admin key got %d, want 401", status)
+	}
+	status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/revoke", map[string]string{"email": "a@example.com", "phone": "+15550000100"}, admin)
+	if status != http.StatusBadRequest || errorCode(out) != string(engine.ErrorIdentifierRequired) {
+		t.Errorf("revoke by email and phone: %d %v", status, out)
+	}
+
+	first := generateOTP(t, r, "compromised@example.com")
+	second := generateOTP(t, r, "compromised@example.com")
+	other := generateOTP(t, r, "bystander@example.com")
+
+	status, out = doJSON(t, r, http.MethodPost, "/api/v1/otp/revoke", map[string]string{"email": "compromised@example.com", "reason": "account_compromised"}, admin)
+	if status != http.StatusOK || data(t, out)["revoked"] != float64(2) {
+		t.Fatalf("revoke: %d %v", status, out)
+	}
+	for _, issued := range []map[string]interface{}{first, second} {
+		if otp := storedOTP(t, issued["otp_id"]); otp.RevokedAt == nil || otp.RevokeReason != "account_compromised" {
+			t.Errorf("OTP %v: revoked at %v for %q", issued["otp_id"], otp.RevokedAt, otp.RevokeReason)
+		}
+	}
+	if otp := storedOTP(t, other["otp_id"]); otp.RevokedAt != nil {
+		t.Error("another address's OTP was revoked")
+	}
+
+	// Without a reason the revocation is attributed to an administrator
+	doJSON(t, r, http.MethodPost, "/api/v1/otp/revoke", map[string]string{"email": "bystander@example.com"}, admin)
+	if otp := storedOTP(t, other["otp_id"]); otp.RevokeReason != "admin" {
+		t.Errorf("revoke reason %q, want admin", otp.RevokeReason)
+	}
+}
@@ -0,0 +1,497 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// FinishPasskeyRegistrationRequest carries the browser's attestation response
+type FinishPasskeyRegistrationRequest struct {
+	CeremonyID string          `json:"ceremony_id" binding:"required"`
+	Name       string          `json:"name" binding:"omitempty,max=128"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// FinishPasskeyLoginRequest carries the browser's assertion response
+type FinishPasskeyLoginRequest struct {
+	CeremonyID string          `json:"ceremony_id" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// passkeyUser adapts models.User to the webauthn.User interface
+type passkeyUser struct {
+	user     models.User
+	passkeys []models.Passkey
+}
+
+func (u *passkeyUser) WebAuthnID() []byte { return []byte(u.user.ID) }
+
+func (u *passkeyUser) WebAuthnName() string {
+	if u.user.Email != "" {
+		return u.user.Email
+	}
+	return u.user.Phone
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string { return u.WebAuthnName() }
+
+func (u *passkeyUser) WebAuthnIcon() string { return "" }
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.passkeys))
+	for _, p := range u.passkeys {
+		id, err := base64.RawURLEncoding.DecodeString(p.CredentialID)
+		if err != nil {
+			continue
+		}
+		var transports []protocol.AuthenticatorTransport
+		for _, t := range strings.Split(p.Transports, ",") {
+			if t != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(t))
+			}
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       p.PublicKey,
+			AttestationType: p.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: p.BackupEligible,
+				BackupState:    p.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    p.AAGUID,
+				SignCount: p.SignCount,
+			},
+		})
+	}
+	return credentials
+}
+
+// loadPasskeyUser loads a user together with their passkeys
+func loadPasskeyUser(userID string) (*passkeyUser, error) {
+	var user models.User
+	if err := config.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	u := &passkeyUser{user: user}
+	config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&u.passkeys)
+	return u, nil
+}
+
+// BeginPasskeyRegistration starts enrolling a passkey for the authenticated
+// user. Only users who verified an email or phone with an OTP can enroll,
+// so OTP always remains available as the recovery path.
+func BeginPasskeyRegistration(c *gin.Context) {
+	u, err := loadPasskeyUser(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	if !u.user.IsEmailVerified && !u.user.IsPhoneVerified {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Verify your email or phone with an OTP before adding a passkey",
+		})
+		return
+	}
+
+	wa, err := utils.NewWebAuthn()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Passkeys are not configured",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(u.passkeys))
+	for _, credential := range u.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	// Passkeys sign in without a password, so the authenticator must verify
+	// the user (PIN or biometrics), not just their presence
+	options, session, err := wa.BeginRegistration(u,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to start passkey registration",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ceremonyID, ok := saveWebAuthnCeremony(c, u.user.ID, "registration", session)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"ceremony_id": ceremonyID,
+			"options":     options,
+		},
+	})
+}
+
+// FinishPasskeyRegistration verifies the attestation and stores the passkey
+func FinishPasskeyRegistration(c *gin.Context) {
+	var req FinishPasskeyRegistrationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	session, ok := takeWebAuthnCeremony(c, req.CeremonyID, userID, "registration")
+	if !ok {
+		return
+	}
+
+	u, err := loadPasskeyUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	wa, err := utils.NewWebAuthn()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Passkeys are not configured",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		fmt.Printf("❌ Invalid passkey registration response for user %s: %s\n", userID, webauthnErrorDetail(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid passkey registration response",
+			"error":   webauthnErrorMessage(err),
+		})
+		return
+	}
+
+	credential, err := wa.CreateCredential(u, *session, parsed)
+	if err != nil {
+		fmt.Printf("❌ Passkey registration failed for user %s: %s\n", userID, webauthnErrorDetail(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Passkey registration failed",
+			"error":   webauthnErrorMessage(err),
+		})
+		return
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	passkey := models.Passkey{
+		ID:              uuid.New().String(),
+		UserID:          userID,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            req.Name,
+	}
+	if err := config.DB.Create(&passkey).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "Passkey is already registered",
+		})
+		return
+	}
+
+	fmt.Printf("🔑 Passkey %s registered for user %s\n", passkey.ID, userID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Passkey registered",
+		"data":    passkey,
+	})
+}
+
+// BeginPasskeyLogin starts a discoverable (username-less) passkey login.
+// The browser offers whichever passkeys it holds for this site, so no
+// account information is revealed.
+func BeginPasskeyLogin(c *gin.Context) {
+	wa, err := utils.NewWebAuthn()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Passkeys are not configured",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	options, session, err := wa.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to start passkey login",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ceremonyID, ok := saveWebAuthnCeremony(c, "", "login", session)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"ceremony_id": ceremonyID,
+			"options":     options,
+		},
+	})
+}
+
+// FinishPasskeyLogin verifies the assertion and starts a session. Users
+// who lost their passkey can still log in with an OTP.
+func FinishPasskeyLogin(c *gin.Context) {
+	var req FinishPasskeyLoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	session, ok := takeWebAuthnCeremony(c, req.CeremonyID, "", "login")
+	if !ok {
+		return
+	}
+
+	wa, err := utils.NewWebAuthn()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Passkeys are not configured",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		fmt.Printf("❌ Invalid passkey login response: %s\n", webauthnErrorDetail(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid passkey login response",
+			"error":   webauthnErrorMessage(err),
+		})
+		return
+	}
+
+	var u *passkeyUser
+	credential, err := wa.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		found, lookupErr := loadPasskeyUser(string(userHandle))
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		u = found
+		return found, nil
+	}, *session, parsed)
+	if err != nil {
+		fmt.Printf("❌ Passkey login failed: %s\n", webauthnErrorDetail(err))
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Passkey login failed. You can still log in with an OTP",
+		})
+		return
+	}
+
+	// A counter that did not advance means the private key was copied
+	if credential.Authenticator.CloneWarning {
+		fmt.Printf("🚨 Possible cloned passkey for user %s\n", u.user.ID)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Passkey login failed. You can still log in with an OTP",
+		})
+		return
+	}
+
+	now := time.Now()
+	config.DB.Model(&models.Passkey{}).
+		Where("credential_id = ? AND user_id = ?", base64.RawURLEncoding.EncodeToString(credential.ID), u.user.ID).
+		Updates(map[string]interface{}{
+			"sign_count":   credential.Authenticator.SignCount,
+			"backup_state": credential.Flags.BackupState,
+			"last_used_at": now,
+		})
+
+	sessionTokens, err := createSession(clientInfo(c), u.user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to create session",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("✅ Passkey login for user %s\n", u.user.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged in with passkey",
+		"data": gin.H{
+			"user":    u.user,
+			"session": sessionTokens,
+		},
+	})
+}
+
+// ListPasskeys returns the authenticated user's passkeys
+func ListPasskeys(c *gin.Context) {
+	var passkeys []models.Passkey
+	config.DB.Where("user_id = ?", c.GetString("user_id")).Order("created_at DESC").Find(&passkeys)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    passkeys,
+	})
+}
+
+// RemovePasskey deletes one of the authenticated user's passkeys
+func RemovePasskey(c *gin.Context) {
+	result := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).Delete(&models.Passkey{})
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Passkey not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Passkey removed",
+	})
+}
+
+// saveWebAuthnCeremony stores the session data of a ceremony and returns
+// its ID. It writes the error response and returns false on failure.
+func saveWebAuthnCeremony(c *gin.Context, userID, kind string, session *webauthn.SessionData) (string, bool) {
+	data, err := json.Marshal(session)
+	if err == nil {
+		ceremony := models.WebAuthnCeremony{
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			Kind:        kind,
+			SessionData: string(data),
+			ExpiresAt:   time.Now().Add(utils.WebAuthnCeremonyTTL),
+		}
+		if err = config.DB.Create(&ceremony).Error; err == nil {
+			return ceremony.ID, true
+		}
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"message": "Failed to start passkey ceremony",
+		"error":   err.Error(),
+	})
+	return "", false
+}
+
+// takeWebAuthnCeremony loads and deletes a ceremony so each challenge can
+// only be answered once. It writes the error response and returns false
+// if the ceremony is unknown, expired or belongs to someone else.
+func takeWebAuthnCeremony(c *gin.Context, id, userID, kind string) (*webauthn.SessionData, bool) {
+	var ceremony models.WebAuthnCeremony
+	err := config.DB.Where("id = ? AND kind = ? AND user_id = ?", id, kind, userID).First(&ceremony).Error
+	if err == nil {
+		result := config.DB.Where("id = ?", ceremony.ID).Delete(&models.WebAuthnCeremony{})
+		if result.RowsAffected != 1 || time.Now().After(ceremony.ExpiresAt) {
+			err = fmt.Errorf("ceremony expired")
+		}
+	}
+
+	var session webauthn.SessionData
+	if err == nil {
+		err = json.Unmarshal([]byte(ceremony.SessionData), &session)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Passkey ceremony not found or expired. Please start again",
+		})
+		return nil, false
+	}
+
+	return &session, true
+}
+
+// webauthnErrorDetail includes the protocol error details, which say
+// which check failed. They are for server logs only.
+func webauthnErrorDetail(err error) string {
+	if perr, ok := err.(*protocol.Error); ok && perr.DevInfo != "" {
+		return perr.Details + ": " + perr.DevInfo
+	}
+	return err.Error()
+}
+
+// webauthnErrorMessage is the part of a WebAuthn error that clients see.
+// It leaves out DevInfo, which describes the failed check in detail.
+func webauthnErrorMessage(err error) string {
+	var perr *protocol.Error
+	if errors.As(err, &perr) {
+		return perr.Details
+	}
+	return "Invalid credential"
+}
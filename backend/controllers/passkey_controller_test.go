@@ -0,0 +1,228 @@
+package controllers_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:5173"
+
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// softwareAuthenticator is a passkey held in memory, standing in for a
+// platform authenticator or security key
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+	// flags are set on every response; clear flagUserVerified to act like
+	// a key that only checks presence
+	flags byte
+	// origin is the page the browser reports the ceremony came from
+	origin string
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &softwareAuthenticator{key: key, credentialID: id, flags: flagUserPresent | flagUserVerified, origin: testOrigin}
+}
+
+func (a *softwareAuthenticator) authenticatorData(extra byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	a.signCount++
+	data := append(rpIDHash[:], a.flags|extra)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softwareAuthenticator) clientData(t *testing.T, kind string, options map[string]interface{}) []byte {
+	t.Helper()
+	publicKey := options["publicKey"].(map[string]interface{})
+	clientData, err := json.Marshal(map[string]string{
+		"type":      kind,
+		"challenge": publicKey["challenge"].(string),
+		"origin":    a.origin,
+	})
+	if err != nil {
+		t.Fatalf("encode client data: %v", err)
+	}
+	return clientData
+}
+
+// register answers a registration ceremony with a "none" attestation
+func (a *softwareAuthenticator) register(t *testing.T, options map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	publicKey := options["publicKey"].(map[string]interface{})
+	a.userHandle, _ = base64.RawURLEncoding.DecodeString(publicKey["user"].(map[string]interface{})["id"].(string))
+
+	coseKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{KeyType: int64(webauthncose.EllipticKey), Algorithm: int64(webauthncose.AlgES256)},
+		Curve:         int64(webauthncose.P256),
+		XCoord:        a.key.X.FillBytes(make([]byte, 32)),
+		YCoord:        a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("encode public key: %v", err)
+	}
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(append(attested, a.credentialID...), coseKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(flagAttested, attested),
+	})
+	if err != nil {
+		t.Fatalf("encode attestation: %v", err)
+	}
+
+	return map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(a.clientData(t, "webauthn.create", options)),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestation),
+		},
+	}
+}
+
+// assert answers a login ceremony with a signed assertion
+func (a *softwareAuthenticator) assert(t *testing.T, options map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	authData := a.authenticatorData(0, nil)
+	clientData := a.clientData(t, "webauthn.get", options)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+
+	return map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authData),
+			"signature":         base64.RawURLEncoding.EncodeToString(signature),
+			"userHandle":        base64.RawURLEncoding.EncodeToString(a.userHandle),
+		},
+	}
+}
+
+func registerPasskey(t *testing.T, r *gin.Engine, token string, a *softwareAuthenticator) (int, map[string]interface{}) {
+	t.Helper()
+	status, out := doJSON(t, r, http.MethodPost, "/api/auth/passkeys/register/begin", nil, bearer(token))
+	if status != http.StatusOK {
+		t.Fatalf("begin registration: %d %v", status, out)
+	}
+	begin := data(t, out)
+	options := begin["options"].(map[string]interface{})
+	if uv := options["publicKey"].(map[string]interface{})["authenticatorSelection"].(map[string]interface{})["userVerification"]; uv != "required" {
+		t.Errorf("registration asks for user verification %v, want required", uv)
+	}
+	return doJSON(t, r, http.MethodPost, "/api/auth/passkeys/register/finish", map[string]interface{}{
+		"ceremony_id": begin["ceremony_id"],
+		"credential":  a.register(t, options),
+	}, bearer(token))
+}
+
+func passkeyLogin(t *testing.T, r *gin.Engine, a *softwareAuthenticator) (int, map[string]interface{}) {
+	t.Helper()
+	status, out := doJSON(t, r, http.MethodPost, "/api/auth/passkeys/login/begin", nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("begin login: %d %v", status, out)
+	}
+	begin := data(t, out)
+	options := begin["options"].(map[string]interface{})
+	if uv := options["publicKey"].(map[string]interface{})["userVerification"]; uv != "required" {
+		t.Errorf("login asks for user verification %v, want required", uv)
+	}
+	return doJSON(t, r, http.MethodPost, "/api/auth/passkeys/login/finish", map[string]interface{}{
+		"ceremony_id": begin["ceremony_id"],
+		"credential":  a.assert(t, options),
+	}, nil)
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	r := newTestRouter(t)
+	token, userID := login(t, r, "passkey@example.com")
+	a := newSoftwareAuthenticator(t)
+
+	if status, out := registerPasskey(t, r, token, a); status != http.StatusCreated {
+		t.Fatalf("register: %d %v", status, out)
+	}
+
+	status, out := passkeyLogin(t, r, a)
+	if status != http.StatusOK {
+		t.Fatalf("login: %d %v", status, out)
+	}
+	result := data(t, out)
+	if result["user"].(map[string]interface{})["id"] != userID || result["session"] == nil {
+		t.Errorf("login result: %v", result)
+	}
+}
+
+// Passwordless login needs a PIN or biometrics, so a key that only checks
+// the user is present cannot register or sign in
+func TestPasskeysRequireUserVerification(t *testing.T) {
+	r := newTestRouter(t)
+	token, _ := login(t, r, "presence@example.com")
+
+	presenceOnly := newSoftwareAuthenticator(t)
+	presenceOnly.flags = flagUserPresent
+	if status, out := registerPasskey(t, r, token, presenceOnly); status != http.StatusBadRequest {
+		t.Errorf("register without user verification: %d %v", status, out)
+	}
+
+	a := newSoftwareAuthenticator(t)
+	if status, out := registerPasskey(t, r, token, a); status != http.StatusCreated {
+		t.Fatalf("register: %d %v", status, out)
+	}
+	a.flags = flagUserPresent
+	if status, out := passkeyLogin(t, r, a); status != http.StatusUnauthorized {
+		t.Errorf("login without user verification: %d %v", status, out)
+	}
+}
+
+func TestPasskeyErrorsLeaveOutDevInfo(t *testing.T) {
+	r := newTestRouter(t)
+	token, _ := login(t, r, "devinfo@example.com")
+	a := newSoftwareAuthenticator(t)
+	a.origin = "https://attacker.example"
+
+	status, out := registerPasskey(t, r, token, a)
+	if status != http.StatusBadRequest {
+		t.Fatalf("register from another origin: %d %v", status, out)
+	}
+	if detail, _ := out["error"].(string); detail == "" || strings.Contains(detail, "attacker.example") || strings.Contains(detail, testOrigin) {
+		t.Errorf("error sent to the client: %q", detail)
+	}
+}
@@ -0,0 +1,423 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/securetoken"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RegisterPushDeviceRequest represents the request body for enrolling a push device
+type RegisterPushDeviceRequest struct {
+	Name string `json:"name" binding:"omitempty,max=128"`
+	// PublicKey is the device's base64 encoded Ed25519 public key
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+// RespondPushRequest represents a device's signed answer to a challenge
+type RespondPushRequest struct {
+	DeviceID  string `json:"device_id" binding:"required"`
+	Decision  string `json:"decision" binding:"required,oneof=approve deny"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+// CompletePushRequest represents the request body for collecting a push result
+type CompletePushRequest struct {
+	OTPID string `json:"otp_id" binding:"required"`
+}
+
+// RegisterPushDevice enrolls a device of the authenticated user for push approval
+func RegisterPushDevice(c *gin.Context) {
+	var req RegisterPushDeviceRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	key, err := utils.ParsePushPublicKey(req.PublicKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid public key",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	device := models.PushDevice{
+		ID:        uuid.New().String(),
+		UserID:    c.GetString("user_id"),
+		Name:      req.Name,
+		PublicKey: utils.EncodePushPublicKey(key),
+	}
+	if err := config.DB.Create(&device).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to register device",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("📲 Push device %s registered for user %s\n", device.ID, device.UserID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Device registered for push approval",
+		"data":    device,
+	})
+}
+
+// ListPushDevices returns the authenticated user's push devices
+func ListPushDevices(c *gin.Context) {
+	var devices []models.PushDevice
+	config.DB.Where("user_id = ? AND revoked_at IS NULL", c.GetString("user_id")).
+		Order("created_at DESC").
+		Find(&devices)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    devices,
+	})
+}
+
+// RemovePushDevice revokes one of the authenticated user's push devices
+func RemovePushDevice(c *gin.Context) {
+	result := config.DB.Model(&models.PushDevice{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), c.GetString("user_id")).
+		Update("revoked_at", time.Now())
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Device not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Device removed",
+	})
+}
+
+// ListPushChallenges returns the pending challenges for a device. The
+// device authenticates by signing its ID and the current Unix time, sent in
+// the X-Device-ID, X-Device-Timestamp and X-Device-Signature headers.
+func ListPushChallenges(c *gin.Context) {
+	device, ok := authenticatePushDevice(c)
+	if !ok {
+		return
+	}
+
+	var challenges []models.PushChallenge
+	config.DB.Where("user_id = ? AND status = ? AND expires_at > ?", device.UserID, models.PushPending, time.Now()).
+		Order("created_at DESC").
+		Find(&challenges)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    challenges,
+	})
+}
+
+// RespondPushChallenge records a device's signed approval or denial. An
+// approval verifies the OTP; a denial blocks its code as well.
+func RespondPushChallenge(c *gin.Context) {
+	var req RespondPushRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var challenge models.PushChallenge
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&challenge).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Challenge not found",
+		})
+		return
+	}
+
+	var device models.PushDevice
+	if err := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", req.DeviceID, challenge.UserID).First(&device).Error; err != nil ||
+		!utils.VerifyPushSignature(device.PublicKey, req.Signature, utils.PushChallengeMessage(challenge.ID, challenge.Nonce, req.Decision)) {
+		fmt.Printf("❌ Invalid push signature for challenge %s\n", challenge.ID)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Invalid device signature",
+		})
+		return
+	}
+
+	now := time.Now()
+	if now.After(challenge.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Challenge has expired",
+		})
+		return
+	}
+
+	status := models.PushApproved
+	if req.Decision == utils.PushDeny {
+		status = models.PushDenied
+	}
+
+	// Only the first answer counts
+	result := config.DB.Model(&models.PushChallenge{}).
+		Where("id = ? AND status = ?", challenge.ID, models.PushPending).
+		Updates(map[string]interface{}{"status": status, "device_id": device.ID, "responded_at": now})
+	if result.Error != nil || result.RowsAffected != 1 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "Challenge has already been answered",
+		})
+		return
+	}
+	config.DB.Model(&device).Update("last_seen_at", now)
+
+	if status == models.PushApproved {
+		if _, err := otpEngine().Approve(c.Request.Context(), challenge.OTPID, engine.VerifiedByPush); err != nil {
+			// Already verified with the code, revoked, expired or locked, so
+			// there is nothing left to hand out
+			config.DB.Model(&models.PushChallenge{}).Where("id = ?", challenge.ID).Update("completed_at", now)
+			otpErr := engine.AsError(err)
+			fmt.Printf("⚠️  Push challenge %s approved on device %s, but OTP %s was not verified: %s\n", challenge.ID, device.ID, challenge.OTPID, otpErr.Message)
+			c.JSON(otpErr.Status, gin.H{
+				"success": false,
+				"message": "Approval recorded, but the code can no longer be verified",
+				"error":   otpErr.Message,
+			})
+			return
+		}
+		fmt.Printf("✅ Push challenge %s approved on device %s\n", challenge.ID, device.ID)
+	} else {
+		// The user says this was not them, so the code must not work either
+		if err := otpEngine().Block(c.Request.Context(), challenge.OTPID, engine.FailedPushDenied); err != nil {
+			fmt.Printf("⚠️  Failed to block OTP %s: %v\n", challenge.OTPID, err)
+		}
+		fmt.Printf("🚫 Push challenge %s denied on device %s\n", challenge.ID, device.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Response recorded",
+		"data": gin.H{
+			"status": status,
+		},
+	})
+}
+
+// CompletePushVerification lets the client that requested the OTP collect
+// the result of a push approval. It answers 202 while the challenge is
+// pending and returns the verification token and session once approved.
+func CompletePushVerification(c *gin.Context) {
+	var req CompletePushRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var challenge models.PushChallenge
+	if err := config.DB.Where("otp_id = ?", req.OTPID).First(&challenge).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "No push challenge for this OTP",
+		})
+		return
+	}
+
+	switch {
+	case challenge.Status == models.PushDenied:
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "The request was denied on the user's device",
+			"data":    gin.H{"status": challenge.Status},
+		})
+		return
+	case challenge.Status == models.PushPending && time.Now().After(challenge.ExpiresAt):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Push challenge has expired",
+			"data":    gin.H{"status": "expired"},
+		})
+		return
+	case challenge.Status == models.PushPending:
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "Waiting for approval on the user's device",
+			"data":    gin.H{"status": challenge.Status},
+		})
+		return
+	}
+
+	// Approved: hand out the result exactly once
+	result := config.DB.Model(&models.PushChallenge{}).
+		Where("id = ? AND completed_at IS NULL", challenge.ID).
+		Update("completed_at", time.Now())
+	if result.Error != nil || result.RowsAffected != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "OTP already verified",
+		})
+		return
+	}
+
+	var otp models.OTP
+	if err := config.DB.Where("id = ?", challenge.OTPID).First(&otp).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "OTP not found",
+		})
+		return
+	}
+
+	user := verifiedUser(&otp)
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	responseData := gin.H{
+		"verified": true,
+		"status":   challenge.Status,
+		"purpose":  otp.Purpose,
+		"user_id":  user.ID,
+	}
+
+	channel, identifier := otpChannel(otp.Email, otp.Phone)
+	addVerificationToken(responseData, user.ID, utils.VerificationClaims{
+		Channel:         channel,
+		Identifier:      identifier,
+		Purpose:         otp.Purpose,
+		TransactionHash: otp.TransactionHash,
+	})
+
+	if otp.Purpose == engine.PurposeLogin || otp.Purpose == engine.PurposeSignup {
+		if session, err := createSession(clientInfo(c), user.ID); err != nil {
+			fmt.Printf("⚠️  Failed to create session: %v\n", err)
+		} else {
+			responseData["session"] = session
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Approved on the user's device",
+		"data":    responseData,
+	})
+}
+
+// createPushChallenge asks the OTP owner's push devices to approve it and
+// returns the push status for the response
+func createPushChallenge(otp *models.OTP) string {
+	var user models.User
+	query := config.DB
+	if otp.Email != "" {
+		query = query.Where("email = ?", otp.Email)
+	} else {
+		query = query.Where("phone = ?", otp.Phone)
+	}
+	if err := query.First(&user).Error; err != nil {
+		return "no_device"
+	}
+
+	var devices int64
+	config.DB.Model(&models.PushDevice{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&devices)
+	if devices == 0 {
+		return "no_device"
+	}
+
+	nonce, err := securetoken.New(16)
+	if err != nil {
+		return "failed"
+	}
+
+	summary := otp.TransactionSummary
+	if summary == "" {
+		summary = "Sign-in request"
+	}
+
+	challenge := models.PushChallenge{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		OTPID:     otp.ID,
+		Nonce:     nonce,
+		Status:    models.PushPending,
+		Purpose:   otp.Purpose,
+		Summary:   summary,
+		ClientIP:  otp.ClientIP,
+		ExpiresAt: otp.ExpiresAt,
+	}
+	if err := config.DB.Create(&challenge).Error; err != nil {
+		fmt.Printf("⚠️  Failed to create push challenge: %v\n", err)
+		return "failed"
+	}
+
+	fmt.Printf("📲 Push challenge %s pending on %d device(s) of user %s\n", challenge.ID, devices, user.ID)
+	return models.PushPending
+}
+
+// authenticatePushDevice checks the signed device headers. It writes the
+// error response and returns false if the request must stop.
+func authenticatePushDevice(c *gin.Context) (*models.PushDevice, bool) {
+	deviceID := c.GetHeader("X-Device-ID")
+	timestamp, err := strconv.ParseInt(c.GetHeader("X-Device-Timestamp"), 10, 64)
+	if deviceID == "" || err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Missing device authentication headers",
+		})
+		return nil, false
+	}
+
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew > utils.PushClockSkew || skew < -utils.PushClockSkew {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Device timestamp is out of range",
+		})
+		return nil, false
+	}
+
+	var device models.PushDevice
+	if err := config.DB.Where("id = ? AND revoked_at IS NULL", deviceID).First(&device).Error; err != nil ||
+		!utils.VerifyPushSignature(device.PublicKey, c.GetHeader("X-Device-Signature"), utils.PushFetchMessage(deviceID, timestamp)) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Invalid device signature",
+		})
+		return nil, false
+	}
+
+	now := time.Now()
+	config.DB.Model(&device).Update("last_seen_at", now)
+	return &device, true
+}
@@ -0,0 +1,189 @@
+package controllers_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+)
+
+// pushDevice is a test client acting as an enrolled phone: it holds the
+// signing key and talks to the device endpoints the way the app would
+type pushDevice struct {
+	t   *testing.T
+	r   http.Handler
+	id  string
+	key ed25519.PrivateKey
+}
+
+// enrollPushDevice registers a new device key for the signed-in user
+func enrollPushDevice(t *testing.T, r http.Handler, accessToken string) *pushDevice {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	status, out := doJSON(t, r, http.MethodPost, "/api/auth/push/devices", map[string]string{
+		"name":       "test phone",
+		"public_key": utils.EncodePushPublicKey(public),
+	}, bearer(accessToken))
+	if status != http.StatusCreated {
+		t.Fatalf("register device: %d %v", status, out)
+	}
+	return &pushDevice{t: t, r: r, id: data(t, out)["id"].(string), key: private}
+}
+
+// fetch lists the device's pending challenges, signed at the given time
+func (d *pushDevice) fetch(at time.Time) (int, []map[string]interface{}) {
+	d.t.Helper()
+	timestamp := at.Unix()
+	status, out := doJSON(d.t, d.r, http.MethodGet, "/api/push/challenges", nil, map[string]string{
+		"X-Device-ID":        d.id,
+		"X-Device-Timestamp": strconv.FormatInt(timestamp, 10),
+		"X-Device-Signature": utils.SignPushMessage(d.key, utils.PushFetchMessage(d.id, timestamp)),
+	})
+	var challenges []map[string]interface{}
+	items, _ := out["data"].([]interface{})
+	for _, item := range items {
+		challenges = append(challenges, item.(map[string]interface{}))
+	}
+	return status, challenges
+}
+
+// respond signs a decision for a challenge with the given key
+func (d *pushDevice) respond(challenge map[string]interface{}, decision string, key ed25519.PrivateKey) int {
+	d.t.Helper()
+	id := challenge["id"].(string)
+	status, _ := doJSON(d.t, d.r, http.MethodPost, "/api/push/challenges/"+id, map[string]string{
+		"device_id": d.id,
+		"decision":  decision,
+		"signature": utils.SignPushMessage(key, utils.PushChallengeMessage(id, challenge["nonce"].(string), decision)),
+	}, nil)
+	return status
+}
+
+// pendingChallenge requests a push-approved OTP and returns its ID and the
+// challenge the device receives
+func pendingChallenge(t *testing.T, r http.Handler, device *pushDevice, email string) (string, map[string]interface{}) {
+	t.Helper()
+	status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]interface{}{"email": email, "push": true}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("generate: %d %v", status, out)
+	}
+	otpID := data(t, out)["otp_id"].(string)
+
+	status, challenges := device.fetch(time.Now())
+	if status != http.StatusOK || len(challenges) != 1 {
+		t.Fatalf("fetch challenges: %d %v", status, challenges)
+	}
+	return otpID, challenges[0]
+}
+
+func completePush(t *testing.T, r http.Handler, otpID string) (int, map[string]interface{}) {
+	t.Helper()
+	return doJSON(t, r, http.MethodPost, "/api/otp/push/complete", map[string]string{"otp_id": otpID}, nil)
+}
+
+func TestPushApproval(t *testing.T) {
+	r := newTestRouter(t)
+	token, userID := login(t, r, "push@example.com")
+	device := enrollPushDevice(t, r, token)
+	otpID, challenge := pendingChallenge(t, r, device, "push@example.com")
+
+	if status, _ := completePush(t, r, otpID); status != http.StatusAccepted {
+		t.Errorf("complete before approval: %d, want %d", status, http.StatusAccepted)
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	if status := device.respond(challenge, utils.PushApprove, otherKey); status != http.StatusUnauthorized {
+		t.Errorf("approval signed by another key: %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := device.respond(challenge, utils.PushApprove, device.key); status != http.StatusOK {
+		t.Fatalf("approve: %d", status)
+	}
+	if status := device.respond(challenge, utils.PushDeny, device.key); status != http.StatusConflict {
+		t.Errorf("second answer: %d, want %d", status, http.StatusConflict)
+	}
+
+	status, out := completePush(t, r, otpID)
+	if status != http.StatusOK {
+		t.Fatalf("complete: %d %v", status, out)
+	}
+	result := data(t, out)
+	if result["user_id"] != userID || result["token"] == nil {
+		t.Errorf("unexpected result: %v", result)
+	}
+	if status, _ := completePush(t, r, otpID); status == http.StatusOK {
+		t.Error("the result was handed out twice")
+	}
+}
+
+// Approving a code that was locked by wrong guesses in the meantime must
+// tell the device it did not go through
+func TestPushApprovalOfLockedCode(t *testing.T) {
+	r := newTestRouter(t)
+	token, _ := login(t, r, "locked@example.com")
+	device := enrollPushDevice(t, r, token)
+	otpID, challenge := pendingChallenge(t, r, device, "locked@example.com")
+
+	for i := 0; i < 3; i++ {
+		doJSON(t, r, http.MethodPost, "/api/v1/otp/verify", map[string]string{"otp_id": otpID, "otp_code": "000000"}, nil)
+	}
+
+	if status := device.respond(challenge, utils.PushApprove, device.key); status != http.StatusBadRequest {
+		t.Errorf("approving a locked code: %d, want %d", status, http.StatusBadRequest)
+	}
+	if status, out := completePush(t, r, otpID); status == http.StatusOK {
+		t.Errorf("locked code handed out a result: %v", out)
+	}
+}
+
+func TestPushDenialBlocksCode(t *testing.T) {
+	r := newTestRouter(t)
+	token, _ := login(t, r, "deny@example.com")
+	device := enrollPushDevice(t, r, token)
+
+	status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]interface{}{"email": "deny@example.com", "push": true}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("generate: %d %v", status, out)
+	}
+	issued := data(t, out)
+	_, challenges := device.fetch(time.Now())
+	if len(challenges) != 1 {
+		t.Fatalf("fetch challenges: %v", challenges)
+	}
+
+	if status := device.respond(challenges[0], utils.PushDeny, device.key); status != http.StatusOK {
+		t.Fatalf("deny: %d", status)
+	}
+	if status, _ := completePush(t, r, issued["otp_id"].(string)); status != http.StatusForbidden {
+		t.Errorf("complete after denial: %d, want %d", status, http.StatusForbidden)
+	}
+	status, _ = doJSON(t, r, http.MethodPost, "/api/v1/otp/verify", map[string]string{
+		"otp_id":   issued["otp_id"].(string),
+		"otp_code": issued["otp_code"].(string),
+	}, nil)
+	if status == http.StatusOK {
+		t.Error("the code still works after the device denied the request")
+	}
+}
+
+func TestPushFetchRequiresFreshSignature(t *testing.T) {
+	r := newTestRouter(t)
+	token, _ := login(t, r, "stale@example.com")
+	device := enrollPushDevice(t, r, token)
+
+	if status, _ := device.fetch(time.Now().Add(-utils.PushClockSkew - time.Minute)); status != http.StatusUnauthorized {
+		t.Errorf("stale timestamp: %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	other := *device
+	_, other.key, _ = ed25519.GenerateKey(rand.Reader)
+	if status, _ := other.fetch(time.Now()); status != http.StatusUnauthorized {
+		t.Errorf("wrong key: %d, want %d", status, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Failed recovery codes allowed per account before its codes are locked,
+// and per client IP before it is rate-limited
+const (
+	maxRecoveryFailures     = 5
+	recoveryLockout         = 15 * time.Minute
+	maxRecoveryFailuresByIP = 20
+	recoveryIPWindow        = time.Hour
+)
+
+// UseRecoveryCodeRequest represents the request body for signing in with a recovery code
+type UseRecoveryCodeRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+	Phone string `json:"phone" binding:"omitempty,min=10,max=15"`
+	Code  string `json:"code" binding:"required,min=10,max=16"`
+}
+
+// GenerateRecoveryCodes creates a new set of recovery codes for the
+// authenticated user. Any previous set stops working.
+func GenerateRecoveryCodes(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	codes := make([]string, utils.RecoveryCodeCount)
+	records := make([]models.RecoveryCode, utils.RecoveryCodeCount)
+	for i := range codes {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to generate recovery codes",
+				"error":   err.Error(),
+			})
+			return
+		}
+		hash, err := utils.HashRecoveryCode(code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to generate recovery codes",
+				"error":   err.Error(),
+			})
+			return
+		}
+		codes[i] = code
+		records[i] = models.RecoveryCode{
+			ID:       uuid.New().String(),
+			UserID:   userID,
+			CodeHash: hash,
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to save recovery codes",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("🛟 New recovery codes generated for user %s\n", userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Store these codes somewhere safe. Each can be used once and they will not be shown again",
+		"data": gin.H{
+			"codes": codes,
+		},
+	})
+}
+
+// UseRecoveryCode signs a user in by consuming one of their recovery codes
+func UseRecoveryCode(c *gin.Context) {
+	var req UseRecoveryCodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if req.Email == "" && req.Phone == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Either email or phone number is required",
+		})
+		return
+	}
+
+	// Every request counts as a failure until a code matches, so a burst
+	// of concurrent guesses cannot slip past the limits. Limits apply to
+	// unknown accounts too, so the responses do not reveal which exist.
+	identifier := req.Email + req.Phone
+	attempt := models.RecoveryAttempt{
+		ID:         uuid.New().String(),
+		Identifier: identifier,
+		ClientIP:   c.ClientIP(),
+	}
+	if err := config.DB.Create(&attempt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to check recovery code",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if recoveryLimited(identifier, attempt.ClientIP) {
+		// A rejected request does not extend the lockout
+		config.DB.Delete(&attempt)
+		fmt.Printf("🔒 Recovery codes locked for %s (IP %s)\n", identifier, attempt.ClientIP)
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": "Too many failed attempts. Please try again later",
+		})
+		return
+	}
+
+	var user models.User
+	query := config.DB
+	if req.Email != "" {
+		query = query.Where("email = ?", req.Email)
+	} else {
+		query = query.Where("phone = ?", req.Phone)
+	}
+	if err := query.First(&user).Error; err != nil {
+		// Same response as a wrong code so accounts cannot be probed
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid recovery code",
+		})
+		return
+	}
+
+	var candidates []models.RecoveryCode
+	config.DB.Where("user_id = ? AND used_at IS NULL", user.ID).Find(&candidates)
+
+	var matched *models.RecoveryCode
+	for i := range candidates {
+		if utils.CheckRecoveryCode(candidates[i].CodeHash, req.Code) {
+			matched = &candidates[i]
+			break
+		}
+	}
+
+	// Consume the code atomically so it cannot be used twice concurrently
+	now := time.Now()
+	if matched == nil || config.DB.Model(&models.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", matched.ID).
+		Update("used_at", now).RowsAffected != 1 {
+		fmt.Printf("❌ Invalid recovery code for user %s\n", user.ID)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid recovery code",
+		})
+		return
+	}
+
+	config.DB.Where("identifier = ?", identifier).Delete(&models.RecoveryAttempt{})
+
+	remaining := countRecoveryCodes(user.ID)
+	fmt.Printf("🛟 Recovery code used by user %s (%d remaining)\n", user.ID, remaining)
+
+	responseData := gin.H{
+		"verified":                 true,
+		"user_id":                  user.ID,
+		"recovery_codes_remaining": remaining,
+		"timestamp":                now,
+	}
+
+	addVerificationToken(responseData, user.ID, utils.VerificationClaims{
+		Channel: "recovery_code",
+		Purpose: engine.PurposeLogin,
+	})
+
+	if session, err := createSession(clientInfo(c), user.ID); err != nil {
+		fmt.Printf("⚠️  Failed to create session: %v\n", err)
+	} else {
+		responseData["session"] = session
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Recovery code accepted",
+		"data":    responseData,
+	})
+}
+
+// countRecoveryCodes returns how many unused recovery codes a user has
+func countRecoveryCodes(userID string) int64 {
+	var count int64
+	config.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count)
+	return count
+}
+
+// recoveryLimited reports whether recent failures lock the account's
+// recovery codes or rate-limit the client IP
+func recoveryLimited(identifier, clientIP string) bool {
+	now := time.Now()
+
+	var byAccount int64
+	config.DB.Model(&models.RecoveryAttempt{}).
+		Where("identifier = ? AND created_at > ?", identifier, now.Add(-recoveryLockout)).
+		Count(&byAccount)
+	if byAccount > maxRecoveryFailures {
+		return true
+	}
+
+	var byIP int64
+	config.DB.Model(&models.RecoveryAttempt{}).
+		Where("client_ip = ? AND created_at > ?", clientIP, now.Add(-recoveryIPWindow)).
+		Count(&byIP)
+	return byIP > maxRecoveryFailuresByIP
+}
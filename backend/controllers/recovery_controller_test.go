@@ -0,0 +1,91 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// recoveryCode signs up and returns one recovery code for the account.
+// The other codes are deleted so each guess costs a single bcrypt check.
+func recoveryCode(t *testing.T, r *gin.Engine, email string) string {
+	t.Helper()
+	token, userID := login(t, r, email)
+	status, out := doJSON(t, r, http.MethodPost, "/api/auth/recovery-codes", nil, bearer(token))
+	if status != http.StatusOK {
+		t.Fatalf("generate recovery codes: %d %v", status, out)
+	}
+	code := data(t, out)["codes"].([]interface{})[0].(string)
+
+	var records []models.RecoveryCode
+	config.DB.Where("user_id = ?", userID).Find(&records)
+	for _, record := range records {
+		if !utils.CheckRecoveryCode(record.CodeHash, code) {
+			config.DB.Delete(&record)
+		}
+	}
+	return code
+}
+
+func useRecoveryCode(t *testing.T, r *gin.Engine, email, code, ip string) int {
+	t.Helper()
+	status, _ := doJSON(t, r, http.MethodPost, "/api/auth/recovery-codes/verify", map[string]string{"email": email, "code": code}, map[string]string{"X-Forwarded-For": ip})
+	return status
+}
+
+func TestRecoveryCodesLockAfterFailures(t *testing.T) {
+	r := newTestRouter(t)
+	code := recoveryCode(t, r, "locked@example.com")
+
+	for _, email := range []string{"locked@example.com", "unknown@example.com"} {
+		for i := 0; i < 5; i++ {
+			// Spread the guesses over addresses so only the account limit applies
+			if status := useRecoveryCode(t, r, email, "aaaaa-aaaaa", fmt.Sprintf("203.0.113.%d", i)); status != http.StatusBadRequest {
+				t.Fatalf("%s guess %d: %d, want %d", email, i+1, status, http.StatusBadRequest)
+			}
+		}
+		if status := useRecoveryCode(t, r, email, code, "198.51.100.1"); status != http.StatusTooManyRequests {
+			t.Errorf("%s after 5 failures: %d, want %d", email, status, http.StatusTooManyRequests)
+		}
+	}
+}
+
+func TestRecoveryCodeSuccessResetsFailures(t *testing.T) {
+	r := newTestRouter(t)
+	code := recoveryCode(t, r, "reset@example.com")
+
+	for i := 0; i < 4; i++ {
+		useRecoveryCode(t, r, "reset@example.com", "aaaaa-aaaaa", "203.0.113.1")
+	}
+	if status := useRecoveryCode(t, r, "reset@example.com", code, "203.0.113.1"); status != http.StatusOK {
+		t.Fatalf("correct code: %d", status)
+	}
+
+	var failures int64
+	config.DB.Model(&models.RecoveryAttempt{}).Where("identifier = ?", "reset@example.com").Count(&failures)
+	if failures != 0 {
+		t.Errorf("%d failures remain after a successful code", failures)
+	}
+}
+
+func TestRecoveryCodesRateLimitedByIP(t *testing.T) {
+	r := newTestRouter(t)
+
+	for i := 0; i < 20; i++ {
+		email := fmt.Sprintf("user%d@example.com", i)
+		if status := useRecoveryCode(t, r, email, "aaaaa-aaaaa", "203.0.113.1"); status != http.StatusBadRequest {
+			t.Fatalf("guess %d: %d, want %d", i+1, status, http.StatusBadRequest)
+		}
+	}
+	if status := useRecoveryCode(t, r, "next@example.com", "aaaaa-aaaaa", "203.0.113.1"); status != http.StatusTooManyRequests {
+		t.Errorf("21st guess from one IP: %d, want %d", status, http.StatusTooManyRequests)
+	}
+	if status := useRecoveryCode(t, r, "next@example.com", "aaaaa-aaaaa", "203.0.113.2"); status != http.StatusBadRequest {
+		t.Errorf("guess from another IP: %d, want %d", status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ListErrorCodes serves the catalog of error codes used by the versioned API
+func ListErrorCodes(c *gin.Context) {
+	respond(c, "", engine.ErrorCatalog)
+}
+
+// respond writes a success response for endpoints served on both the
+// legacy and the versioned API
+func respond(c *gin.Context, message string, data interface{}) {
+	middleware.Respond(c, http.StatusOK, message, data)
+}
+
+// respondError writes an error response for endpoints served on both the
+// legacy and the versioned API. Details are merged into the legacy body,
+// so keys already returned there must keep their names.
+func respondError(c *gin.Context, status int, code engine.ErrorCode, message string, details gin.H) {
+	middleware.RespondError(c, status, code, message, details)
+}
+
+// respondInternalError reports an unexpected failure
+func respondInternalError(c *gin.Context, message string, err error) {
+	respondError(c, http.StatusInternalServerError, engine.ErrorInternal, message, gin.H{"error": err.Error()})
+}
+
+// respondBindError reports a request body that failed to bind into req.
+// The legacy API returns the raw error; the versioned API lists the
+// failing fields by their JSON names.
+func respondBindError(c *gin.Context, req interface{}, err error) {
+	if !middleware.IsVersioned(c) {
+		respondError(c, http.StatusBadRequest, engine.ErrorValidationFailed, "Invalid request data", gin.H{"error": err.Error()})
+		return
+	}
+
+	respondOTPError(c, validationError(req, err))
+}
+
+// jsonFieldPath turns a validator namespace such as
+// "GenerateOTPRequest.Transaction.Amount" into "transaction.amount"
+func jsonFieldPath(t reflect.Type, namespace string) string {
+	parts := strings.Split(namespace, ".")[1:]
+	names := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			names = append(names, part)
+			continue
+		}
+
+		sf, ok := t.FieldByName(part)
+		if !ok {
+			names = append(names, part)
+			t = nil
+			continue
+		}
+		t = sf.Type
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" && sf.Anonymous {
+			// Embedded structs are flattened into the parent object
+			continue
+		}
+		if name == "" || name == "-" {
+			name = part
+		}
+		names = append(names, name)
+	}
+
+	return strings.Join(names, ".")
+}
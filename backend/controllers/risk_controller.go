@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/securetoken"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	riskEngine = utils.NewRiskEngine(utils.GetRiskConfig())
+	geoLocator utils.GeoLocator
+)
+
+// EvaluateRiskRequest describes the request a service wants a decision for
+type EvaluateRiskRequest struct {
+	UserID      string `json:"user_id" binding:"required"`
+	IP          string `json:"ip" binding:"required,ip"`
+	UserAgent   string `json:"user_agent"`
+	DeviceToken string `json:"device_token"`
+	// Action is what the user is doing, e.g. "login" or "transaction"
+	Action string `json:"action" binding:"omitempty,max=64"`
+}
+
+// InitRiskEngine builds the rules engine and opens the GeoIP database
+func InitRiskEngine() {
+	cfg := utils.GetRiskConfig()
+
+	var locator utils.GeoLocator
+	if cfg.GeoIPFile != "" {
+		maxmind, err := utils.NewMaxMindLocator(cfg.GeoIPFile)
+		if err != nil {
+			log.Fatal("Failed to open GeoIP database:", err)
+		}
+		locator = maxmind
+		fmt.Printf("✅ GeoIP database loaded from %s\n", cfg.GeoIPFile)
+	} else {
+		fmt.Println("⚠️  GEOIP_DATABASE_FILE not set - location rules disabled")
+	}
+
+	SetRiskEngine(utils.NewRiskEngine(cfg), locator)
+}
+
+// SetRiskEngine replaces the engine and locator, e.g. to plug in custom
+// rules or a utils.StaticLocator in tests
+func SetRiskEngine(engine *utils.RiskEngine, locator utils.GeoLocator) {
+	riskEngine = engine
+	geoLocator = locator
+}
+
+// EvaluateRisk tells a service whether a request needs an OTP challenge.
+// Every decision is stored with its reasons for audit.
+func EvaluateRisk(c *gin.Context) {
+	var req EvaluateRiskRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("id = ?", req.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	ctx := buildRiskContext(&user, &req)
+	decision := riskEngine.Evaluate(ctx)
+
+	reasons, _ := json.Marshal(decision.Reasons)
+	entry := models.RiskDecisionLog{
+		ID:                uuid.New().String(),
+		UserID:            user.ID,
+		Action:            req.Action,
+		ClientIP:          req.IP,
+		UserAgent:         models.Truncate(req.UserAgent, 255),
+		ChallengeRequired: decision.ChallengeRequired,
+		Score:             decision.Score,
+		Reasons:           string(reasons),
+	}
+	if ctx.Location != nil {
+		entry.Country = ctx.Location.Country
+	}
+	if err := config.DB.Create(&entry).Error; err != nil {
+		fmt.Printf("⚠️  Failed to write risk audit log: %v\n", err)
+	}
+
+	fmt.Printf("🛡️  Risk decision %s for user %s (%s from %s): score %d, challenge %t, reasons %s\n",
+		entry.ID, user.ID, req.Action, req.IP, decision.Score, decision.ChallengeRequired, reasons)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"decision_id":        entry.ID,
+			"challenge_required": decision.ChallengeRequired,
+			"score":              decision.Score,
+			"threshold":          decision.Threshold,
+			"reasons":            decision.Reasons,
+			"location":           ctx.Location,
+		},
+	})
+}
+
+// ListRiskDecisions returns the audit log, newest first, optionally for one user
+func ListRiskDecisions(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	query := config.DB.Order("created_at DESC").Limit(limit)
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var entries []models.RiskDecisionLog
+	query.Find(&entries)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entries,
+	})
+}
+
+// buildRiskContext gathers device, location and history signals for a user
+func buildRiskContext(user *models.User, req *EvaluateRiskRequest) *utils.RiskContext {
+	now := time.Now()
+	ctx := &utils.RiskContext{
+		UserID:    user.ID,
+		IP:        req.IP,
+		UserAgent: req.UserAgent,
+		Action:    req.Action,
+		Now:       now,
+		Location:  locate(req.IP),
+	}
+
+	// Known device: a trusted device token of this user from the same browser
+	if req.DeviceToken != "" {
+		var device models.TrustedDevice
+		err := config.DB.Where("token_hash = ? AND user_id = ?", securetoken.Hash(req.DeviceToken), user.ID).First(&device).Error
+		ctx.KnownDevice = err == nil &&
+			device.IsActive(now, utils.GetDeviceConfig().IdleTTL) &&
+			utils.DeviceFingerprintMatches(device.Fingerprint, req.UserAgent)
+	}
+
+	// Sign-in history from sessions started in the last 90 days
+	var sessions []models.Session
+	config.DB.Where("user_id = ? AND created_at > ?", user.ID, now.AddDate(0, 0, -90)).
+		Order("created_at DESC").
+		Limit(50).
+		Find(&sessions)
+	for _, s := range sessions {
+		ctx.History = append(ctx.History, utils.PastSignIn{At: s.CreatedAt, IP: s.ClientIP, Location: locate(s.ClientIP)})
+	}
+
+	// Wrong guesses on the user's OTPs in the last 24 hours. A verified OTP
+	// used one attempt for the correct code.
+	var failed struct{ Total int }
+	config.DB.Model(&models.OTP{}).
+		Select("COALESCE(SUM(CASE WHEN is_verified THEN attempt_count - 1 ELSE attempt_count END), 0) AS total").
+		Where("created_at > ? AND ((email <> '' AND email = ?) OR (phone <> '' AND phone = ?))", now.Add(-24*time.Hour), user.Email, user.Phone).
+		Scan(&failed)
+	ctx.FailedAttempts = failed.Total
+
+	return ctx
+}
+
+func locate(ip string) *utils.GeoLocation {
+	if geoLocator == nil || ip == "" {
+		return nil
+	}
+	loc, err := geoLocator.Locate(ip)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
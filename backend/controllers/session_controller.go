@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/securetoken"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefreshRequest represents the request body for refreshing a session
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest represents the request body for logging out
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+var errRefreshTokenReused = fmt.Errorf("refresh token already used")
+
+// SessionTokens are the credentials of a session
+type SessionTokens struct {
+	SessionID        string    `json:"session_id"`
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	TokenType        string    `json:"token_type"`
+}
+
+// createSession starts a session for the user and returns its tokens
+func createSession(client ClientInfo, userID string) (*SessionTokens, error) {
+	cfg := utils.GetSessionConfig()
+	now := time.Now()
+
+	session := models.Session{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		UserAgent:  models.Truncate(client.UserAgent, 255),
+		ClientIP:   client.IP,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(cfg.RefreshTTL),
+	}
+
+	var tokens *SessionTokens
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		tokens, err = issueSessionTokens(tx, &session, cfg)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// issueSessionTokens creates the next refresh token in the session's chain
+// and a fresh access token
+func issueSessionTokens(tx *gorm.DB, session *models.Session, cfg *utils.SessionConfig) (*SessionTokens, error) {
+	signer := utils.GetTokenSigner()
+	if signer == nil {
+		return nil, fmt.Errorf("token signing not configured")
+	}
+
+	refreshToken, err := securetoken.New(32)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: session.ID,
+		TokenHash: securetoken.Hash(refreshToken),
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	accessToken, accessExpiresAt, err := signer.IssueAccessToken(session.UserID, session.ID, cfg.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionTokens{
+		SessionID:        session.ID,
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: record.ExpiresAt,
+		TokenType:        "Bearer",
+	}, nil
+}
+
+// RefreshSession rotates a refresh token. Presenting a token that was
+// already rotated revokes the whole session, since it must have leaked.
+func RefreshSession(c *gin.Context) {
+	var req RefreshRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var record models.RefreshToken
+	if err := config.DB.Where("token_hash = ?", securetoken.Hash(req.RefreshToken)).First(&record).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Invalid refresh token",
+		})
+		return
+	}
+
+	var session models.Session
+	if err := config.DB.Where("id = ?", record.SessionID).First(&session).Error; err != nil || !session.IsActive() {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Session has been revoked or expired",
+		})
+		return
+	}
+
+	now := time.Now()
+
+	if record.UsedAt != nil {
+		revokeReusedSession(c, &session, now)
+		return
+	}
+
+	var tokens *SessionTokens
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Mark the token used only if nobody else rotated it concurrently
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", record.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		session.LastUsedAt = now
+		if err := tx.Save(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		tokens, err = issueSessionTokens(tx, &session, utils.GetSessionConfig())
+		return err
+	})
+	if err == errRefreshTokenReused {
+		// Another request rotated the token in the meantime: one of the
+		// two holders is not the client, so treat it as reuse
+		revokeReusedSession(c, &session, now)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to refresh session",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session refreshed",
+		"data":    tokens,
+	})
+}
+
+// revokeReusedSession revokes a session whose refresh token was presented
+// twice and responds that the token was rejected
+func revokeReusedSession(c *gin.Context, session *models.Session, now time.Time) {
+	fmt.Printf("🚨 Refresh token reuse detected! Revoking session %s (user %s)\n", session.ID, session.UserID)
+	config.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", session.ID).
+		Update("revoked_at", now)
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"message": "Refresh token reuse detected. Session revoked",
+	})
+}
+
+// Logout revokes the session the refresh token belongs to
+func Logout(c *gin.Context) {
+	var req LogoutRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var record models.RefreshToken
+	if err := config.DB.Where("token_hash = ?", securetoken.Hash(req.RefreshToken)).First(&record).Error; err == nil {
+		now := time.Now()
+		config.DB.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", record.SessionID).
+			Update("revoked_at", now)
+	}
+
+	// Respond the same way for unknown tokens so logout is idempotent
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out successfully",
+	})
+}
+
+// ListSessions returns the active sessions of the authenticated user
+func ListSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var sessions []models.Session
+	config.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions)
+
+	currentID := c.GetString("session_id")
+	list := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, gin.H{
+			"id":           s.ID,
+			"user_agent":   s.UserAgent,
+			"client_ip":    s.ClientIP,
+			"created_at":   s.CreatedAt,
+			"last_used_at": s.LastUsedAt,
+			"expires_at":   s.ExpiresAt,
+			"current":      s.ID == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+	})
+}
+
+// RevokeSession revokes one of the authenticated user's sessions
+func RevokeSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result := config.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), userID).
+		Update("revoked_at", time.Now())
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Session not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session revoked",
+	})
+}
+
+// RevokeAllSessions revokes every session of the authenticated user
+func RevokeAllSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result := config.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("%d sessions revoked", result.RowsAffected),
+	})
+}
@@ -0,0 +1,169 @@
+package controllers_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// startSession signs up with an emailed code and returns the new session's
+// tokens
+func startSession(t *testing.T, r *gin.Engine, email string) map[string]interface{} {
+	t.Helper()
+	status, out := doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": email, "purpose": "signup"}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("generate: %d %v", status, out)
+	}
+	issued := data(t, out)
+
+	status, out = doJSON(t, r, http.MethodPost, "/api/v1/otp/verify", map[string]string{
+		"otp_id":   issued["otp_id"].(string),
+		"otp_code": issued["otp_code"].(string),
+		"purpose":  "signup",
+	}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("verify: %d %v", status, out)
+	}
+	session, ok := data(t, out)["session"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("verify returned no session: %v", out)
+	}
+	return session
+}
+
+func refresh(t *testing.T, r *gin.Engine, token interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	return doJSON(t, r, http.MethodPost, "/api/auth/refresh", map[string]interface{}{"refresh_token": token}, nil)
+}
+
+func sessionRevoked(t *testing.T, id interface{}) bool {
+	t.Helper()
+	var session models.Session
+	if err := config.DB.Where("id = ?", id).First(&session).Error; err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	return session.RevokedAt != nil
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	r := newTestRouter(t)
+	session := startSession(t, r, "rotate@example.com")
+
+	status, out := refresh(t, r, session["refresh_token"])
+	if status != http.StatusOK {
+		t.Fatalf("refresh: %d %v", status, out)
+	}
+	rotated := data(t, out)
+	if rotated["refresh_token"] == session["refresh_token"] || rotated["session_id"] != session["session_id"] {
+		t.Errorf("refresh did not rotate the token within the session: %v", rotated)
+	}
+
+	if status, out := refresh(t, r, rotated["refresh_token"]); status != http.StatusOK {
+		t.Errorf("rotated token refused: %d %v", status, out)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	r := newTestRouter(t)
+	session := startSession(t, r, "reuse@example.com")
+
+	_, out := refresh(t, r, session["refresh_token"])
+	rotated := data(t, out)
+
+	if status, _ := refresh(t, r, session["refresh_token"]); status != http.StatusUnauthorized {
+		t.Errorf("reused token got %d, want 401", status)
+	}
+	if !sessionRevoked(t, session["session_id"]) {
+		t.Error("session not revoked after reuse")
+	}
+	if status, _ := refresh(t, r, rotated["refresh_token"]); status != http.StatusUnauthorized {
+		t.Errorf("token of the revoked session got %d, want 401", status)
+	}
+	if status, _ := doJSON(t, r, http.MethodGet, "/api/auth/sessions", nil, bearer(rotated["access_token"].(string))); status != http.StatusUnauthorized {
+		t.Errorf("access token of the revoked session got %d, want 401", status)
+	}
+}
+
+// Two requests rotating the same token at once must not both succeed, and
+// the loser revokes the session as for any other reuse
+func TestConcurrentRefreshRevokesSession(t *testing.T) {
+	r := newTestRouter(t)
+	session := startSession(t, r, "race@example.com")
+
+	statuses := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i], _ = refresh(t, r, session["refresh_token"])
+		}(i)
+	}
+	wg.Wait()
+
+	var ok int
+	for _, status := range statuses {
+		if status == http.StatusOK {
+			ok++
+		}
+	}
+	if ok != 1 {
+		t.Errorf("statuses %v, want exactly one success", statuses)
+	}
+	if !sessionRevoked(t, session["session_id"]) {
+		t.Error("session not revoked after concurrent reuse")
+	}
+}
+
+func TestRefreshExpiredSession(t *testing.T) {
+	r := newTestRouter(t)
+	session := startSession(t, r, "expired@example.com")
+	config.DB.Model(&models.Session{}).Where("id = ?", session["session_id"]).Update("expires_at", time.Now().Add(-time.Minute))
+
+	if status, out := refresh(t, r, session["refresh_token"]); status != http.StatusUnauthorized {
+		t.Errorf("expired session refreshed: %d %v", status, out)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	r := newTestRouter(t)
+	first := startSession(t, r, "revoke@example.com")
+	second := startSession(t, r, "revoke@example.com")
+	third := startSession(t, r, "revoke@example.com")
+	current := bearer(first["access_token"].(string))
+
+	status, out := doJSON(t, r, http.MethodDelete, "/api/auth/sessions/"+second["session_id"].(string), nil, current)
+	if status != http.StatusOK {
+		t.Fatalf("revoke: %d %v", status, out)
+	}
+	if status, _ := refresh(t, r, second["refresh_token"]); status != http.StatusUnauthorized {
+		t.Errorf("revoked session refreshed: %d", status)
+	}
+	if status, _ := doJSON(t, r, http.MethodDelete, "/api/auth/sessions/"+second["session_id"].(string), nil, current); status != http.StatusNotFound {
+		t.Errorf("revoking twice got %d, want 404", status)
+	}
+
+	// Another user's session cannot be revoked
+	other := startSession(t, r, "other@example.com")
+	if status, _ := doJSON(t, r, http.MethodDelete, "/api/auth/sessions/"+other["session_id"].(string), nil, current); status != http.StatusNotFound {
+		t.Errorf("revoking another user's session got %d, want 404", status)
+	}
+
+	status, out = doJSON(t, r, http.MethodDelete, "/api/auth/sessions", nil, current)
+	if status != http.StatusOK || out["message"] != "2 sessions revoked" {
+		t.Fatalf("revoke all: %d %v", status, out)
+	}
+	for _, s := range []map[string]interface{}{first, third} {
+		if !sessionRevoked(t, s["session_id"]) {
+			t.Errorf("session %v not revoked", s["session_id"])
+		}
+	}
+	if sessionRevoked(t, other["session_id"]) {
+		t.Error("revoke all revoked another user's session")
+	}
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS publishes the public keys used to sign verification tokens so other
+// services can validate them offline
+func JWKS(c *gin.Context) {
+	signer := utils.GetTokenSigner()
+	if signer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": "Token signing not configured",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, signer.JWKS())
+}
+
+// VerificationToken is a signed token other services can check offline
+type VerificationToken struct {
+	Token          string    `json:"token"`
+	TokenType      string    `json:"token_type"`
+	TokenExpiresAt time.Time `json:"token_expires_at"`
+}
+
+// issueVerificationToken signs a verification token for the user.
+// Signing failures are logged and nil returned.
+func issueVerificationToken(userID string, claims utils.VerificationClaims) *VerificationToken {
+	signer := utils.GetTokenSigner()
+	if signer == nil {
+		return nil
+	}
+
+	token, expiresAt, err := signer.IssueVerificationToken(userID, claims)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to sign verification token: %v\n", err)
+		return nil
+	}
+
+	return &VerificationToken{
+		Token:          token,
+		TokenType:      "Bearer",
+		TokenExpiresAt: expiresAt,
+	}
+}
+
+// addVerificationToken adds a verification token for the user to the
+// response data. Signing failures are logged and the token omitted.
+func addVerificationToken(data gin.H, userID string, claims utils.VerificationClaims) {
+	if token := issueVerificationToken(userID, claims); token != nil {
+		data["token"] = token.Token
+		data["token_type"] = token.TokenType
+		data["token_expires_at"] = token.TokenExpiresAt
+	}
+}
+
+// otpChannel returns the delivery channel and identifier of an OTP
+func otpChannel(email, phone string) (string, string) {
+	if email != "" {
+		return "email", email
+	}
+	return "sms", phone
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetProfile returns the authenticated user with their second-factor status
+func GetProfile(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Where("id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	var authenticators int64
+	config.DB.Model(&models.Authenticator{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL", user.ID).
+		Count(&authenticators)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"user":                     user,
+			"authenticator_enrolled":   authenticators > 0,
+			"recovery_codes_remaining": countRecoveryCodes(user.ID),
+		},
+	})
+}
@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/events"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/securetoken"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/Avinashkr000/otp-verification-system/backend/webhooks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// webhookQueueSize is how many events can wait to be stored as webhook
+// deliveries. Events beyond it are dropped and logged.
+const webhookQueueSize = 1024
+
+var (
+	webhookMu          sync.Mutex
+	webhookDispatcher  *webhooks.Dispatcher
+	stopWebhookSending func()
+)
+
+// InitWebhooks starts delivering webhook events in the background
+func InitWebhooks() {
+	dispatcher := webhooks.NewDispatcher(config.DB, utils.GetWebhookConfig())
+	SetWebhookDispatcher(dispatcher)
+	go dispatcher.Run(context.Background())
+}
+
+// SetWebhookDispatcher replaces the dispatcher that sends the events on
+// the event bus to webhook subscriptions. nil stops sending them.
+//
+// Storing deliveries writes to the database, so events are handed to a
+// goroutine instead of being stored on the publisher's goroutine.
+func SetWebhookDispatcher(dispatcher *webhooks.Dispatcher) {
+	webhookMu.Lock()
+	defer webhookMu.Unlock()
+
+	if stopWebhookSending != nil {
+		stopWebhookSending()
+		stopWebhookSending = nil
+	}
+	webhookDispatcher = dispatcher
+	if dispatcher == nil {
+		return
+	}
+
+	queue := make(chan events.Event, webhookQueueSize)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case event := <-queue:
+				publishWebhook(dispatcher, event)
+			case <-stop:
+				// Store what was queued before stopping
+				for {
+					select {
+					case event := <-queue:
+						publishWebhook(dispatcher, event)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	unsubscribe := eventBus.Subscribe(func(event events.Event) {
+		select {
+		case queue <- event:
+		default:
+			fmt.Printf("⚠️  Webhook queue full, dropped %s event\n", event.Type)
+		}
+	})
+	stopWebhookSending = func() {
+		unsubscribe()
+		close(stop)
+		<-done
+	}
+}
+
+// currentWebhookDispatcher returns the dispatcher set with
+// SetWebhookDispatcher
+func currentWebhookDispatcher() *webhooks.Dispatcher {
+	webhookMu.Lock()
+	defer webhookMu.Unlock()
+	return webhookDispatcher
+}
+
+func publishWebhook(dispatcher *webhooks.Dispatcher, event events.Event) {
+	if err := dispatcher.Publish(event.Type, event.Data); err != nil {
+		fmt.Printf("⚠️  Failed to queue webhook %s: %v\n", event.Type, err)
+	}
+}
+
+// CreateWebhookRequest represents the request body for subscribing to events
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=512"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Description string   `json:"description" binding:"max=255"`
+}
+
+// WebhookResponse describes a subscription. Secret is only returned when
+// the subscription is created.
+type WebhookResponse struct {
+	ID          string     `json:"id"`
+	URL         string     `json:"url"`
+	Events      []string   `json:"events"`
+	Description string     `json:"description"`
+	Secret      string     `json:"secret,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+}
+
+// WebhookDeliveryResponse is a delivery log entry with its payload
+type WebhookDeliveryResponse struct {
+	models.WebhookDelivery
+	Payload webhooks.Payload `json:"payload"`
+}
+
+// CreateWebhook subscribes an endpoint to events. The returned secret
+// signs every request to the endpoint.
+func CreateWebhook(c *gin.Context) {
+	var req CreateWebhookRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := utils.GetWebhookConfig().CheckURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid webhook URL",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	for _, event := range req.Events {
+		if !webhooks.IsEventType(event) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("Unknown event type %q", event),
+				"events":  webhooks.EventTypes,
+			})
+			return
+		}
+	}
+
+	secret, err := securetoken.New(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to generate webhook secret",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	subscription := models.WebhookSubscription{
+		ID:          uuid.New().String(),
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      strings.Join(req.Events, ","),
+		Description: req.Description,
+	}
+	if err := config.DB.Create(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to create webhook",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("🪝 Webhook %s subscribed to %s (%s)\n", subscription.ID, subscription.Events, subscription.URL)
+
+	data := webhookResponse(&subscription)
+	data.Secret = secret
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Webhook created. Store the secret now; it is not shown again",
+		"data":    data,
+	})
+}
+
+// ListWebhooks lists all webhook subscriptions
+func ListWebhooks(c *gin.Context) {
+	var subscriptions []models.WebhookSubscription
+	config.DB.Order("created_at DESC").Find(&subscriptions)
+
+	data := make([]WebhookResponse, 0, len(subscriptions))
+	for i := range subscriptions {
+		data = append(data, webhookResponse(&subscriptions[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// DeleteWebhook disables a subscription. Its pending deliveries are
+// dropped; the delivery log is kept.
+func DeleteWebhook(c *gin.Context) {
+	result := config.DB.Model(&models.WebhookSubscription{}).
+		Where("id = ? AND disabled_at IS NULL", c.Param("id")).
+		Update("disabled_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Webhook not found",
+		})
+		return
+	}
+
+	fmt.Printf("🪝 Webhook %s disabled\n", c.Param("id"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Webhook disabled",
+	})
+}
+
+// ListWebhookDeliveries returns the delivery log, newest first. It can be
+// filtered by subscription_id, event_type and status.
+func ListWebhookDeliveries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	query := config.DB.Order("created_at DESC").Limit(limit)
+	if subscriptionID := c.Query("subscription_id"); subscriptionID != "" {
+		query = query.Where("subscription_id = ?", subscriptionID)
+	}
+	if eventType := c.Query("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	query.Find(&deliveries)
+
+	data := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		entry := WebhookDeliveryResponse{WebhookDelivery: delivery}
+		json.Unmarshal([]byte(delivery.Payload), &entry.Payload)
+		data = append(data, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// ReplayWebhookDelivery sends a delivery's event again, e.g. after the
+// receiving system was fixed
+func ReplayWebhookDelivery(c *gin.Context) {
+	dispatcher := currentWebhookDispatcher()
+	if dispatcher == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": "Webhooks are not running",
+		})
+		return
+	}
+
+	replay, err := dispatcher.Replay(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Webhook delivery not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Delivery queued",
+		"data":    replay,
+	})
+}
+
+func webhookResponse(subscription *models.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:          subscription.ID,
+		URL:         subscription.URL,
+		Events:      subscription.EventList(),
+		Description: subscription.Description,
+		CreatedAt:   subscription.CreatedAt,
+		DisabledAt:  subscription.DisabledAt,
+	}
+}
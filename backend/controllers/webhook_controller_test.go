@@ -0,0 +1,108 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/Avinashkr000/otp-verification-system/backend/webhooks"
+)
+
+// waitForDeliveries waits until n webhook deliveries are stored
+func waitForDeliveries(t *testing.T, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var count int64
+		config.DB.Model(&models.WebhookDelivery{}).Count(&count)
+		if count == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d webhook deliveries stored, want %d", count, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookDeliveriesAreStoredInBackground(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "admin-key")
+	r := newTestRouter(t)
+	admin := map[string]string{"X-Admin-Key": "admin-key"}
+
+	code, out := doJSON(t, r, http.MethodPost, "/api/webhooks", map[string]interface{}{
+		"url":    "https://hooks.example.com/otp",
+		"events": []string{"otp.generated"},
+	}, admin)
+	if code != http.StatusCreated && code != http.StatusOK {
+		t.Fatalf("create webhook: %d %v", code, out)
+	}
+
+	controllers.SetWebhookDispatcher(webhooks.NewDispatcher(config.DB, utils.GetWebhookConfig()))
+	t.Cleanup(func() { controllers.SetWebhookDispatcher(nil) })
+
+	doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "hook@example.com"}, nil)
+	waitForDeliveries(t, 1)
+
+	// Stopping waits for queued events, and later events are not stored
+	controllers.SetWebhookDispatcher(nil)
+	doJSON(t, r, http.MethodPost, "/api/v1/otp/generate", map[string]string{"email": "hook2@example.com"}, nil)
+	time.Sleep(50 * time.Millisecond)
+	waitForDeliveries(t, 1)
+}
+
+func TestSetWebhookDispatcherConcurrently(t *testing.T) {
+	newTestRouter(t)
+	dispatcher := webhooks.NewDispatcher(config.DB, utils.GetWebhookConfig())
+
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			defer func() { done <- struct{}{} }()
+			for j := 0; j < 25; j++ {
+				if (i+j)%2 == 0 {
+					controllers.SetWebhookDispatcher(dispatcher)
+				} else {
+					controllers.SetWebhookDispatcher(nil)
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	controllers.SetWebhookDispatcher(nil)
+}
+
+func TestCreateWebhookRequiresPublicHTTPS(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "admin-key")
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "")
+	r := newTestRouter(t)
+	admin := map[string]string{"X-Admin-Key": "admin-key"}
+
+	for _, url := range []string{
+		"http://hooks.example.com/otp",
+		"https://localhost/otp",
+		"https://127.0.0.1/otp",
+		"https://192.168.1.10/otp",
+		"https://169.254.169.254/latest/meta-data",
+	} {
+		status, out := doJSON(t, r, http.MethodPost, "/api/webhooks", map[string]interface{}{
+			"url":    url,
+			"events": []string{"*"},
+		}, admin)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: %d %v, want %d", url, status, out, http.StatusBadRequest)
+		}
+	}
+
+	var count int64
+	config.DB.Model(&models.WebhookSubscription{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d subscriptions created, want 0", count)
+	}
+}
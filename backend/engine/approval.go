@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"gorm.io/gorm"
+)
+
+// VerifyMagicLink verifies an OTP through the link emailed with it. linkID
+// is the ID signed into the link. The link is consumed together with the
+// code, so the link and the typed code cannot both succeed.
+//
+// On failure the OTP is returned too when it exists, so callers can still
+// send the user to its return URL.
+func (e *Engine) VerifyMagicLink(ctx context.Context, otpID, linkID string) (*models.OTP, error) {
+	var otp models.OTP
+	if err := e.db(ctx).Where("id = ?", otpID).First(&otp).Error; err != nil {
+		return nil, NewError(http.StatusNotFound, ErrorOTPNotFound, "OTP not found", nil)
+	}
+
+	if err := pendingError(&otp); err != nil {
+		e.logf("❌ Magic link for OTP %s rejected: %s\n", otp.ID, err.Code)
+		return &otp, err
+	}
+	if otp.MagicLinkID == "" || subtle.ConstantTimeCompare([]byte(otp.MagicLinkID), []byte(linkID)) != 1 {
+		e.logf("❌ Magic link for OTP %s rejected: unknown link\n", otp.ID)
+		return &otp, NewError(http.StatusNotFound, ErrorOTPNotFound, "OTP not found", nil)
+	}
+
+	// Mark verified and consume the link in one conditional update, unless
+	// the code was locked meanwhile
+	now := time.Now()
+	result := e.db(ctx).Model(&models.OTP{}).
+		Where("id = ? AND is_verified = ? AND revoked_at IS NULL AND superseded_by = ? AND magic_link_id = ? AND attempt_count < max_attempts", otp.ID, false, "", linkID).
+		Updates(map[string]interface{}{
+			"is_verified":   true,
+			"verified_at":   now,
+			"magic_link_id": "",
+		})
+	if result.Error != nil {
+		return &otp, internalError("Failed to verify OTP", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return &otp, e.lostRace(ctx, otp.ID)
+	}
+
+	otp.IsVerified = true
+	otp.VerifiedAt = &now
+	otp.MagicLinkID = ""
+	e.logf("✅ OTP %s verified by magic link\n", otp.ID)
+	e.Emit(ctx, EventVerified, &otp, map[string]interface{}{"method": VerifiedByMagicLink})
+	return &otp, nil
+}
+
+// Approve verifies a pending OTP without its code, after the user approved
+// it some other way, e.g. on an enrolled push device. method is reported
+// with EventVerified. Expired codes cannot be approved, and neither can
+// codes locked by wrong guesses: whoever is guessing may be the one who
+// triggered the approval request.
+func (e *Engine) Approve(ctx context.Context, otpID, method string) (*models.OTP, error) {
+	now := time.Now()
+	result := e.db(ctx).Model(&models.OTP{}).
+		Where("id = ? AND is_verified = ? AND revoked_at IS NULL AND superseded_by = ? AND expires_at > ? AND attempt_count < max_attempts",
+			otpID, false, "", now).
+		Updates(map[string]interface{}{"is_verified": true, "verified_at": now})
+	if result.Error != nil {
+		return nil, internalError("Failed to verify OTP", result.Error)
+	}
+	// Already verified with the code, revoked, resent, expired or locked
+	if result.RowsAffected != 1 {
+		return nil, e.lostRace(ctx, otpID)
+	}
+
+	var otp models.OTP
+	if err := e.db(ctx).Where("id = ?", otpID).First(&otp).Error; err != nil {
+		return nil, internalError("Failed to verify OTP", err)
+	}
+
+	e.logf("✅ OTP %s verified by %s\n", otp.ID, method)
+	e.Emit(ctx, EventVerified, &otp, map[string]interface{}{"method": method})
+	return &otp, nil
+}
+
+// Block uses up an OTP's remaining attempts so its code can no longer be
+// verified, e.g. when the user denied the request on a push device.
+// reason is reported with EventFailed.
+func (e *Engine) Block(ctx context.Context, otpID, reason string) error {
+	result := e.db(ctx).Model(&models.OTP{}).
+		Where("id = ? AND is_verified = ?", otpID, false).
+		Update("attempt_count", gorm.Expr("max_attempts"))
+	if result.Error != nil {
+		return internalError("Failed to block OTP", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return nil
+	}
+
+	var otp models.OTP
+	if err := e.db(ctx).Where("id = ?", otpID).First(&otp).Error; err != nil {
+		return internalError("Failed to block OTP", err)
+	}
+
+	e.logf("🚫 OTP %s blocked: %s\n", otp.ID, reason)
+	e.Emit(ctx, EventFailed, &otp, map[string]interface{}{"reason": reason, "attempts_remaining": 0})
+	return nil
+}
+
+// lostRace returns the error for an OTP whose conditional verify update
+// matched no row because it was verified, revoked or resent meanwhile
+func (e *Engine) lostRace(ctx context.Context, otpID string) *Error {
+	var otp models.OTP
+	if err := e.db(ctx).Where("id = ?", otpID).First(&otp).Error; err != nil {
+		return NewError(http.StatusNotFound, ErrorOTPNotFound, "OTP not found", nil)
+	}
+	if err := pendingError(&otp); err != nil {
+		return err
+	}
+	return NewError(http.StatusBadRequest, ErrorOTPAlreadyVerified, "OTP already verified", nil)
+}
+
+// pendingError returns the error for an OTP that can no longer be
+// verified, or nil if it is still pending
+func pendingError(otp *models.OTP) *Error {
+	switch {
+	case otp.IsVerified:
+		return NewError(http.StatusBadRequest, ErrorOTPAlreadyVerified, "OTP already verified", nil)
+	case otp.RevokedAt != nil:
+		return NewError(http.StatusGone, ErrorOTPRevoked, "OTP has been revoked", nil)
+	case otp.SupersededBy != "":
+		return NewError(http.StatusBadRequest, ErrorOTPSuperseded, "OTP has been replaced by a newer code", nil)
+	case time.Now().After(otp.ExpiresAt):
+		return NewError(http.StatusBadRequest, ErrorOTPExpired, "OTP has expired", map[string]interface{}{"expired_at": otp.ExpiresAt})
+	case otp.AttemptCount >= otp.MaxAttempts:
+		return NewError(http.StatusBadRequest, ErrorOTPLocked, "Maximum verification attempts exceeded", map[string]interface{}{"max_attempts": otp.MaxAttempts})
+	}
+	return nil
+}
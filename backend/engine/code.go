@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"crypto/subtle"
+	"strings"
+)
+
+// Code formats supported by OTP policies
+const (
+	FormatNumeric      = "numeric"
+	FormatAlphanumeric = "alphanumeric"
+)
+
+// Alphabets used to generate codes. The alphanumeric alphabet leaves out
+// characters that are easily confused: 0/O and 1/I/L.
+const (
+	NumericAlphabet      = "0123456789"
+	AlphanumericAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+)
+
+// CodeAlphabet returns the alphabet for a format
+func CodeAlphabet(format string) string {
+	if format == FormatAlphanumeric {
+		return AlphanumericAlphabet
+	}
+	return NumericAlphabet
+}
+
+// FormatCode groups a code for display, e.g. "123456" -> "123-456".
+// A group size of 0 leaves the code unchanged.
+func FormatCode(code string, groupSize int) string {
+	if groupSize <= 0 || len(code) <= groupSize {
+		return code
+	}
+
+	var b strings.Builder
+	for i, r := range code {
+		if i > 0 && i%groupSize == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// NormalizeCode removes separators and whitespace and upper-cases the code
+// so users may type it the way it was displayed, or not
+func NormalizeCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '.', '_', '\t':
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// CodesMatch compares a stored code with user input in constant time,
+// ignoring case and separators
+func CodesMatch(stored, input string) bool {
+	return subtle.ConstantTimeCompare([]byte(NormalizeCode(stored)), []byte(NormalizeCode(input))) == 1
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"crypto/rand"
+	"math"
+	"strings"
+	"testing"
+)
+
+// chiSquared returns Pearson's statistic for counts that should all equal
+// the same expected value
+func chiSquared(counts map[rune]int, alphabet string, total int) float64 {
+	expected := float64(total) / float64(len(alphabet))
+	var stat float64
+	for _, r := range alphabet {
+		d := float64(counts[r]) - expected
+		stat += d * d / expected
+	}
+	return stat
+}
+
+// chiSquaredCritical approximates the value a chi-squared statistic with df
+// degrees of freedom exceeds with probability 0.0001 (Wilson-Hilferty)
+func chiSquaredCritical(df int) float64 {
+	const z = 3.719
+	k := float64(df)
+	return k * math.Pow(1-2/(9*k)+z*math.Sqrt(2/(9*k)), 3)
+}
+
+// sampleCounts draws n codes and counts characters overall and per position
+func sampleCounts(t *testing.T, n, length int, alphabet string, generate func() string) (map[rune]int, []map[rune]int) {
+	t.Helper()
+	overall := map[rune]int{}
+	positions := make([]map[rune]int, length)
+	for i := range positions {
+		positions[i] = map[rune]int{}
+	}
+	for i := 0; i < n; i++ {
+		code := generate()
+		if len(code) != length {
+			t.Fatalf("code %q has length %d, want %d", code, len(code), length)
+		}
+		for pos, r := range code {
+			if !strings.ContainsRune(alphabet, r) {
+				t.Fatalf("code %q contains %q, which is not in %q", code, r, alphabet)
+			}
+			overall[r]++
+			positions[pos][r]++
+		}
+	}
+	return overall, positions
+}
+
+// Every character of each alphabet must be equally likely at every position
+func TestGenerateCodeIsUniform(t *testing.T) {
+	const n, length = 20000, 6
+	for _, alphabet := range []string{NumericAlphabet, AlphanumericAlphabet} {
+		overall, positions := sampleCounts(t, n, length, alphabet, func() string {
+			code, err := GenerateCode(length, alphabet)
+			if err != nil {
+				t.Fatalf("GenerateCode: %v", err)
+			}
+			return code
+		})
+
+		critical := chiSquaredCritical(len(alphabet) - 1)
+		if stat := chiSquared(overall, alphabet, n*length); stat > critical {
+			t.Errorf("%s: chi-squared %.1f exceeds %.1f", alphabet, stat, critical)
+		}
+		for pos, counts := range positions {
+			if stat := chiSquared(counts, alphabet, n); stat > critical {
+				t.Errorf("%s position %d: chi-squared %.1f exceeds %.1f", alphabet, pos, stat, critical)
+			}
+		}
+	}
+}
+
+// The test must be able to catch the usual mistake of reducing a random
+// byte modulo the alphabet size
+func TestUniformityCheckDetectsModuloBias(t *testing.T) {
+	const n, length = 20000, 6
+	alphabet := AlphanumericAlphabet
+	overall, _ := sampleCounts(t, n, length, alphabet, func() string {
+		buf := make([]byte, length)
+		rand.Read(buf)
+		for i, b := range buf {
+			buf[i] = alphabet[int(b)%len(alphabet)]
+		}
+		return string(buf)
+	})
+
+	if stat := chiSquared(overall, alphabet, n*length); stat <= chiSquaredCritical(len(alphabet)-1) {
+		t.Errorf("biased generator passed with chi-squared %.1f", stat)
+	}
+}
+
+func TestFormatAndMatchCodes(t *testing.T) {
+	for _, tc := range []struct {
+		code      string
+		groupSize int
+		want      string
+	}{
+		{"123456", 3, "123-456"},
+		{"ABCDEFGH", 4, "ABCD-EFGH"},
+		{"1234567", 3, "123-456-7"},
+		{"123456", 0, "123456"},
+		{"123", 3, "123"},
+	} {
+		if got := FormatCode(tc.code, tc.groupSize); got != tc.want {
+			t.Errorf("FormatCode(%q, %d) = %q, want %q", tc.code, tc.groupSize, got, tc.want)
+		}
+	}
+
+	for _, input := range []string{"ABCD-EFGH", "abcd efgh", "abcdefgh", "ab.cd_ef-gh"} {
+		if !CodesMatch("ABCDEFGH", input) {
+			t.Errorf("%q does not match ABCDEFGH", input)
+		}
+	}
+	for _, input := range []string{"ABCDEFG", "ABCDEFGX", "ABCDEFGHI"} {
+		if CodesMatch("ABCDEFGH", input) {
+			t.Errorf("%q matches ABCDEFGH", input)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+)
+
+// Delivery statuses stored on an OTP and returned to clients
+const (
+	DeliveryNotSent = "not_sent"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	// DeliveryQueued is returned while an asynchronous delivery is pending
+	DeliveryQueued = "queued"
+	// DeliveryNotConfigured predates pluggable senders; the value is kept
+	// for clients that already check it
+	DeliveryNotConfigured = "twilio_not_configured"
+)
+
+// ErrSenderNotConfigured is returned by a Sender that lacks credentials
+var ErrSenderNotConfigured = errors.New("sender not configured")
+
+// Message is a code ready to be delivered
+type Message struct {
+	OTPID string
+	// To is the email address or phone number
+	To   string
+	Code string
+	// Text is the message rendered from the purpose's template
+	Text      string
+	MagicLink string
+}
+
+// Sender delivers codes over one channel, e.g. SMS or email
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SenderFunc adapts a function to the Sender interface
+type SenderFunc func(ctx context.Context, msg Message) error
+
+// Send calls f
+func (f SenderFunc) Send(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// Deliver sends the code over the OTP's channel and builds its magic link.
+// It returns the delivery status, which is also stored on the OTP, the
+// link (empty if none) and whether the link was emailed. The link is only
+// ever emailed, never sent by SMS.
+func (e *Engine) Deliver(ctx context.Context, otp *models.OTP, otpCode string, policy *OTPPolicy) (string, string, bool) {
+	var magicLink string
+	if otp.MagicLinkID != "" && e.config.MagicLink != nil {
+		link, err := e.config.MagicLink(otp)
+		if err != nil {
+			e.logf("⚠️  Failed to create magic link: %v\n", err)
+		}
+		magicLink = link
+	}
+
+	msg := Message{
+		OTPID: otp.ID,
+		Code:  otpCode,
+		Text:  policy.RenderMessage(otpCode, otp.TransactionSummary),
+	}
+
+	// The SMS status is reported when the OTP has a phone number
+	status := DeliveryNotSent
+	if otp.Phone != "" {
+		msg.To = otp.Phone
+		status = e.send(ctx, e.config.SMS, msg)
+	}
+
+	var magicLinkSent bool
+	if otp.Email != "" {
+		msg.To = otp.Email
+		msg.MagicLink = magicLink
+		if e.config.Email != nil {
+			emailStatus := e.send(ctx, e.config.Email, msg)
+			if otp.Phone == "" {
+				status = emailStatus
+			}
+			magicLinkSent = magicLink != "" && emailStatus == DeliverySent
+		} else {
+			e.logf("📧 No email sender configured, OTP %s not emailed\n", otp.ID)
+		}
+	}
+
+	otp.DeliveryStatus = status
+	e.db(ctx).Model(otp).Update("delivery_status", status)
+
+	channel := "email"
+	if otp.Phone != "" {
+		channel = "sms"
+	}
+	switch status {
+	case DeliverySent:
+		e.Emit(ctx, EventDelivered, otp, map[string]interface{}{"channel": channel, "status": status})
+	case DeliveryFailed:
+		e.Emit(ctx, EventFailed, otp, map[string]interface{}{"reason": FailedDelivery, "channel": channel})
+	}
+
+	return status, magicLink, magicLinkSent
+}
+
+// send delivers msg and returns the resulting delivery status
+func (e *Engine) send(ctx context.Context, sender Sender, msg Message) string {
+	if sender == nil {
+		return DeliveryNotSent
+	}
+
+	err := sender.Send(ctx, msg)
+	switch {
+	case err == nil:
+		return DeliverySent
+	case errors.Is(err, ErrSenderNotConfigured):
+		return DeliveryNotConfigured
+	default:
+		e.logf("❌ Failed to deliver OTP %s: %v\n", msg.OTPID, err)
+		return DeliveryFailed
+	}
+}
+
+// GenerateCode generates a cryptographically secure random code.
+// rand.Int draws uniformly, so every alphabet character is equally likely.
+func GenerateCode(length int, alphabet string) (string, error) {
+	code := make([]byte, length)
+
+	for i := range code {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = alphabet[num.Int64()]
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,681 @@
+// Package engine generates, stores, delivers and verifies one-time codes.
+// It is the core of the OTP server's HTTP and gRPC APIs and can be
+// embedded in other Go services that want OTP verification without
+// deploying the server:
+//
+//	otps := engine.New(engine.Config{DB: db, SMS: mySMSSender})
+//	issued, err := otps.Generate(ctx, engine.GenerateRequest{Phone: "+919876543210"})
+//	...
+//	otp, err := otps.Verify(ctx, engine.VerifyRequest{OTPID: issued.OTP.ID, Code: code})
+//
+// OTPs are stored in the otps table of models.OTP. Failed operations
+// return an *Error carrying one of the ErrorCode values. The package only
+// depends on GORM and reads no environment variables; the server's
+// configuration lives in its utils package.
+package engine
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/securetoken"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Config configures an Engine. Only DB is required.
+type Config struct {
+	// DB stores the OTPs. The otps table must already exist.
+	DB *gorm.DB
+	// Policy returns the policy for a purpose. Defaults to DefaultPolicy.
+	Policy func(purpose string) (*OTPPolicy, error)
+	// SMS and Email deliver codes. Codes for a channel without a sender
+	// are stored but not delivered.
+	SMS   Sender
+	Email Sender
+	// MagicLink builds the link emailed with OTPs that asked for one.
+	// Without it magic links are unavailable.
+	MagicLink func(otp *models.OTP) (string, error)
+	// AllowReturnURL reports whether a magic link may redirect to a URL.
+	// Without it no return URL is accepted.
+	AllowReturnURL func(url string) bool
+	// MaxPerHour limits the OTPs an email or phone number can request per
+	// hour. Defaults to 3.
+	MaxPerHour int
+	// OnEvent is called as OTPs are generated, delivered, verified, fail
+	// and expire. It runs on the caller's goroutine and must not block.
+	OnEvent func(ctx context.Context, event Event)
+	// Logger receives a line for every operation. Lines never contain
+	// codes or tokens. Nil disables logging.
+	Logger func(format string, args ...interface{})
+}
+
+// Engine runs OTP operations against one database
+type Engine struct {
+	config Config
+}
+
+// New returns an engine for the configuration
+func New(config Config) *Engine {
+	if config.Policy == nil {
+		config.Policy = DefaultPolicy
+	}
+	if config.AllowReturnURL == nil {
+		config.AllowReturnURL = func(string) bool { return false }
+	}
+	if config.MaxPerHour <= 0 {
+		config.MaxPerHour = 3
+	}
+	return &Engine{config: config}
+}
+
+// GenerateRequest asks for a new OTP
+type GenerateRequest struct {
+	// Email or Phone is the destination; email wins if both are set
+	Email string
+	Phone string
+	// Purpose is what the code authorizes; defaults to DefaultPurpose
+	Purpose string
+	// Action limits the code to one protected action, e.g. a step-up for
+	// "DELETE /account". It must be presented again to verify the code.
+	Action string
+	// Transaction is required for, and only allowed with, purpose
+	// "transaction". The OTP is bound to it.
+	Transaction *TransactionContext
+	// MagicLink also emails a link that verifies without typing the code
+	MagicLink bool
+	ReturnURL string
+	ClientIP  string
+	// Precheck runs once the destination is known, before rate limits are
+	// applied and a code is issued. An error aborts the request.
+	Precheck func(email, phone string) error
+	// Async delivers the code in the background so the caller does not
+	// wait for the SMS or email provider. The returned DeliveryStatus is
+	// DeliveryQueued and no magic link is returned.
+	Async bool
+	// Decoy stores the request like a real one, so it counts toward rate
+	// limits and bot checks, but sends nothing, emits no events and revokes
+	// the OTP at once. Login uses it for addresses without an account.
+	Decoy bool
+}
+
+// ResendRequest asks for a new code replacing an existing OTP
+type ResendRequest struct {
+	OTPID    string
+	ClientIP string
+	Precheck func(email, phone string) error
+}
+
+// VerifyRequest presents a code
+type VerifyRequest struct {
+	OTPID string
+	Code  string
+	// Purpose must match the purpose the OTP was issued for; defaults to
+	// DefaultPurpose
+	Purpose string
+	// Action must match the action the OTP was issued for, if any
+	Action string
+	// TransactionHash or Transaction must match the transaction the OTP
+	// was issued for
+	TransactionHash string
+	Transaction     *TransactionContext
+	// Email or Phone, when set, must be the OTP's destination. Other OTPs
+	// are reported as not found.
+	Email string
+	Phone string
+}
+
+// RevokeRequest selects the pending OTPs to revoke
+type RevokeRequest struct {
+	Email   string
+	Phone   string
+	Purpose string // all purposes if empty
+	Reason  string
+}
+
+// Issued is a newly generated OTP. Code and StatusToken are only
+// available here; the database keeps the code and a hash of the token.
+type Issued struct {
+	OTP    *models.OTP
+	Policy *OTPPolicy
+	Code   string
+	// StatusToken authorizes Lookup and Cancel for this OTP
+	StatusToken    string
+	DeliveryStatus string
+	MagicLink      string
+	// MagicLinkSent is set once the link was emailed successfully
+	MagicLinkSent bool
+}
+
+// Generate creates an OTP and delivers it
+func (e *Engine) Generate(ctx context.Context, req GenerateRequest) (*Issued, error) {
+	// Validate that at least email or phone is provided
+	if req.Email == "" && req.Phone == "" {
+		return nil, NewError(http.StatusBadRequest, ErrorIdentifierRequired, "Either email or phone number is required", nil)
+	}
+
+	// Transaction approvals must carry the transaction they approve
+	if req.Transaction != nil && req.Purpose == "" {
+		req.Purpose = PurposeTransaction
+	}
+	if (req.Purpose == PurposeTransaction) != (req.Transaction != nil) {
+		return nil, NewError(http.StatusBadRequest, ErrorTransactionRequired, "Transaction details are required for, and only allowed with, purpose \"transaction\"", nil)
+	}
+	if req.Transaction != nil {
+		if err := req.Transaction.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Magic links are emailed and cannot carry transaction details
+	if req.MagicLink && (req.Email == "" || req.Purpose == PurposeTransaction || e.config.MagicLink == nil) {
+		return nil, NewError(http.StatusBadRequest, ErrorMagicLinkUnavailable, "Magic links are only available for email verification without a transaction", nil)
+	}
+	if req.MagicLink && e.config.Email == nil {
+		return nil, NewError(http.StatusBadRequest, ErrorMagicLinkUnavailable, "Magic links are unavailable because no email sender is configured", nil)
+	}
+	if req.ReturnURL != "" && !e.config.AllowReturnURL(req.ReturnURL) {
+		return nil, NewError(http.StatusBadRequest, ErrorReturnURLNotAllowed, "Return URL is not allowed", nil)
+	}
+
+	if req.Precheck != nil {
+		if err := req.Precheck(req.Email, req.Phone); err != nil {
+			return nil, err
+		}
+	}
+
+	if e.RecentCount(ctx, req.Email, req.Phone) >= int64(e.config.MaxPerHour) {
+		return nil, NewError(http.StatusTooManyRequests, ErrorRateLimited, "Too many OTP requests. Please try again after an hour", nil)
+	}
+
+	// Look up the policy for what this code authorizes
+	policy, err := e.config.Policy(req.Purpose)
+	if err != nil {
+		return nil, NewError(http.StatusBadRequest, ErrorPurposeInvalid, "Invalid OTP purpose", map[string]interface{}{"error": err.Error()})
+	}
+
+	otpCode, err := GenerateCode(policy.Length, policy.Alphabet())
+	if err != nil {
+		return nil, internalError("Failed to generate OTP", err)
+	}
+
+	// Only the client holding this token can look up the OTP's status
+	statusToken, err := securetoken.New(24)
+	if err != nil {
+		return nil, internalError("Failed to generate OTP", err)
+	}
+
+	var transactionHash, transactionSummary string
+	if req.Transaction != nil {
+		transactionHash = req.Transaction.Hash()
+		transactionSummary = models.Truncate(req.Transaction.Summary(), 255)
+	}
+
+	otp := models.OTP{
+		ID:           uuid.New().String(),
+		Email:        req.Email,
+		Phone:        req.Phone,
+		OTPCode:      otpCode,
+		Purpose:      policy.Purpose,
+		Action:       req.Action,
+		IsVerified:   false,
+		AttemptCount: 0,
+		MaxAttempts:  policy.MaxAttempts,
+		ClientIP:     req.ClientIP,
+		ExpiresAt:    time.Now().Add(policy.Expiry),
+
+		TransactionHash:    transactionHash,
+		TransactionSummary: transactionSummary,
+
+		StatusTokenHash: securetoken.Hash(statusToken),
+	}
+	if req.MagicLink {
+		otp.MagicLinkID = uuid.New().String()
+		otp.ReturnURL = req.ReturnURL
+	}
+
+	if req.Decoy {
+		now := time.Now()
+		otp.RevokedAt = &now
+		otp.RevokeReason = "decoy"
+	}
+
+	if err := e.db(ctx).Create(&otp).Error; err != nil {
+		return nil, internalError("Failed to save OTP", err)
+	}
+
+	// Deliver the code (and magic link, if requested)
+	var deliveryStatus, magicLink string
+	var magicLinkSent bool
+	switch {
+	case req.Decoy:
+		deliveryStatus = DeliveryNotSent
+		if req.Async {
+			deliveryStatus = DeliveryQueued
+		}
+	case req.Async:
+		e.Emit(ctx, EventGenerated, &otp, nil)
+		deliveryStatus = DeliveryQueued
+		queued := otp
+		go e.Deliver(context.WithoutCancel(ctx), &queued, otpCode, policy)
+	default:
+		e.Emit(ctx, EventGenerated, &otp, nil)
+		deliveryStatus, magicLink, magicLinkSent = e.Deliver(ctx, &otp, otpCode, policy)
+	}
+
+	// Log the OTP; the code itself is never logged
+	e.logf("\n═══════════════════════════════════════════\n")
+	e.logf("         🔐 OTP GENERATED                 \n")
+	e.logf("═══════════════════════════════════════════\n")
+	e.logf("OTP ID:      %s\n", otp.ID)
+	e.logf("Email:       %s\n", req.Email)
+	e.logf("Phone:       %s\n", req.Phone)
+	e.logf("Purpose:     %s\n", otp.Purpose)
+	if otp.TransactionSummary != "" {
+		e.logf("Transaction: %s\n", otp.TransactionSummary)
+	}
+	e.logf("SMS Status:  %s\n", deliveryStatus)
+	e.logf("Expires At:  %s\n", otp.ExpiresAt.Format("2006-01-02 15:04:05"))
+	e.logf("Valid For:   %d minutes\n", int(policy.Expiry/time.Minute))
+	e.logf("═══════════════════════════════════════════\n\n")
+
+	return &Issued{
+		OTP:            &otp,
+		Policy:         policy,
+		Code:           otpCode,
+		StatusToken:    statusToken,
+		DeliveryStatus: deliveryStatus,
+		MagicLink:      magicLink,
+		MagicLinkSent:  magicLinkSent,
+	}, nil
+}
+
+// Resend replaces an OTP with a new code sent to the same destination.
+// The old code and magic link stop working.
+func (e *Engine) Resend(ctx context.Context, req ResendRequest) (*Issued, error) {
+	e.logf("\n🔄 OTP Resend Request\n")
+	e.logf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	e.logf("OTP ID: %s\n", req.OTPID)
+	e.logf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+
+	var oldOTP models.OTP
+	if err := e.db(ctx).Where("id = ?", req.OTPID).First(&oldOTP).Error; err != nil {
+		e.logf("❌ OTP not found\n\n")
+		return nil, NewError(http.StatusNotFound, ErrorOTPNotFound, "OTP not found", nil)
+	}
+
+	if oldOTP.IsVerified {
+		e.logf("❌ OTP already verified\n\n")
+		return nil, NewError(http.StatusBadRequest, ErrorOTPAlreadyVerified, "OTP already verified", nil)
+	}
+
+	if req.Precheck != nil {
+		if err := req.Precheck(oldOTP.Email, oldOTP.Phone); err != nil {
+			return nil, err
+		}
+	}
+
+	// Reuse the policy of the original purpose
+	policy, err := e.config.Policy(oldOTP.Purpose)
+	if err != nil {
+		return nil, NewError(http.StatusBadRequest, ErrorPurposeInvalid, "Invalid OTP purpose", map[string]interface{}{"error": err.Error()})
+	}
+
+	if oldOTP.RevokedAt != nil {
+		return nil, NewError(http.StatusGone, ErrorOTPRevoked, "OTP has been revoked", nil)
+	}
+
+	// Resending from a replaced OTP would bypass the cooldown
+	if oldOTP.SupersededBy != "" {
+		return nil, NewError(http.StatusBadRequest, ErrorOTPSuperseded, "OTP has been replaced by a newer code", nil)
+	}
+
+	otpCode, err := GenerateCode(policy.Length, policy.Alphabet())
+	if err != nil {
+		return nil, internalError("Failed to generate OTP", err)
+	}
+
+	statusToken, err := securetoken.New(24)
+	if err != nil {
+		return nil, internalError("Failed to generate OTP", err)
+	}
+
+	newOTP := models.OTP{
+		ID:           uuid.New().String(),
+		Email:        oldOTP.Email,
+		Phone:        oldOTP.Phone,
+		OTPCode:      otpCode,
+		Purpose:      policy.Purpose,
+		Action:       oldOTP.Action,
+		IsVerified:   false,
+		AttemptCount: 0,
+		MaxAttempts:  policy.MaxAttempts,
+		ClientIP:     req.ClientIP,
+		ExpiresAt:    time.Now().Add(policy.Expiry),
+
+		TransactionHash:    oldOTP.TransactionHash,
+		TransactionSummary: oldOTP.TransactionSummary,
+
+		StatusTokenHash: securetoken.Hash(statusToken),
+	}
+	if oldOTP.MagicLinkID != "" {
+		newOTP.MagicLinkID = uuid.New().String()
+		newOTP.ReturnURL = oldOTP.ReturnURL
+	}
+
+	// Superseding the old OTP, the cooldown check and saving the new one
+	// happen in one transaction. The conditional update locks the old row,
+	// so of two concurrent resends only one can replace it.
+	err = e.db(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.OTP{}).
+			Where("id = ? AND superseded_by = ? AND is_verified = ? AND revoked_at IS NULL", oldOTP.ID, "", false).
+			Updates(map[string]interface{}{"superseded_by": newOTP.ID, "magic_link_id": ""})
+		if result.Error != nil {
+			return internalError("Failed to resend OTP", result.Error)
+		}
+		if result.RowsAffected != 1 {
+			// Verified, revoked or resent since it was loaded
+			var current models.OTP
+			if err := tx.Where("id = ?", oldOTP.ID).First(&current).Error; err != nil {
+				return internalError("Failed to resend OTP", err)
+			}
+			if err := pendingError(&current); err != nil {
+				return err
+			}
+			return NewError(http.StatusBadRequest, ErrorOTPSuperseded, "OTP has been replaced by a newer code", nil)
+		}
+
+		resendAvailableAt := oldOTP.CreatedAt.Add(policy.ResendCooldown)
+		if time.Now().Before(resendAvailableAt) {
+			retryAfter := int(time.Until(resendAvailableAt).Seconds()) + 1
+			err := NewError(http.StatusTooManyRequests, ErrorResendCooldown, fmt.Sprintf("Please wait %d seconds before requesting a new code", retryAfter),
+				map[string]interface{}{"resend_available_at": resendAvailableAt})
+			err.RetryAfter = retryAfter
+			return err
+		}
+
+		if err := tx.Create(&newOTP).Error; err != nil {
+			return internalError("Failed to save OTP", err)
+		}
+		return nil
+	})
+	if err != nil {
+		e.logf("❌ Resend of OTP %s rejected: %s\n\n", oldOTP.ID, AsError(err).Code)
+		return nil, err
+	}
+
+	e.Emit(ctx, EventGenerated, &newOTP, map[string]interface{}{"replaces": oldOTP.ID})
+
+	deliveryStatus, magicLink, magicLinkSent := e.Deliver(ctx, &newOTP, otpCode, policy)
+
+	// Log the new OTP; the code itself is never logged
+	e.logf("\n═══════════════════════════════════════════\n")
+	e.logf("         🔁 OTP RESENT                    \n")
+	e.logf("═══════════════════════════════════════════\n")
+	e.logf("New OTP ID:  %s\n", newOTP.ID)
+	e.logf("Phone:       %s\n", oldOTP.Phone)
+	e.logf("SMS Status:  %s\n", deliveryStatus)
+	e.logf("Expires At:  %s\n", newOTP.ExpiresAt.Format("2006-01-02 15:04:05"))
+	e.logf("═══════════════════════════════════════════\n\n")
+
+	return &Issued{
+		OTP:            &newOTP,
+		Policy:         policy,
+		Code:           otpCode,
+		StatusToken:    statusToken,
+		DeliveryStatus: deliveryStatus,
+		MagicLink:      magicLink,
+		MagicLinkSent:  magicLinkSent,
+	}, nil
+}
+
+// Verify checks a code and marks the OTP verified. Attempts are counted
+// with conditional updates so concurrent guesses cannot exceed the limit
+// and a code can only be used once.
+func (e *Engine) Verify(ctx context.Context, req VerifyRequest) (*models.OTP, error) {
+	e.logf("\n🔍 OTP Verification Attempt\n")
+	e.logf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	e.logf("OTP ID: %s\n", req.OTPID)
+	e.logf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+
+	var otp models.OTP
+	err := e.db(ctx).Where("id = ?", req.OTPID).First(&otp).Error
+	if err != nil || (req.Email != "" && otp.Email != req.Email) || (req.Phone != "" && otp.Phone != req.Phone) {
+		e.logf("❌ OTP not found in database\n\n")
+		return nil, NewError(http.StatusNotFound, ErrorOTPNotFound, "OTP not found", nil)
+	}
+
+	if otp.IsVerified {
+		e.logf("❌ OTP already used\n\n")
+		return nil, NewError(http.StatusBadRequest, ErrorOTPAlreadyVerified, "OTP already verified", nil)
+	}
+
+	// Cancelled by the client or revoked by an administrator
+	if otp.RevokedAt != nil {
+		e.logf("❌ OTP revoked at: %s\n\n", otp.RevokedAt.Format("2006-01-02 15:04:05"))
+		return nil, NewError(http.StatusGone, ErrorOTPRevoked, "OTP has been revoked", nil)
+	}
+
+	// A resend replaces the code; only the newest one is valid
+	if otp.SupersededBy != "" {
+		e.logf("❌ OTP superseded by %s\n\n", otp.SupersededBy)
+		return nil, NewError(http.StatusBadRequest, ErrorOTPSuperseded, "OTP has been replaced by a newer code", nil)
+	}
+
+	if time.Now().After(otp.ExpiresAt) {
+		e.logf("❌ OTP expired at: %s\n\n", otp.ExpiresAt.Format("2006-01-02 15:04:05"))
+		return nil, NewError(http.StatusBadRequest, ErrorOTPExpired, "OTP has expired", map[string]interface{}{"expired_at": otp.ExpiresAt})
+	}
+
+	// Count the attempt unless the OTP's policy limit is already reached
+	result := e.db(ctx).Model(&models.OTP{}).
+		Where("id = ? AND is_verified = ? AND attempt_count < max_attempts", otp.ID, false).
+		Update("attempt_count", gorm.Expr("attempt_count + 1"))
+	if result.Error != nil {
+		return nil, internalError("Failed to verify OTP", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		e.logf("❌ Maximum attempts exceeded\n\n")
+		return nil, NewError(http.StatusBadRequest, ErrorOTPLocked, "Maximum verification attempts exceeded", map[string]interface{}{"max_attempts": otp.MaxAttempts})
+	}
+	otp.AttemptCount++
+
+	// A code issued for one purpose or action can never authorize another
+	purpose := req.Purpose
+	if purpose == "" {
+		purpose = DefaultPurpose
+	}
+	if otp.Purpose != purpose || otp.Action != req.Action {
+		e.logf("❌ Purpose mismatch: issued for %s %q, presented for %s %q\n\n", otp.Purpose, otp.Action, purpose, req.Action)
+		e.emitFailed(ctx, &otp, FailedPurposeMismatch)
+		return nil, NewError(http.StatusBadRequest, ErrorPurposeMismatch, "OTP was not issued for this purpose", nil)
+	}
+
+	// Transaction approvals must present the same transaction (dynamic linking)
+	if otp.TransactionHash != "" {
+		presented := req.TransactionHash
+		if req.Transaction != nil {
+			if err := req.Transaction.Validate(); err != nil {
+				return nil, err
+			}
+			presented = req.Transaction.Hash()
+		}
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(otp.TransactionHash)) != 1 {
+			e.logf("❌ Transaction details do not match the approved transaction\n\n")
+			e.emitFailed(ctx, &otp, FailedTransactionMismatch)
+			return nil, NewError(http.StatusBadRequest, ErrorTransactionMismatch, "Transaction details do not match the approved transaction", nil)
+		}
+	}
+
+	if !CodesMatch(otp.OTPCode, req.Code) {
+		remaining := otp.MaxAttempts - otp.AttemptCount
+		e.logf("❌ Invalid OTP code. Attempts remaining: %d\n\n", remaining)
+		e.emitFailed(ctx, &otp, FailedInvalidCode)
+		return nil, NewError(http.StatusBadRequest, ErrorOTPInvalid, fmt.Sprintf("Invalid OTP code. %d attempts remaining", remaining),
+			map[string]interface{}{"attempts_remaining": remaining})
+	}
+
+	// Only the verification columns are written, and only while the OTP is
+	// still pending, so a concurrent verification, revoke or resend wins
+	now := time.Now()
+	result = e.db(ctx).Model(&models.OTP{}).
+		Where("id = ? AND is_verified = ? AND revoked_at IS NULL AND superseded_by = ?", otp.ID, false, "").
+		Updates(map[string]interface{}{"is_verified": true, "verified_at": now})
+	if result.Error != nil {
+		return nil, internalError("Failed to verify OTP", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return nil, e.lostRace(ctx, otp.ID)
+	}
+
+	otp.IsVerified = true
+	otp.VerifiedAt = &now
+	e.Emit(ctx, EventVerified, &otp, map[string]interface{}{"method": VerifiedByCode})
+	return &otp, nil
+}
+
+// Lookup returns an OTP to the holder of its status token. Unknown IDs and
+// wrong tokens get the same error so IDs cannot be probed.
+func (e *Engine) Lookup(ctx context.Context, id, statusToken string) (*models.OTP, error) {
+	var otp models.OTP
+	err := e.db(ctx).Where("id = ?", id).First(&otp).Error
+	if err != nil || statusToken == "" || otp.StatusTokenHash == "" ||
+		subtle.ConstantTimeCompare([]byte(securetoken.Hash(statusToken)), []byte(otp.StatusTokenHash)) != 1 {
+		return nil, NewError(http.StatusNotFound, ErrorOTPNotFound, "OTP not found", nil)
+	}
+
+	return &otp, nil
+}
+
+// maxResends bounds how many replacements LookupLatest follows
+const maxResends = 20
+
+// LookupLatest is Lookup for the newest code of an OTP: if the OTP was
+// resent, it follows SupersededBy to the code that replaced it. The status
+// token of the first OTP covers its replacements, which go to the same
+// destination for the same purpose.
+func (e *Engine) LookupLatest(ctx context.Context, id, statusToken string) (*models.OTP, error) {
+	otp, err := e.Lookup(ctx, id, statusToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; otp.SupersededBy != "" && i < maxResends; i++ {
+		var next models.OTP
+		if err := e.db(ctx).Where("id = ?", otp.SupersededBy).First(&next).Error; err != nil {
+			return nil, internalError("Failed to look up OTP", err)
+		}
+		otp = &next
+	}
+	return otp, nil
+}
+
+// Cancel revokes a pending OTP for the holder of its status token.
+// Cancelling an OTP twice is not an error.
+func (e *Engine) Cancel(ctx context.Context, id, statusToken string) (*models.OTP, error) {
+	otp, err := e.Lookup(ctx, id, statusToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if otp.IsVerified {
+		return nil, NewError(http.StatusConflict, ErrorOTPAlreadyVerified, "OTP already verified", nil)
+	}
+
+	if otp.RevokedAt == nil {
+		now := time.Now()
+		result := e.db(ctx).Model(&models.OTP{}).
+			Where("id = ? AND is_verified = ? AND revoked_at IS NULL", otp.ID, false).
+			Updates(revokeUpdates(now, "cancelled"))
+		if result.Error != nil {
+			return nil, internalError("Failed to cancel OTP", result.Error)
+		}
+		// Lost the race against a verification
+		if result.RowsAffected != 1 {
+			return nil, NewError(http.StatusConflict, ErrorOTPAlreadyVerified, "OTP already verified", nil)
+		}
+		otp.RevokedAt = &now
+		e.logf("🚫 OTP %s cancelled by client\n", otp.ID)
+	}
+
+	return otp, nil
+}
+
+// Revoke revokes every pending OTP for an email or phone number and
+// returns how many were revoked
+func (e *Engine) Revoke(ctx context.Context, req RevokeRequest) (int64, error) {
+	if (req.Email == "") == (req.Phone == "") {
+		return 0, NewError(http.StatusBadRequest, ErrorIdentifierRequired, "Provide either an email or a phone number", nil)
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "admin"
+	}
+
+	query := e.db(ctx).Model(&models.OTP{}).
+		Where("is_verified = ? AND revoked_at IS NULL AND expires_at > ?", false, time.Now())
+	if req.Email != "" {
+		query = query.Where("email = ?", req.Email)
+	} else {
+		query = query.Where("phone = ?", req.Phone)
+	}
+	if req.Purpose != "" {
+		query = query.Where("purpose = ?", req.Purpose)
+	}
+
+	result := query.Updates(revokeUpdates(time.Now(), reason))
+	if result.Error != nil {
+		return 0, internalError("Failed to revoke OTPs", result.Error)
+	}
+
+	e.logf("🚫 Revoked %d pending OTP(s) for %s%s (%s)\n", result.RowsAffected, req.Email, req.Phone, reason)
+	return result.RowsAffected, nil
+}
+
+// Policy returns the engine's policy for a purpose
+func (e *Engine) Policy(purpose string) (*OTPPolicy, error) {
+	return e.config.Policy(purpose)
+}
+
+// RecentCount returns how many OTPs were requested for the email or phone
+// within the last hour
+func (e *Engine) RecentCount(ctx context.Context, email, phone string) int64 {
+	var count int64
+	oneHourAgo := time.Now().Add(-1 * time.Hour)
+
+	query := e.db(ctx).Model(&models.OTP{}).Where("created_at > ?", oneHourAgo)
+
+	if email != "" {
+		query = query.Where("email = ?", email)
+	} else {
+		query = query.Where("phone = ?", phone)
+	}
+
+	query.Count(&count)
+	return count
+}
+
+func (e *Engine) db(ctx context.Context) *gorm.DB {
+	return e.config.DB.WithContext(ctx)
+}
+
+// revokeUpdates marks an OTP revoked and retires its magic link
+func revokeUpdates(now time.Time, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"revoked_at":    now,
+		"revoke_reason": reason,
+		"magic_link_id": "",
+	}
+}
+
+// logf writes a line to Config.Logger, if set
+func (e *Engine) logf(format string, args ...interface{}) {
+	if e.config.Logger != nil {
+		e.config.Logger(format, args...)
+	}
+}
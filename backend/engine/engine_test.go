@@ -0,0 +1,444 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testOutbox records the messages sent by a test engine
+type testOutbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (o *testOutbox) Send(_ context.Context, msg Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// newTestEngine returns an engine on a fresh in-memory database that
+// records what it sends. Codes can be resent immediately.
+func newTestEngine(t *testing.T) (*Engine, *testOutbox) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// Every connection to :memory: is a separate database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.OTP{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	outbox := &testOutbox{}
+	e := New(Config{
+		DB:         db,
+		SMS:        outbox,
+		Email:      outbox,
+		MaxPerHour: 100,
+		Policy: func(purpose string) (*OTPPolicy, error) {
+			policy, err := DefaultPolicy(purpose)
+			if err != nil {
+				return nil, err
+			}
+			policy.ResendCooldown = 0
+			return policy, nil
+		},
+	})
+	return e, outbox
+}
+
+func errorCode(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+func TestResendReplacesCode(t *testing.T) {
+	e, _ := newTestEngine(t)
+	ctx := context.Background()
+
+	issued, err := e.Generate(ctx, GenerateRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	resent, err := e.Resend(ctx, ResendRequest{OTPID: issued.OTP.ID})
+	if err != nil {
+		t.Fatalf("Resend: %v", err)
+	}
+
+	if _, err := e.Verify(ctx, VerifyRequest{OTPID: issued.OTP.ID, Code: issued.Code}); errorCode(err) != ErrorOTPSuperseded {
+		t.Errorf("old code: got %v, want %s", err, ErrorOTPSuperseded)
+	}
+	if _, err := e.Verify(ctx, VerifyRequest{OTPID: resent.OTP.ID, Code: resent.Code}); err != nil {
+		t.Errorf("new code: %v", err)
+	}
+	if _, err := e.Resend(ctx, ResendRequest{OTPID: issued.OTP.ID}); errorCode(err) != ErrorOTPSuperseded {
+		t.Errorf("resend of replaced OTP: got %v, want %s", err, ErrorOTPSuperseded)
+	}
+}
+
+func TestResendCooldown(t *testing.T) {
+	e, _ := newTestEngine(t)
+	e.config.Policy = DefaultPolicy
+	ctx := context.Background()
+
+	issued, err := e.Generate(ctx, GenerateRequest{Phone: "+15550000001"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	_, err = e.Resend(ctx, ResendRequest{OTPID: issued.OTP.ID})
+	if errorCode(err) != ErrorResendCooldown {
+		t.Fatalf("got %v, want %s", err, ErrorResendCooldown)
+	}
+
+	// A rejected resend leaves the original code usable
+	if _, err := e.Verify(ctx, VerifyRequest{OTPID: issued.OTP.ID, Code: issued.Code}); err != nil {
+		t.Errorf("original code after rejected resend: %v", err)
+	}
+}
+
+func TestResendAfterVerify(t *testing.T) {
+	e, _ := newTestEngine(t)
+	ctx := context.Background()
+
+	issued, err := e.Generate(ctx, GenerateRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := e.Verify(ctx, VerifyRequest{OTPID: issued.OTP.ID, Code: issued.Code}); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if _, err := e.Resend(ctx, ResendRequest{OTPID: issued.OTP.ID}); errorCode(err) != ErrorOTPAlreadyVerified {
+		t.Errorf("got %v, want %s", err, ErrorOTPAlreadyVerified)
+	}
+}
+
+func TestConcurrentResendsIssueOneCode(t *testing.T) {
+	e, outbox := newTestEngine(t)
+	ctx := context.Background()
+
+	issued, err := e.Generate(ctx, GenerateRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	const resends = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, resends)
+	for i := 0; i < resends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := e.Resend(ctx, ResendRequest{OTPID: issued.OTP.ID})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else if errorCode(err) != ErrorOTPSuperseded {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d resends succeeded, want 1", succeeded)
+	}
+
+	var pending int64
+	e.config.DB.Model(&models.OTP{}).Where("email = ? AND superseded_by = ?", "user@example.com", "").Count(&pending)
+	if pending != 1 {
+		t.Errorf("%d OTPs are not superseded, want 1", pending)
+	}
+	if len(outbox.messages) != 2 {
+		t.Errorf("%d messages sent, want 2", len(outbox.messages))
+	}
+}
+
+func TestGenerateLogsNoCode(t *testing.T) {
+	e, _ := newTestEngine(t)
+	var lines []string
+	e.config.Logger = func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	issued, err := e.Generate(context.Background(), GenerateRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for _, line := range lines {
+		if strings.Contains(line, issued.Code) {
+			t.Fatalf("code logged: %q", line)
+		}
+	}
+}
+
+func TestApproveRejectsSupersededOTP(t *testing.T) {
+	e, _ := newTestEngine(t)
+	ctx := context.Background()
+
+	issued, err := e.Generate(ctx, GenerateRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := e.Resend(ctx, ResendRequest{OTPID: issued.OTP.ID}); err != nil {
+		t.Fatalf("Resend: %v", err)
+	}
+
+	if _, err := e.Approve(ctx, issued.OTP.ID, VerifiedByPush); errorCode(err) != ErrorOTPSuperseded {
+		t.Errorf("got %v, want %s", err, ErrorOTPSuperseded)
+	}
+	var otp models.OTP
+	e.config.DB.Where("id = ?", issued.OTP.ID).First(&otp)
+	if otp.IsVerified {
+		t.Error("superseded OTP was marked verified")
+	}
+}
+
+// An approval cannot revive a code that expired or was locked by wrong
+// guesses
+func TestApproveRejectsExpiredAndLockedOTP(t *testing.T) {
+	e, _ := newTestEngine(t)
+	ctx := context.Background()
+
+	expired, err := e.Generate(ctx, GenerateRequest{Email: "expired@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	e.config.DB.Model(&models.OTP{}).Where("id = ?", expired.OTP.ID).Update("expires_at", time.Now().Add(-time.Minute))
+
+	locked, err := e.Generate(ctx, GenerateRequest{Email: "locked@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for i := 0; i < locked.OTP.MaxAttempts; i++ {
+		e.Verify(ctx, VerifyRequest{OTPID: locked.OTP.ID, Code: "wrong"})
+	}
+
+	for id, want := range map[string]ErrorCode{expired.OTP.ID: ErrorOTPExpired, locked.OTP.ID: ErrorOTPLocked} {
+		if _, err := e.Approve(ctx, id, VerifiedByPush); errorCode(err) != want {
+			t.Errorf("got %v, want %s", err, want)
+		}
+		var otp models.OTP
+		e.config.DB.Where("id = ?", id).First(&otp)
+		if otp.IsVerified {
+			t.Errorf("%s OTP was approved", want)
+		}
+	}
+}
+
+func TestVerifyWritesOnlyVerificationColumns(t *testing.T) {
+	e, _ := newTestEngine(t)
+	ctx := context.Background()
+
+	issued, err := e.Generate(ctx, GenerateRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	// A resend recorded after Verify loaded the OTP must survive
+	var calls int
+	e.config.DB.Callback().Update().Before("gorm:update").Register("test:resend", func(db *gorm.DB) {
+		if calls++; calls == 2 {
+			db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Exec("UPDATE otps SET superseded_by = ? WHERE id = ?", "other", issued.OTP.ID)
+		}
+	})
+
+	if _, err := e.Verify(ctx, VerifyRequest{OTPID: issued.OTP.ID, Code: issued.Code}); errorCode(err) != ErrorOTPSuperseded {
+		t.Errorf("got %v, want %s", err, ErrorOTPSuperseded)
+	}
+	var otp models.OTP
+	e.config.DB.Where("id = ?", issued.OTP.ID).First(&otp)
+	if otp.IsVerified || otp.SupersededBy != "other" {
+		t.Errorf("verified %v, superseded by %q", otp.IsVerified, otp.SupersededBy)
+	}
+}
+
+func TestLookupLatestFollowsResends(t *testing.T) {
+	e, _ := newTestEngine(t)
+	ctx := context.Background()
+
+	issued, err := e.Generate(ctx, GenerateRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	first, err := e.Resend(ctx, ResendRequest{OTPID: issued.OTP.ID})
+	if err != nil {
+		t.Fatalf("Resend: %v", err)
+	}
+	second, err := e.Resend(ctx, ResendRequest{OTPID: first.OTP.ID})
+	if err != nil {
+		t.Fatalf("Resend: %v", err)
+	}
+
+	otp, err := e.LookupLatest(ctx, issued.OTP.ID, issued.StatusToken)
+	if err != nil || otp.ID != second.OTP.ID {
+		t.Errorf("got %v, %v; want OTP %s", otp, err, second.OTP.ID)
+	}
+	if _, err := e.LookupLatest(ctx, issued.OTP.ID, first.StatusToken); errorCode(err) != ErrorOTPNotFound {
+		t.Errorf("another OTP's token: got %v, want %s", err, ErrorOTPNotFound)
+	}
+}
+
+func TestGenerateAsyncDelivers(t *testing.T) {
+	e, outbox := newTestEngine(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	issued, err := e.Generate(ctx, GenerateRequest{Email: "async@example.com", Async: true})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	// Delivery must outlive the request that asked for it
+	cancel()
+	if issued.DeliveryStatus != DeliveryQueued {
+		t.Errorf("delivery status %q, want %q", issued.DeliveryStatus, DeliveryQueued)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		outbox.mu.Lock()
+		sent := len(outbox.messages)
+		outbox.mu.Unlock()
+		if sent == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("code was not delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// A decoy must look like a real request to every counter, and nothing else
+func TestDecoyIsRecordedButNeverSent(t *testing.T) {
+	e, outbox := newTestEngine(t)
+	ctx := context.Background()
+	var events []string
+	e.config.OnEvent = func(_ context.Context, event Event) { events = append(events, event.Type) }
+
+	issued, err := e.Generate(ctx, GenerateRequest{Email: "nobody@example.com", Async: true, Decoy: true})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if issued.DeliveryStatus != DeliveryQueued {
+		t.Errorf("delivery status %q, want %q like a real login", issued.DeliveryStatus, DeliveryQueued)
+	}
+	if n := e.RecentCount(ctx, "nobody@example.com", ""); n != 1 {
+		t.Errorf("decoy counted %d times toward the rate limit, want 1", n)
+	}
+
+	_, err = e.Verify(ctx, VerifyRequest{OTPID: issued.OTP.ID, Code: issued.Code})
+	if errorCode(err) != ErrorOTPRevoked {
+		t.Errorf("verify decoy: got %v, want %s", err, ErrorOTPRevoked)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if len(outbox.messages) != 0 || len(events) != 0 {
+		t.Errorf("decoy sent %d messages and emitted %v", len(outbox.messages), events)
+	}
+}
+
+// Guessing the code until it locks must also disable its magic link
+func TestMagicLinkRejectedOnceLocked(t *testing.T) {
+	e, _ := newTestEngine(t)
+	e.config.MagicLink = func(otp *models.OTP) (string, error) { return "https://example.com/magic", nil }
+	ctx := context.Background()
+
+	issued, err := e.Generate(ctx, GenerateRequest{Email: "user@example.com", MagicLink: true})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for i := 0; i < issued.OTP.MaxAttempts; i++ {
+		e.Verify(ctx, VerifyRequest{OTPID: issued.OTP.ID, Code: "wrong"})
+	}
+
+	if _, err := e.VerifyMagicLink(ctx, issued.OTP.ID, issued.OTP.MagicLinkID); errorCode(err) != ErrorOTPLocked {
+		t.Errorf("got %v, want %s", err, ErrorOTPLocked)
+	}
+	var otp models.OTP
+	e.config.DB.Where("id = ?", issued.OTP.ID).First(&otp)
+	if otp.IsVerified {
+		t.Error("locked OTP was verified by its magic link")
+	}
+}
+
+// Magic links are only emailed, so they need an email sender
+func TestMagicLinkNeedsEmailSender(t *testing.T) {
+	e, _ := newTestEngine(t)
+	e.config.MagicLink = func(otp *models.OTP) (string, error) { return "https://example.com/magic", nil }
+	e.config.Email = nil
+
+	_, err := e.Generate(context.Background(), GenerateRequest{Email: "user@example.com", MagicLink: true})
+	if errorCode(err) != ErrorMagicLinkUnavailable {
+		t.Errorf("got %v, want %s", err, ErrorMagicLinkUnavailable)
+	}
+}
+
+func TestMagicLinkOnlyEmailed(t *testing.T) {
+	e, outbox := newTestEngine(t)
+	e.config.MagicLink = func(otp *models.OTP) (string, error) { return "https://example.com/magic", nil }
+
+	issued, err := e.Generate(context.Background(), GenerateRequest{Email: "user@example.com", Phone: "+15550100", MagicLink: true})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !issued.MagicLinkSent {
+		t.Error("MagicLinkSent not set after the link was emailed")
+	}
+	var smsSent bool
+	for _, msg := range outbox.messages {
+		switch msg.To {
+		case "+15550100":
+			smsSent = true
+			if msg.MagicLink != "" || strings.Contains(msg.Text, "example.com/magic") {
+				t.Errorf("SMS carried the magic link: %+v", msg)
+			}
+		case "user@example.com":
+			if msg.MagicLink != "https://example.com/magic" {
+				t.Errorf("email link = %q", msg.MagicLink)
+			}
+		}
+	}
+	if !smsSent {
+		t.Error("no SMS sent")
+	}
+}
+
+func TestMagicLinkNotSentWhenEmailFails(t *testing.T) {
+	e, _ := newTestEngine(t)
+	e.config.MagicLink = func(otp *models.OTP) (string, error) { return "https://example.com/magic", nil }
+	e.config.Email = SenderFunc(func(context.Context, Message) error { return errors.New("smtp down") })
+
+	issued, err := e.Generate(context.Background(), GenerateRequest{Email: "user@example.com", MagicLink: true})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if issued.MagicLinkSent {
+		t.Error("MagicLinkSent set although the email failed")
+	}
+	if issued.MagicLink == "" {
+		t.Error("link not built")
+	}
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrorCode is a stable, machine-readable error identifier returned by the
+// versioned API. Codes are never renamed; new ones may be added.
+type ErrorCode string
+
+// Error codes of the versioned API
+const (
+	ErrorValidationFailed      ErrorCode = "VALIDATION_FAILED"
+	ErrorIdentifierRequired    ErrorCode = "IDENTIFIER_REQUIRED"
+	ErrorPurposeInvalid        ErrorCode = "PURPOSE_INVALID"
+	ErrorPurposeMismatch       ErrorCode = "PURPOSE_MISMATCH"
+	ErrorTransactionRequired   ErrorCode = "TRANSACTION_REQUIRED"
+	ErrorTransactionMismatch   ErrorCode = "TRANSACTION_MISMATCH"
+	ErrorMagicLinkUnavailable  ErrorCode = "MAGIC_LINK_UNAVAILABLE"
+	ErrorReturnURLNotAllowed   ErrorCode = "RETURN_URL_NOT_ALLOWED"
+	ErrorCaptchaRequired       ErrorCode = "CAPTCHA_REQUIRED"
+	ErrorCaptchaFailed         ErrorCode = "CAPTCHA_FAILED"
+	ErrorRateLimited           ErrorCode = "RATE_LIMITED"
+	ErrorResendCooldown        ErrorCode = "RESEND_COOLDOWN"
+	ErrorIdempotencyKeyInvalid ErrorCode = "IDEMPOTENCY_KEY_INVALID"
+	ErrorIdempotencyKeyReused  ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	ErrorIdempotencyInProgress ErrorCode = "IDEMPOTENCY_IN_PROGRESS"
+	ErrorOTPNotFound           ErrorCode = "OTP_NOT_FOUND"
+	ErrorOTPAlreadyVerified    ErrorCode = "OTP_ALREADY_VERIFIED"
+	ErrorOTPRevoked            ErrorCode = "OTP_REVOKED"
+	ErrorOTPSuperseded         ErrorCode = "OTP_SUPERSEDED"
+	ErrorOTPExpired            ErrorCode = "OTP_EXPIRED"
+	ErrorOTPLocked             ErrorCode = "OTP_LOCKED"
+	ErrorOTPInvalid            ErrorCode = "OTP_INVALID"
+	ErrorUnauthorized          ErrorCode = "UNAUTHORIZED"
+	ErrorStepUpRequired        ErrorCode = "STEP_UP_REQUIRED"
+	ErrorAdminDisabled         ErrorCode = "ADMIN_API_DISABLED"
+	ErrorInternal              ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrorInfo documents an error code
+type ErrorInfo struct {
+	Code  ErrorCode `json:"code"`
+	Title string    `json:"title"`
+}
+
+// ErrorCatalog lists every error code with a short, fixed title. The
+// title is used as the RFC 7807 "title"; messages may change, titles don't.
+var ErrorCatalog = []ErrorInfo{
+	{ErrorValidationFailed, "The request body is invalid"},
+	{ErrorIdentifierRequired, "An email or phone number is required"},
+	{ErrorPurposeInvalid, "Unknown OTP purpose"},
+	{ErrorPurposeMismatch, "The OTP was issued for a different purpose"},
+	{ErrorTransactionRequired, "Transaction details are missing or not allowed"},
+	{ErrorTransactionMismatch, "The transaction does not match the approved one"},
+	{ErrorMagicLinkUnavailable, "Magic links are not available for this request"},
+	{ErrorReturnURLNotAllowed, "The return URL is not allowed"},
+	{ErrorCaptchaRequired, "A verification challenge must be solved"},
+	{ErrorCaptchaFailed, "The verification challenge failed"},
+	{ErrorRateLimited, "Too many OTP requests"},
+	{ErrorResendCooldown, "A new code cannot be sent yet"},
+	{ErrorIdempotencyKeyInvalid, "The Idempotency-Key header is invalid"},
+	{ErrorIdempotencyKeyReused, "The Idempotency-Key was used with a different request"},
+	{ErrorIdempotencyInProgress, "A request with this Idempotency-Key is still being processed"},
+	{ErrorOTPNotFound, "OTP not found"},
+	{ErrorOTPAlreadyVerified, "The OTP has already been verified"},
+	{ErrorOTPRevoked, "The OTP has been revoked"},
+	{ErrorOTPSuperseded, "The OTP has been replaced by a newer code"},
+	{ErrorOTPExpired, "The OTP has expired"},
+	{ErrorOTPLocked, "Maximum verification attempts exceeded"},
+	{ErrorOTPInvalid, "The code is incorrect"},
+	{ErrorUnauthorized, "Missing or invalid credentials"},
+	{ErrorStepUpRequired, "A one-time code is required to continue"},
+	{ErrorAdminDisabled, "The admin API is disabled"},
+	{ErrorInternal, "Internal server error"},
+}
+
+// ErrorTitle returns the catalog title of a code
+func ErrorTitle(code ErrorCode) string {
+	for _, info := range ErrorCatalog {
+		if info.Code == code {
+			return info.Title
+		}
+	}
+	return string(code)
+}
+
+// APIResponse is the response envelope of the versioned API
+type APIResponse struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   *APIError   `json:"error,omitempty"`
+}
+
+// APIError describes a failed request in an APIResponse
+type APIError struct {
+	Code    ErrorCode              `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Error is a failed OTP operation. Status is the HTTP status the JSON API
+// answers with; other transports map Code to their own status codes.
+type Error struct {
+	Status  int
+	Code    ErrorCode
+	Message string
+	Details map[string]interface{}
+	// RetryAfter is the number of seconds to wait before retrying, if known
+	RetryAfter int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError returns an Error. Callers use it for checks of their own, such
+// as bot protection, so every failure reaches clients in the same shape.
+func NewError(status int, code ErrorCode, message string, details map[string]interface{}) *Error {
+	return &Error{Status: status, Code: code, Message: message, Details: details}
+}
+
+func internalError(message string, err error) *Error {
+	return NewError(http.StatusInternalServerError, ErrorInternal, message, map[string]interface{}{"error": err.Error()})
+}
+
+// AsError returns err as an *Error, reporting errors of other types as
+// internal errors
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return internalError("Internal server error", err)
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+)
+
+// Event types reported to Config.OnEvent
+const (
+	EventGenerated = "otp.generated"
+	EventDelivered = "otp.delivered"
+	EventVerified  = "otp.verified"
+	EventFailed    = "otp.failed"
+	EventExpired   = "otp.expired"
+)
+
+// Reasons reported with EventFailed
+const (
+	FailedInvalidCode         = "invalid_code"
+	FailedPurposeMismatch     = "purpose_mismatch"
+	FailedTransactionMismatch = "transaction_mismatch"
+	FailedDelivery            = "delivery_failed"
+	FailedPushDenied          = "push_denied"
+)
+
+// Ways an OTP is verified, reported with EventVerified
+const (
+	VerifiedByCode      = "code"
+	VerifiedByMagicLink = "magic_link"
+	VerifiedByPush      = "push"
+)
+
+// Event is a change in an OTP's lifecycle
+type Event struct {
+	Type string
+	OTP  *models.OTP
+	// Data holds fields of the event type, e.g. the delivery status or
+	// the failure reason
+	Data map[string]interface{}
+	At   time.Time
+}
+
+// Emit reports an event for an OTP that changed outside the engine, e.g.
+// one verified by push approval
+func (e *Engine) Emit(ctx context.Context, eventType string, otp *models.OTP, data map[string]interface{}) {
+	if e.config.OnEvent == nil {
+		return
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	e.config.OnEvent(ctx, Event{Type: eventType, OTP: otp, Data: data, At: time.Now()})
+}
+
+// ReportExpired emits EventExpired for codes that expired unused within
+// the last hour. Each OTP is reported once, even with several servers
+// running the check.
+func (e *Engine) ReportExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	var otps []models.OTP
+	err := e.db(ctx).
+		Where("is_verified = ? AND revoked_at IS NULL AND (superseded_by = '' OR superseded_by IS NULL) AND expiry_reported = ?", false, false).
+		Where("expires_at <= ? AND expires_at > ?", now, now.Add(-time.Hour)).
+		Limit(100).
+		Find(&otps).Error
+	if err != nil {
+		return 0, err
+	}
+
+	reported := 0
+	for i := range otps {
+		otp := &otps[i]
+		result := e.db(ctx).Model(&models.OTP{}).
+			Where("id = ? AND expiry_reported = ?", otp.ID, false).
+			Update("expiry_reported", true)
+		if result.Error != nil || result.RowsAffected != 1 {
+			continue
+		}
+		e.Emit(ctx, EventExpired, otp, map[string]interface{}{"expired_at": otp.ExpiresAt})
+		reported++
+	}
+
+	if reported > 0 {
+		e.logf("⌛ Reported %d expired OTP(s)\n", reported)
+	}
+	return reported, nil
+}
+
+// emitFailed reports a verification attempt that counted against the OTP
+func (e *Engine) emitFailed(ctx context.Context, otp *models.OTP, reason string) {
+	e.Emit(ctx, EventFailed, otp, map[string]interface{}{
+		"reason":             reason,
+		"attempts_remaining": otp.MaxAttempts - otp.AttemptCount,
+	})
+}
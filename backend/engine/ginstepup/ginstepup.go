@@ -0,0 +1,36 @@
+// Package ginstepup protects gin routes with the engine's step-up check.
+// It lives in its own package so services embedding the engine without
+// gin do not depend on it.
+package ginstepup
+
+import (
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware is engine.StepUp for gin routes. An empty action defaults to
+// the request's method and route pattern, e.g. "DELETE /account/:id".
+func Middleware(otps *engine.Engine, purpose, action string, identify func(c *gin.Context) (engine.Identity, bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := identify(c)
+		if !ok {
+			identity = engine.Identity{}
+		}
+
+		check := engine.StepUpCheck{
+			Purpose:  purpose,
+			Action:   action,
+			Identity: identity,
+			ClientIP: c.ClientIP(),
+		}
+		if check.Action == "" && c.FullPath() != "" {
+			check.Action = c.Request.Method + " " + c.FullPath()
+		}
+
+		if !otps.CheckStepUp(c.Writer, c.Request, check) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
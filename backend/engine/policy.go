@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTP purposes. A code is only accepted for the purpose it was issued for.
+const (
+	PurposeLogin         = "login"
+	PurposeSignup        = "signup"
+	PurposePasswordReset = "password_reset"
+	PurposeTransaction   = "transaction"
+)
+
+// DefaultPurpose is used when a client does not specify one
+const DefaultPurpose = PurposeLogin
+
+// OTPPolicy controls how codes for a purpose are generated and verified
+type OTPPolicy struct {
+	Purpose     string
+	Length      int
+	Format      string // numeric or alphanumeric
+	GroupSize   int    // display grouping, e.g. 3 for "123-456"; 0 disables
+	Expiry      time.Duration
+	MaxAttempts int
+	Template    string // supports {{code}}, {{minutes}}, {{purpose}} and {{details}}
+	// ResendCooldown is the minimum time between sending a code and resending it
+	ResendCooldown time.Duration
+}
+
+var defaultPolicies = map[string]OTPPolicy{
+	PurposeLogin: {
+		Length:      6,
+		Format:      FormatNumeric,
+		Expiry:      5 * time.Minute,
+		MaxAttempts: 3,
+		Template:    "Your OTP verification code is: {{code}}\n\nThis code will expire in {{minutes}} minutes.\n\nDo not share this code with anyone.",
+	},
+	PurposeSignup: {
+		Length:      6,
+		Format:      FormatNumeric,
+		Expiry:      10 * time.Minute,
+		MaxAttempts: 3,
+		Template:    "Welcome! Your sign-up code is: {{code}}\n\nThis code will expire in {{minutes}} minutes.\n\nDo not share this code with anyone.",
+	},
+	PurposePasswordReset: {
+		Length:      8,
+		Format:      FormatAlphanumeric,
+		GroupSize:   4,
+		Expiry:      10 * time.Minute,
+		MaxAttempts: 3,
+		Template:    "Your password reset code is: {{code}}\n\nThis code will expire in {{minutes}} minutes. If you did not request a reset, ignore this message.",
+	},
+	PurposeTransaction: {
+		Length:      8,
+		Format:      FormatNumeric,
+		GroupSize:   4,
+		Expiry:      3 * time.Minute,
+		MaxAttempts: 2,
+		Template:    "Approve {{details}} with code: {{code}}\n\nThis code will expire in {{minutes}} minutes. If you did not make this payment, do not use the code.\n\nNever share this code, not even with our staff.",
+	},
+}
+
+// DefaultPolicy returns the built-in policy for a purpose. It is the
+// default Config.Policy.
+func DefaultPolicy(purpose string) (*OTPPolicy, error) {
+	if purpose == "" {
+		purpose = DefaultPurpose
+	}
+
+	policy, ok := defaultPolicies[purpose]
+	if !ok {
+		return nil, fmt.Errorf("unknown OTP purpose: %s", purpose)
+	}
+	policy.Purpose = purpose
+	policy.ResendCooldown = 30 * time.Second
+
+	return &policy, nil
+}
+
+// Alphabet returns the characters codes are drawn from
+func (p *OTPPolicy) Alphabet() string {
+	return CodeAlphabet(p.Format)
+}
+
+// RenderMessage fills the policy template for a code, grouped for display.
+// details is the human-readable transaction summary, if any.
+func (p *OTPPolicy) RenderMessage(code, details string) string {
+	return strings.NewReplacer(
+		"{{code}}", FormatCode(code, p.GroupSize),
+		"{{details}}", details,
+		"{{minutes}}", strconv.Itoa(int(p.Expiry/time.Minute)),
+		"{{purpose}}", strings.ReplaceAll(p.Purpose, "_", " "),
+	).Replace(p.Template)
+}
@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers a client uses to answer a step-up challenge
+const (
+	// StepUpSendHeader asks for a code to be sent. Requests without it or a
+	// code are rejected without sending anything.
+	StepUpSendHeader  = "X-OTP-Send"
+	StepUpOTPIDHeader = "X-OTP-ID"
+	StepUpCodeHeader  = "X-OTP-Code"
+)
+
+// Identity is where step-up codes for the current user are sent
+type Identity struct {
+	Email string
+	Phone string
+}
+
+// StepUpChallenge is the data of a STEP_UP_REQUIRED response that sent a
+// code
+type StepUpChallenge struct {
+	OTPID          string    `json:"otp_id"`
+	StatusToken    string    `json:"status_token"`
+	Purpose        string    `json:"purpose"`
+	Action         string    `json:"action"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	DeliveryStatus string    `json:"delivery_status"`
+}
+
+// StepUpCheck describes a request to a route protected by step-up
+type StepUpCheck struct {
+	// Purpose selects the policy of the codes; defaults to DefaultPurpose
+	Purpose string
+	// Action is what the code authorizes, e.g. "DELETE /account". Codes
+	// sent for one action are rejected by every other. Defaults to the
+	// request's method and path.
+	Action string
+	// Identity is the signed-in user; empty for unauthenticated requests
+	Identity Identity
+	ClientIP string
+}
+
+// StepUp returns net/http middleware that requires a fresh OTP for action
+// before the next handler runs. identify returns the signed-in user's
+// identity, or false to reject the request as unauthenticated.
+//
+// A request without a code is answered with 401 STEP_UP_REQUIRED. The
+// client asks for a code by repeating the request with X-OTP-Send, then
+// repeats it once more with the OTP ID in X-OTP-ID and the code in
+// X-OTP-Code. Each code authorizes one request.
+func (e *Engine) StepUp(purpose, action string, identify func(r *http.Request) (Identity, bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := identify(r)
+			if !ok {
+				identity = Identity{}
+			}
+			clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				clientIP = r.RemoteAddr
+			}
+			check := StepUpCheck{Purpose: purpose, Action: action, Identity: identity, ClientIP: clientIP}
+			if e.CheckStepUp(w, r, check) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// CheckStepUp verifies the step-up code on the request, or sends a new one
+// if the client asked for it. It writes the response and returns false if
+// the request must stop. Adapters for other routers are built on it; see
+// the ginstepup package.
+func (e *Engine) CheckStepUp(w http.ResponseWriter, r *http.Request, check StepUpCheck) bool {
+	identity := check.Identity
+	if identity.Email == "" && identity.Phone == "" {
+		writeError(w, NewError(http.StatusUnauthorized, ErrorUnauthorized, "Authentication required", nil))
+		return false
+	}
+	purpose := check.Purpose
+	if purpose == "" {
+		purpose = DefaultPurpose
+	}
+	action := check.Action
+	if action == "" {
+		action = r.Method + " " + r.URL.Path
+	}
+
+	// The OTP must belong to the signed-in user and the protected action,
+	// or a code sent for one could authorize another
+	if otpID := r.Header.Get(StepUpOTPIDHeader); otpID != "" {
+		_, err := e.Verify(r.Context(), VerifyRequest{
+			OTPID:   otpID,
+			Code:    r.Header.Get(StepUpCodeHeader),
+			Purpose: purpose,
+			Action:  action,
+			Email:   identity.Email,
+			Phone:   identity.Phone,
+		})
+		if err != nil {
+			writeError(w, AsError(err))
+			return false
+		}
+		return true
+	}
+
+	// Codes cost money and count against the hourly limit, so they are
+	// only sent when the client asks
+	if r.Header.Get(StepUpSendHeader) == "" {
+		writeJSON(w, http.StatusUnauthorized, APIResponse{
+			Success: false,
+			Message: "Step-up verification required",
+			Error: &APIError{
+				Code:    ErrorStepUpRequired,
+				Message: "Repeat the request with " + StepUpSendHeader + " to receive a code",
+				Details: map[string]interface{}{"action": action},
+			},
+		})
+		return false
+	}
+
+	issued, err := e.Generate(r.Context(), GenerateRequest{
+		Email:    identity.Email,
+		Phone:    identity.Phone,
+		Purpose:  purpose,
+		Action:   action,
+		ClientIP: check.ClientIP,
+	})
+	if err != nil {
+		writeError(w, AsError(err))
+		return false
+	}
+
+	w.Header().Set("WWW-Authenticate", `OTP otp_id="`+issued.OTP.ID+`"`)
+	writeJSON(w, http.StatusUnauthorized, APIResponse{
+		Success: false,
+		Message: "Step-up verification required",
+		Data: StepUpChallenge{
+			OTPID:          issued.OTP.ID,
+			StatusToken:    issued.StatusToken,
+			Purpose:        issued.OTP.Purpose,
+			Action:         action,
+			ExpiresAt:      issued.OTP.ExpiresAt,
+			DeliveryStatus: issued.DeliveryStatus,
+		},
+		Error: &APIError{
+			Code:    ErrorStepUpRequired,
+			Message: "Enter the code sent to you to continue",
+		},
+	})
+	return false
+}
+
+// writeError writes an Error in the versioned API's envelope
+func writeError(w http.ResponseWriter, err *Error) {
+	if err.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(err.RetryAfter))
+	}
+	// Internal error details are for the server log only
+	details := err.Details
+	if err.Code == ErrorInternal {
+		details = nil
+	}
+	writeJSON(w, err.Status, APIResponse{
+		Success: false,
+		Message: err.Message,
+		Error: &APIError{
+			Code:    err.Code,
+			Message: err.Message,
+			Details: details,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
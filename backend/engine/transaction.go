@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TransactionContext describes the transaction a code approves. The OTP is
+// bound to its hash so it cannot authorize a different amount or payee.
+type TransactionContext struct {
+	Amount    string `json:"amount" binding:"required,max=32"`
+	Currency  string `json:"currency" binding:"required,len=3"`
+	Payee     string `json:"payee" binding:"required,max=128"`
+	Reference string `json:"reference" binding:"max=128"`
+}
+
+// Validate rejects control characters, which could make two transactions
+// look alike in the message the user reads, and invalid UTF-8
+func (t *TransactionContext) Validate() *Error {
+	var fields []map[string]interface{}
+	for _, f := range []struct{ name, value string }{
+		{"transaction.amount", t.Amount},
+		{"transaction.currency", t.Currency},
+		{"transaction.payee", t.Payee},
+		{"transaction.reference", t.Reference},
+	} {
+		if !utf8.ValidString(f.value) || strings.IndexFunc(f.value, unicode.IsControl) >= 0 {
+			fields = append(fields, map[string]interface{}{"field": f.name, "rule": "printable"})
+		}
+	}
+	if len(fields) > 0 {
+		return NewError(http.StatusBadRequest, ErrorValidationFailed, "Invalid request data", map[string]interface{}{"fields": fields})
+	}
+	return nil
+}
+
+// Canonical returns the canonical form that is hashed: a compact JSON
+// array of amount, upper-case currency, payee and reference, without HTML
+// escaping. Each field is quoted and escaped, so no two transactions share
+// a canonical form.
+func (t *TransactionContext) Canonical() string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode([]string{
+		strings.TrimSpace(t.Amount),
+		strings.ToUpper(strings.TrimSpace(t.Currency)),
+		strings.TrimSpace(t.Payee),
+		strings.TrimSpace(t.Reference),
+	})
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// Hash returns the hex SHA-256 of the canonical form
+func (t *TransactionContext) Hash() string {
+	sum := sha256.Sum256([]byte(t.Canonical()))
+	return hex.EncodeToString(sum[:])
+}
+
+// Summary returns the human-readable details shown in the message
+func (t *TransactionContext) Summary() string {
+	summary := fmt.Sprintf("%s %s to %s",
+		strings.ToUpper(strings.TrimSpace(t.Currency)),
+		strings.TrimSpace(t.Amount),
+		strings.TrimSpace(t.Payee),
+	)
+	if ref := strings.TrimSpace(t.Reference); ref != "" {
+		summary += " (ref " + ref + ")"
+	}
+	return summary
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTransactionCanonicalForm(t *testing.T) {
+	tx := TransactionContext{Amount: " 500.00 ", Currency: "inr", Payee: "Smith & Sons <Ltd>", Reference: `INV "42"`}
+	want := `["500.00","INR","Smith & Sons <Ltd>","INV \"42\""]`
+	if got := tx.Canonical(); got != want {
+		t.Errorf("Canonical() = %s, want %s", got, want)
+	}
+}
+
+// Moving text from one field into its neighbour must change the hash
+func TestTransactionHashSeparatesFields(t *testing.T) {
+	pairs := [][2]TransactionContext{
+		{
+			{Amount: "1", Currency: "USD", Payee: "ACME\nINV-42", Reference: ""},
+			{Amount: "1", Currency: "USD", Payee: "ACME", Reference: "INV-42"},
+		},
+		{
+			{Amount: "1", Currency: "USD", Payee: `ACME","X`, Reference: ""},
+			{Amount: "1", Currency: "USD", Payee: "ACME", Reference: "X"},
+		},
+	}
+	for _, pair := range pairs {
+		if pair[0].Hash() == pair[1].Hash() {
+			t.Errorf("%q and %q have the same hash", pair[0].Canonical(), pair[1].Canonical())
+		}
+	}
+}
+
+func TestTransactionRejectsControlCharacters(t *testing.T) {
+	e, _ := newTestEngine(t)
+	for _, tx := range []TransactionContext{
+		{Amount: "1", Currency: "USD", Payee: "ACME\nLtd"},
+		{Amount: "1", Currency: "USD", Payee: "ACME", Reference: "INV\x00"},
+		{Amount: "1\r", Currency: "USD", Payee: "ACME"},
+		{Amount: "1", Currency: "USD", Payee: "ACME\xff"},
+	} {
+		tx := tx
+		_, err := e.Generate(context.Background(), GenerateRequest{Email: "tx@example.com", Transaction: &tx})
+		if errorCode(err) != ErrorValidationFailed {
+			t.Errorf("%q: got %v, want %s", tx.Canonical(), err, ErrorValidationFailed)
+		}
+	}
+
+	tx := TransactionContext{Amount: "1", Currency: "EUR", Payee: "Café Zoë"}
+	if _, err := e.Generate(context.Background(), GenerateRequest{Email: "tx@example.com", Transaction: &tx}); err != nil {
+		t.Errorf("printable Unicode payee: %v", err)
+	}
+}
+
+// A long summary is cut to the column width between characters, never
+// inside one
+func TestTransactionSummaryTruncatedOnCharacters(t *testing.T) {
+	e, _ := newTestEngine(t)
+	tx := TransactionContext{Amount: "1", Currency: "EUR", Payee: strings.Repeat("Zoë ", 100)}
+	issued, err := e.Generate(context.Background(), GenerateRequest{Email: "tx@example.com", Transaction: &tx})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	summary := issued.OTP.TransactionSummary
+	if !utf8.ValidString(summary) || utf8.RuneCountInString(summary) != 255 {
+		t.Errorf("summary of %d characters, valid UTF-8 %t", utf8.RuneCountInString(summary), utf8.ValidString(summary))
+	}
+}
@@ -0,0 +1,70 @@
+// Package events is an in-process publish/subscribe bus for lifecycle
+// events such as a code being delivered or verified. It only reaches
+// subscribers in the same process; webhooks carry events to other systems.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is something that happened to an OTP or a user
+type Event struct {
+	Type string
+	// OTPID is the OTP the event is about; empty for user events
+	OTPID string
+	// Data is the event's payload, as sent to webhooks
+	Data map[string]interface{}
+	At   time.Time
+}
+
+// Handler receives published events. Handlers run on the publisher's
+// goroutine, so slow work must be handed off.
+type Handler func(Event)
+
+// Bus delivers every published event to all subscribers
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[int]Handler
+	next     int
+}
+
+// NewBus returns a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{handlers: map[int]Handler{}}
+}
+
+// Subscribe calls handler for every event published from now on. The
+// returned function removes the subscription.
+func (b *Bus) Subscribe(handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish calls every subscriber with the event
+func (b *Bus) Publish(event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPublishReachesSubscribers(t *testing.T) {
+	bus := NewBus()
+	var first, second []Event
+	bus.Subscribe(func(e Event) { first = append(first, e) })
+	unsubscribe := bus.Subscribe(func(e Event) { second = append(second, e) })
+
+	bus.Publish(Event{Type: "otp.generated", OTPID: "a"})
+	unsubscribe()
+	bus.Publish(Event{Type: "otp.verified", OTPID: "a"})
+
+	if len(first) != 2 || len(second) != 1 {
+		t.Fatalf("first got %d events, second %d; want 2 and 1", len(first), len(second))
+	}
+	if first[0].At.IsZero() {
+		t.Error("Publish did not set At")
+	}
+}
+
+func TestUnsubscribeDuringPublish(t *testing.T) {
+	bus := NewBus()
+	var unsubscribe func()
+	calls := 0
+	unsubscribe = bus.Subscribe(func(Event) {
+		calls++
+		unsubscribe()
+	})
+
+	bus.Publish(Event{Type: "otp.generated"})
+	bus.Publish(Event{Type: "otp.generated"})
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestConcurrentPublishAndSubscribe(t *testing.T) {
+	bus := NewBus()
+	var mu sync.Mutex
+	received := 0
+	bus.Subscribe(func(Event) {
+		mu.Lock()
+		received++
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			bus.Publish(Event{Type: "otp.generated"})
+		}()
+		go func() {
+			defer wg.Done()
+			bus.Subscribe(func(Event) {})()
+		}()
+	}
+	wg.Wait()
+
+	if received != 20 {
+		t.Errorf("received %d events, want 20", received)
+	}
+}
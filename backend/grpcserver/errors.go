@@ -0,0 +1,98 @@
+package grpcserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is the ErrorInfo domain of every error returned by the service
+const errorDomain = "otp-backend"
+
+// grpcCodes maps API error codes onto gRPC status codes
+var grpcCodes = map[engine.ErrorCode]codes.Code{
+	engine.ErrorValidationFailed:     codes.InvalidArgument,
+	engine.ErrorIdentifierRequired:   codes.InvalidArgument,
+	engine.ErrorPurposeInvalid:       codes.InvalidArgument,
+	engine.ErrorPurposeMismatch:      codes.InvalidArgument,
+	engine.ErrorTransactionRequired:  codes.InvalidArgument,
+	engine.ErrorTransactionMismatch:  codes.InvalidArgument,
+	engine.ErrorMagicLinkUnavailable: codes.InvalidArgument,
+	engine.ErrorReturnURLNotAllowed:  codes.InvalidArgument,
+	engine.ErrorOTPInvalid:           codes.InvalidArgument,
+	engine.ErrorCaptchaRequired:      codes.PermissionDenied,
+	engine.ErrorCaptchaFailed:        codes.PermissionDenied,
+	engine.ErrorRateLimited:          codes.ResourceExhausted,
+	engine.ErrorResendCooldown:       codes.ResourceExhausted,
+	engine.ErrorOTPNotFound:          codes.NotFound,
+	engine.ErrorOTPAlreadyVerified:   codes.FailedPrecondition,
+	engine.ErrorOTPRevoked:           codes.FailedPrecondition,
+	engine.ErrorOTPSuperseded:        codes.FailedPrecondition,
+	engine.ErrorOTPExpired:           codes.FailedPrecondition,
+	engine.ErrorOTPLocked:            codes.FailedPrecondition,
+	engine.ErrorUnauthorized:         codes.Unauthenticated,
+	engine.ErrorStepUpRequired:       codes.Unauthenticated,
+	engine.ErrorAdminDisabled:        codes.PermissionDenied,
+	engine.ErrorInternal:             codes.Internal,
+}
+
+// statusError converts a failed OTP operation into a gRPC status. The API
+// error code travels as the ErrorInfo reason, so clients can handle the
+// same codes on both transports.
+func statusError(err *controllers.OTPError) error {
+	code, ok := grpcCodes[err.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	// Internal error details are for the server log only
+	metadata := map[string]string{}
+	if err.Code == engine.ErrorInternal {
+		fmt.Printf("❌ gRPC %s: %v\n", err.Message, err.Details["error"])
+	} else {
+		for k, v := range err.Details {
+			metadata[k] = metadataValue(v)
+		}
+	}
+
+	st := status.New(code, err.Message)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason:   string(err.Code),
+		Domain:   errorDomain,
+		Metadata: metadata,
+	}}
+	if err.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(time.Duration(err.RetryAfter) * time.Second),
+		})
+	}
+
+	if withDetails, detailErr := st.WithDetails(details...); detailErr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// metadataValue formats an error detail as an ErrorInfo metadata string
+func metadataValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
@@ -0,0 +1,326 @@
+// Package grpcserver serves the OTP API over gRPC. The RPCs are thin
+// adapters over the same operations the HTTP controllers use, so both
+// transports share validation, policies and error codes.
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	otpv1 "github.com/Avinashkr000/otp-verification-system/backend/proto/otp/v1"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Callers are backend services, so the end user's address and browser come
+// from metadata rather than the connection
+const (
+	endUserIPKey        = "x-end-user-ip"
+	endUserAgentKey     = "x-end-user-agent"
+	healthServicePrefix = "/grpc.health.v1.Health/"
+)
+
+// NewServer returns a gRPC server with the OTP service, the standard
+// health service and, if enabled, server reflection. Every RPC except the
+// health check needs one of the configured API keys.
+func NewServer(cfg *utils.GRPCConfig) (*grpc.Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	auth := apiKeyAuth(cfg.APIKeys)
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := auth(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := auth(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+	if cfg.TLS() {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load gRPC TLS certificate: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	server := grpc.NewServer(opts...)
+	otpv1.RegisterOTPServiceServer(server, &otpService{})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(otpv1.OTPService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	if cfg.Reflection {
+		reflection.Register(server)
+	}
+
+	return server, nil
+}
+
+// ListenAndServe starts the gRPC server on the configured port. It blocks
+// until the server stops.
+func ListenAndServe(cfg *utils.GRPCConfig) error {
+	server, err := NewServer(cfg)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🚀 gRPC server starting on :%s (TLS: %t, reflection: %t)\n", cfg.Port, cfg.TLS(), cfg.Reflection)
+	return server.Serve(listener)
+}
+
+// apiKeyAuth checks the bearer key in the "authorization" metadata. The
+// health service stays open for load balancer probes.
+func apiKeyAuth(keys []string) func(ctx context.Context, method string) error {
+	return func(ctx context.Context, method string) error {
+		if strings.HasPrefix(method, healthServicePrefix) {
+			return nil
+		}
+
+		var presented string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				presented = strings.TrimPrefix(values[0], "Bearer ")
+			}
+		}
+		for _, key := range keys {
+			if presented != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(key)) == 1 {
+				return nil
+			}
+		}
+		return statusError(engine.NewError(http.StatusUnauthorized, engine.ErrorUnauthorized, "Missing or invalid API key", nil))
+	}
+}
+
+type otpService struct {
+	otpv1.UnimplementedOTPServiceServer
+}
+
+func (s *otpService) Generate(ctx context.Context, in *otpv1.GenerateRequest) (*otpv1.OTPIssued, error) {
+	req := controllers.GenerateOTPRequest{
+		Email:        in.GetEmail(),
+		Phone:        in.GetPhone(),
+		Purpose:      in.GetPurpose(),
+		Transaction:  transactionContext(in.GetTransaction()),
+		MagicLink:    in.GetMagicLink(),
+		ReturnURL:    in.GetReturnUrl(),
+		Push:         in.GetPush(),
+		CaptchaToken: in.GetCaptchaToken(),
+	}
+	if err := controllers.ValidateRequest(&req); err != nil {
+		return nil, statusError(err)
+	}
+
+	client, clientErr := clientInfo(ctx)
+	if clientErr != nil {
+		return nil, statusError(clientErr)
+	}
+
+	data, err := controllers.IssueOTP(ctx, client, req)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return otpIssued(data), nil
+}
+
+func (s *otpService) Verify(ctx context.Context, in *otpv1.VerifyRequest) (*otpv1.VerifyResponse, error) {
+	req := controllers.VerifyOTPRequest{
+		OTPID:           in.GetOtpId(),
+		OTPCode:         in.GetOtpCode(),
+		Purpose:         in.GetPurpose(),
+		TransactionHash: in.GetTransactionHash(),
+		Transaction:     transactionContext(in.GetTransaction()),
+		RememberDeviceOptions: controllers.RememberDeviceOptions{
+			RememberDevice: in.GetRememberDevice(),
+			DeviceName:     in.GetDeviceName(),
+		},
+	}
+	if err := controllers.ValidateRequest(&req); err != nil {
+		return nil, statusError(err)
+	}
+
+	client, clientErr := clientInfo(ctx)
+	if clientErr != nil {
+		return nil, statusError(clientErr)
+	}
+
+	data, err := controllers.CheckOTP(ctx, client, req)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	out := &otpv1.VerifyResponse{
+		Verified:  data.Verified,
+		Purpose:   data.Purpose,
+		UserId:    data.UserID,
+		Email:     data.Email,
+		Phone:     data.Phone,
+		Timestamp: timestamppb.New(data.Timestamp),
+	}
+	if data.VerificationToken != nil {
+		out.VerificationToken = &otpv1.VerificationToken{
+			Token:     data.Token,
+			TokenType: data.TokenType,
+			ExpiresAt: timestamppb.New(data.TokenExpiresAt),
+		}
+	}
+	if data.Session != nil {
+		out.Session = &otpv1.SessionTokens{
+			SessionId:        data.Session.SessionID,
+			AccessToken:      data.Session.AccessToken,
+			AccessExpiresAt:  timestamppb.New(data.Session.AccessExpiresAt),
+			RefreshToken:     data.Session.RefreshToken,
+			RefreshExpiresAt: timestamppb.New(data.Session.RefreshExpiresAt),
+			TokenType:        data.Session.TokenType,
+		}
+	}
+	if data.Device != nil {
+		out.Device = &otpv1.TrustedDevice{
+			DeviceId:    data.Device.DeviceID,
+			DeviceToken: data.Device.DeviceToken,
+			ExpiresAt:   timestamppb.New(data.Device.ExpiresAt),
+		}
+	}
+	return out, nil
+}
+
+func (s *otpService) Resend(ctx context.Context, in *otpv1.ResendRequest) (*otpv1.OTPIssued, error) {
+	req := controllers.ResendOTPRequest{
+		OTPID:        in.GetOtpId(),
+		CaptchaToken: in.GetCaptchaToken(),
+	}
+	if err := controllers.ValidateRequest(&req); err != nil {
+		return nil, statusError(err)
+	}
+
+	client, clientErr := clientInfo(ctx)
+	if clientErr != nil {
+		return nil, statusError(clientErr)
+	}
+
+	data, err := controllers.ReissueOTP(ctx, client, req)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return otpIssued(data), nil
+}
+
+func (s *otpService) GetStatus(ctx context.Context, in *otpv1.GetStatusRequest) (*otpv1.OTPStatus, error) {
+	data, err := controllers.LookupOTPStatus(ctx, in.GetOtpId(), in.GetStatusToken())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &otpv1.OTPStatus{
+		OtpId:             data.OTPID,
+		Purpose:           data.Purpose,
+		State:             data.State,
+		AttemptsRemaining: int32(data.AttemptsRemaining),
+		MaxAttempts:       int32(data.MaxAttempts),
+		CreatedAt:         timestamppb.New(data.CreatedAt),
+		ExpiresAt:         timestamppb.New(data.ExpiresAt),
+		VerifiedAt:        optionalTimestamp(data.VerifiedAt),
+		ResendAvailableAt: optionalTimestamp(data.ResendAvailableAt),
+		RevokedAt:         optionalTimestamp(data.RevokedAt),
+		SupersededBy:      data.SupersededBy,
+		DeliveryChannel:   data.Delivery.Channel,
+		DeliveryStatus:    data.Delivery.Status,
+	}, nil
+}
+
+func (s *otpService) Cancel(ctx context.Context, in *otpv1.CancelRequest) (*otpv1.CancelResponse, error) {
+	data, err := controllers.CancelOwnedOTP(ctx, in.GetOtpId(), in.GetStatusToken())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &otpv1.CancelResponse{
+		OtpId:     data.OTPID,
+		State:     data.State,
+		RevokedAt: optionalTimestamp(data.RevokedAt),
+	}, nil
+}
+
+// clientInfo describes the end user the calling service acts for. The
+// connection belongs to that service, so its address would put every user
+// behind one rate limit; the caller has to pass the user's address instead.
+func clientInfo(ctx context.Context) (controllers.ClientInfo, *controllers.OTPError) {
+	var client controllers.ClientInfo
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(endUserIPKey); len(values) > 0 && net.ParseIP(values[0]) != nil {
+		client.IP = values[0]
+	} else {
+		return client, engine.NewError(http.StatusBadRequest, engine.ErrorValidationFailed,
+			"The "+endUserIPKey+" metadata must be the end user's IP address", nil)
+	}
+	if ua := md.Get(endUserAgentKey); len(ua) > 0 {
+		client.UserAgent = ua[0]
+	}
+
+	return client, nil
+}
+
+func transactionContext(t *otpv1.Transaction) *engine.TransactionContext {
+	if t == nil {
+		return nil
+	}
+	return &engine.TransactionContext{
+		Amount:    t.GetAmount(),
+		Currency:  t.GetCurrency(),
+		Payee:     t.GetPayee(),
+		Reference: t.GetReference(),
+	}
+}
+
+func otpIssued(data *controllers.OTPIssuedResponse) *otpv1.OTPIssued {
+	return &otpv1.OTPIssued{
+		OtpId:       data.OTPID,
+		StatusToken: data.StatusToken,
+		Purpose:     data.Purpose,
+		CodeFormat: &otpv1.CodeFormat{
+			Format:    data.CodeFormat.Format,
+			Length:    int32(data.CodeFormat.Length),
+			GroupSize: int32(data.CodeFormat.GroupSize),
+		},
+		ExpiresAt:       timestamppb.New(data.ExpiresAt),
+		SmsStatus:       data.SMSStatus,
+		TransactionHash: data.TransactionHash,
+		MagicLinkSent:   data.MagicLinkSent,
+		PushStatus:      data.PushStatus,
+		OtpCode:         data.OTPCode,
+		MagicLink:       data.MagicLink,
+	}
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
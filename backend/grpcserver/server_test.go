@@ -0,0 +1,136 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	otpv1 "github.com/Avinashkr000/otp-verification-system/backend/proto/otp/v1"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/glebarez/sqlite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dial serves cfg on an in-memory listener backed by an in-memory database
+func dial(t *testing.T, cfg *utils.GRPCConfig) *grpc.ClientConn {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := config.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	config.DB = db
+
+	server, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func testConfig() *utils.GRPCConfig {
+	return &utils.GRPCConfig{Port: "9090", APIKeys: []string{"service-key"}, AllowPlaintext: true}
+}
+
+func withKey(key string, pairs ...string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), append([]string{"authorization", "Bearer " + key}, pairs...)...)
+}
+
+func TestValidateRequiresAuthAndTLS(t *testing.T) {
+	for name, cfg := range map[string]*utils.GRPCConfig{
+		"no API keys": {Port: "9090", AllowPlaintext: true},
+		"no TLS":      {Port: "9090", APIKeys: []string{"service-key"}},
+		"half TLS":    {Port: "9090", APIKeys: []string{"service-key"}, TLSCertFile: "cert.pem"},
+	} {
+		if _, err := NewServer(cfg); err == nil {
+			t.Errorf("%s: server was created", name)
+		}
+	}
+
+	t.Setenv("GRPC_PORT", "")
+	t.Setenv("GRPC_REFLECTION", "")
+	if cfg := utils.GetGRPCConfig(); cfg.Port != "" || cfg.Reflection {
+		t.Errorf("gRPC on by default: %+v", cfg)
+	}
+}
+
+func TestRPCsRequireAPIKey(t *testing.T) {
+	client := otpv1.NewOTPServiceClient(dial(t, testConfig()))
+	req := &otpv1.GenerateRequest{Phone: "+15550000001"}
+
+	for name, ctx := range map[string]context.Context{
+		"no key":    context.Background(),
+		"wrong key": withKey("other-key", endUserIPKey, "203.0.113.9"),
+	} {
+		if _, err := client.Generate(ctx, req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s: got %v, want %s", name, err, codes.Unauthenticated)
+		}
+	}
+}
+
+func TestHealthCheckIsOpen(t *testing.T) {
+	health := healthpb.NewHealthClient(dial(t, testConfig()))
+	if _, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+}
+
+func TestClientIPComesFromMetadata(t *testing.T) {
+	client := otpv1.NewOTPServiceClient(dial(t, testConfig()))
+	req := &otpv1.GenerateRequest{Phone: "+15550000001"}
+
+	if _, err := client.Generate(withKey("service-key"), req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("no end-user IP: got %v, want %s", err, codes.InvalidArgument)
+	}
+
+	if _, err := client.Generate(withKey("service-key", endUserIPKey, "203.0.113.9"), req); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	var otp models.OTP
+	if err := config.DB.First(&otp).Error; err != nil {
+		t.Fatalf("load OTP: %v", err)
+	}
+	if otp.ClientIP != "203.0.113.9" {
+		t.Errorf("client IP %q, want the end user's", otp.ClientIP)
+	}
+}
+
+func TestReflectionIsOptIn(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		cfg := testConfig()
+		cfg.Reflection = enabled
+		server, err := NewServer(cfg)
+		if err != nil {
+			t.Fatalf("NewServer: %v", err)
+		}
+		if _, ok := server.GetServiceInfo()["grpc.reflection.v1.ServerReflection"]; ok != enabled {
+			t.Errorf("Reflection %t: reflection registered = %t", enabled, ok)
+		}
+	}
+}
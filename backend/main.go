@@ -3,10 +3,14 @@ package main
 import (
 	"fmt"
 	"log"
-	"otp-backend/config"
-	"otp-backend/routes"
 	"os"
 
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/grpcserver"
+	"github.com/Avinashkr000/otp-verification-system/backend/middleware"
+	"github.com/Avinashkr000/otp-verification-system/backend/routes"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -34,15 +38,46 @@ func main() {
 	// Initialize database connection
 	config.ConnectDatabase()
 
-	// Create Gin router
-	router := gin.Default()
+	// Load the key used to sign verification tokens
+	if err := utils.InitTokenSigner(); err != nil {
+		log.Fatal("Failed to configure token signing:", err)
+	}
+	if os.Getenv("JWT_PRIVATE_KEY_FILE") == "" {
+		fmt.Println("⚠️  JWT_PRIVATE_KEY_FILE not set - using an ephemeral signing key")
+	}
+
+	if os.Getenv("IDEMPOTENCY_SECRET") == "" {
+		fmt.Println("⚠️  IDEMPOTENCY_SECRET not set - stored responses cannot be replayed after a restart")
+	}
+	middleware.StartIdempotencyPurge()
+
+	// Build the API reference once, so a broken document stops startup
+	if err := controllers.InitAPIDocs(); err != nil {
+		log.Fatal("Failed to build API docs:", err)
+	}
+
+	// Configure bot protection for OTP generation
+	controllers.InitBotProtection()
+
+	// Load the step-up rules and GeoIP database
+	controllers.InitRiskEngine()
+
+	// Send lifecycle events to webhook subscriptions, including codes that
+	// expire unused
+	controllers.InitWebhooks()
+	controllers.StartExpiryWatcher()
+
+	// Create Gin router. The access log redacts tokens sent in the query
+	// string, such as the status token of an event stream.
+	router := gin.New()
+	router.Use(middleware.RequestLogger(), gin.Recovery())
 
 	// Configure CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-OTP-Status-Token", "Idempotency-Key"},
+		ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed"},
 		AllowCredentials: true,
 	}))
 
@@ -56,10 +91,32 @@ func main() {
 
 	// Register routes
 	routes.RegisterOTPRoutes(router)
+	routes.RegisterAuthRoutes(router)
+	routes.RegisterOIDCRoutes(router)
+	routes.RegisterRiskRoutes(router)
+	routes.RegisterPushRoutes(router)
+	routes.RegisterWebhookRoutes(router)
+
+	// Serve the OTP API over gRPC on its own port. Both servers report why
+	// they stopped here, so a failed listener stops the process.
+	serveErrors := make(chan error, 2)
+	grpcConfig := utils.GetGRPCConfig()
+	if err := grpcConfig.Validate(); err != nil {
+		log.Fatal("Invalid gRPC configuration:", err)
+	}
+	if grpcConfig.Port != "" {
+		go func() {
+			serveErrors <- fmt.Errorf("gRPC server: %w", grpcserver.ListenAndServe(grpcConfig))
+		}()
+	} else {
+		fmt.Println("⚠️  GRPC_PORT not set - gRPC server disabled")
+	}
 
 	// Start server
 	log.Println("\n🚀 Server starting on http://localhost:8080")
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal("Failed to start server:", err)
-	}
+	go func() {
+		serveErrors <- fmt.Errorf("HTTP server: %w", router.Run(":8080"))
+	}()
+
+	log.Fatal(<-serveErrors)
 }
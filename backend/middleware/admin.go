@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdminKey protects administrative endpoints with the ADMIN_API_KEY
+// sent in the X-Admin-Key header. Without a configured key they are disabled.
+func RequireAdminKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := os.Getenv("ADMIN_API_KEY")
+		if key == "" {
+			AbortWithError(c, http.StatusForbidden, engine.ErrorAdminDisabled, "Admin API disabled. Set ADMIN_API_KEY to enable it", nil)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Key")), []byte(key)) != 1 {
+			AbortWithError(c, http.StatusUnauthorized, engine.ErrorUnauthorized, "Invalid admin key", nil)
+			return
+		}
+
+		c.Next()
+	}
+}
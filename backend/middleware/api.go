@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/gin-gonic/gin"
+)
+
+const apiVersionKey = "api_version"
+
+// ProblemContentType is the RFC 7807 media type. Versioned endpoints use it
+// for errors when the client lists it in the Accept header.
+const ProblemContentType = "application/problem+json"
+
+// APIVersion marks requests routed through a versioned group so responses
+// use the typed envelope and error codes instead of the legacy shape
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Header("API-Version", version)
+		c.Next()
+	}
+}
+
+// IsVersioned reports whether the request came in through a versioned group
+func IsVersioned(c *gin.Context) bool {
+	return c.GetString(apiVersionKey) != ""
+}
+
+// Respond writes a successful response in the envelope shared by the
+// legacy and versioned APIs
+func Respond(c *gin.Context, status int, message string, data interface{}) {
+	c.JSON(status, engine.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// RespondError writes an error response. Legacy routes get the original
+// {"success", "message"} body with details merged in at the top level;
+// versioned routes get an APIError, or an RFC 7807 problem if asked for.
+func RespondError(c *gin.Context, status int, code engine.ErrorCode, message string, details gin.H) {
+	if !IsVersioned(c) {
+		body := gin.H{
+			"success": false,
+			"message": message,
+		}
+		for k, v := range details {
+			body[k] = v
+		}
+		c.JSON(status, body)
+		return
+	}
+
+	if strings.Contains(c.GetHeader("Accept"), ProblemContentType) {
+		problem := gin.H{}
+		for k, v := range details {
+			problem[k] = v
+		}
+		problem["type"] = "/api/" + c.GetString(apiVersionKey) + "/errors#" + string(code)
+		problem["title"] = engine.ErrorTitle(code)
+		problem["status"] = status
+		problem["detail"] = message
+		problem["instance"] = c.Request.URL.Path
+		problem["code"] = code
+
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(status, problem)
+		return
+	}
+
+	c.JSON(status, engine.APIResponse{
+		Success: false,
+		Message: message,
+		Error: &engine.APIError{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	})
+}
+
+// AbortWithError writes an error response and stops the handler chain
+func AbortWithError(c *gin.Context, status int, code engine.ErrorCode, message string, details gin.H) {
+	RespondError(c, status, code, message, details)
+	c.Abort()
+}
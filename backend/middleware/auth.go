@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAccessToken rejects requests without a valid session access token.
+// On success the user and session IDs are stored as "user_id" and "session_id".
+func RequireAccessToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Missing access token",
+			})
+			return
+		}
+
+		signer := utils.GetTokenSigner()
+		if signer == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"success": false,
+				"message": "Token signing not configured",
+			})
+			return
+		}
+
+		claims, err := signer.ParseAccessToken(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Invalid access token",
+			})
+			return
+		}
+
+		// Revoked sessions lose access immediately, not when the token expires
+		var session models.Session
+		if err := config.DB.Where("id = ? AND user_id = ?", claims.SessionID, claims.Subject).First(&session).Error; err != nil || !session.IsActive() {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Session has been revoked or expired",
+			})
+			return
+		}
+
+		c.Set("user_id", claims.Subject)
+		c.Set("session_id", claims.SessionID)
+		c.Next()
+	}
+}
@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/securetoken"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader names the client-chosen key that makes retries safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Idempotency replays the stored response when a request is retried with
+// the same Idempotency-Key, so a retry on a flaky network doesn't send
+// another code or count against rate limits. Keys are scoped to the route
+// and the client: its Authorization header if it sends one, otherwise the
+// request body, so an anonymous retry is replayed even from a new address.
+// Only successful responses are stored; after an error the client may
+// retry with the same key. Requests without the header are not affected.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		cfg := utils.GetIdempotencyConfig()
+		if len(key) > cfg.MaxKeyLength {
+			AbortWithError(c, http.StatusBadRequest, engine.ErrorIdempotencyKeyInvalid,
+				fmt.Sprintf("Idempotency-Key must be at most %d characters", cfg.MaxKeyLength), nil)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				AbortWithError(c, http.StatusRequestEntityTooLarge, engine.ErrorValidationFailed,
+					fmt.Sprintf("Request body must be at most %d bytes", cfg.MaxBodyBytes), nil)
+				return
+			}
+			AbortWithError(c, http.StatusBadRequest, engine.ErrorValidationFailed, "Invalid request data", gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(sum[:])
+
+		now := time.Now()
+		record := models.IdempotencyRecord{
+			ID:          utils.IdempotencyRecordID(c.Request.Method, c.FullPath(), idempotencyClient(c, requestHash), key),
+			RequestHash: requestHash,
+			ExpiresAt:   now.Add(cfg.TTL),
+		}
+
+		// The primary key makes claiming the key atomic
+		if config.DB.Create(&record).Error != nil {
+			var existing models.IdempotencyRecord
+			if err := config.DB.Where("id = ?", record.ID).First(&existing).Error; err != nil {
+				AbortWithError(c, http.StatusInternalServerError, engine.ErrorInternal, "Failed to check Idempotency-Key", gin.H{"error": err.Error()})
+				return
+			}
+
+			abandoned := existing.CompletedAt == nil && now.Sub(existing.CreatedAt) > cfg.InFlightTimeout
+			// A response sealed under another secret, e.g. a random one
+			// from before a restart, is as good as missing
+			var replay []byte
+			unreadable := false
+			if existing.CompletedAt != nil && existing.RequestHash == record.RequestHash {
+				replay, err = utils.OpenIdempotentResponse(key, existing.ID, existing.Body)
+				if err != nil {
+					fmt.Printf("⚠️  Stored response for %s %s cannot be opened, running the request again: %v\n", c.Request.Method, c.FullPath(), err)
+					unreadable = true
+				}
+			}
+
+			switch {
+			case now.After(existing.ExpiresAt) || abandoned || unreadable:
+				// Take over the key; if another retry got there first it is in progress
+				config.DB.Where("id = ? AND created_at = ?", existing.ID, existing.CreatedAt).Delete(&models.IdempotencyRecord{})
+				if config.DB.Create(&record).Error != nil {
+					AbortWithError(c, http.StatusConflict, engine.ErrorIdempotencyInProgress, "A request with this Idempotency-Key is still being processed", nil)
+					return
+				}
+			case existing.RequestHash != record.RequestHash:
+				AbortWithError(c, http.StatusUnprocessableEntity, engine.ErrorIdempotencyKeyReused, "Idempotency-Key was already used with a different request body", nil)
+				return
+			case existing.CompletedAt == nil:
+				AbortWithError(c, http.StatusConflict, engine.ErrorIdempotencyInProgress, "A request with this Idempotency-Key is still being processed", nil)
+				return
+			default:
+				replayIdempotentResponse(c, &existing, replay)
+				return
+			}
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status < 200 || status >= 300 {
+			config.DB.Delete(&models.IdempotencyRecord{}, "id = ?", record.ID)
+			return
+		}
+
+		sealed, err := utils.SealIdempotentResponse(key, record.ID, recorder.body.Bytes())
+		if err != nil {
+			fmt.Printf("⚠️  Failed to store idempotent response: %v\n", err)
+			config.DB.Delete(&models.IdempotencyRecord{}, "id = ?", record.ID)
+			return
+		}
+		config.DB.Model(&record).Updates(map[string]interface{}{
+			"status_code":  status,
+			"content_type": recorder.Header().Get("Content-Type"),
+			"body":         sealed,
+			"completed_at": time.Now(),
+		})
+	}
+}
+
+func replayIdempotentResponse(c *gin.Context, record *models.IdempotencyRecord, body []byte) {
+	fmt.Printf("🔁 Replaying response for %s %s (Idempotency-Key)\n", c.Request.Method, c.FullPath())
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.StatusCode, record.ContentType, body)
+	c.Abort()
+}
+
+// idempotencyClient identifies the caller an Idempotency-Key belongs to.
+// Anonymous callers have nothing to identify them by but the request
+// itself: their IP address may change between retries.
+func idempotencyClient(c *gin.Context, requestHash string) string {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		return "auth:" + securetoken.Hash(auth)
+	}
+	return "request:" + requestHash
+}
+
+// PurgeIdempotencyRecords deletes stored responses whose keys have expired
+func PurgeIdempotencyRecords() (int64, error) {
+	result := config.DB.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// StartIdempotencyPurge purges expired idempotency records once an hour
+func StartIdempotencyPurge() {
+	go func() {
+		for range time.Tick(time.Hour) {
+			if _, err := PurgeIdempotencyRecords(); err != nil {
+				fmt.Printf("⚠️  Failed to purge idempotency records: %v\n", err)
+			}
+		}
+	}()
+}
+
+// responseRecorder keeps a copy of the response body
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// idempotentRouter serves POST /generate behind Idempotency and counts the
+// requests that reach the handler
+func idempotentRouter(t *testing.T) (*gin.Engine, *int) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.IdempotencyRecord{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	config.DB = db
+
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	r := gin.New()
+	r.POST("/generate", Idempotency(), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"status_token": "secret-status-token", "call": calls})
+	})
+	return r, &calls
+}
+
+func postIdempotent(r *gin.Engine, key, remoteAddr, auth string) *httptest.ResponseRecorder {
+	return postIdempotentBody(r, key, remoteAddr, auth, `{"phone":"+15550000001"}`)
+}
+
+func postIdempotentBody(r *gin.Engine, key, remoteAddr, auth, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysForSameClient(t *testing.T) {
+	r, calls := idempotentRouter(t)
+
+	first := postIdempotent(r, "key-1", "203.0.113.1:1000", "")
+	retry := postIdempotent(r, "key-1", "203.0.113.1:2000", "")
+
+	if *calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", *calls)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry was not replayed: %s", retry.Body.String())
+	}
+}
+
+func TestIdempotencyKeysAreScopedToClient(t *testing.T) {
+	r, calls := idempotentRouter(t)
+
+	// Anonymous clients are scoped by the request, not their address
+	postIdempotent(r, "shared-key", "203.0.113.1:1000", "")
+	moved := postIdempotent(r, "shared-key", "198.51.100.7:1000", "")
+	if *calls != 1 || moved.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry from a new address was not replayed: %s", moved.Body.String())
+	}
+	other := postIdempotentBody(r, "shared-key", "203.0.113.1:1000", "", `{"phone":"+15550000002"}`)
+	if *calls != 2 || other.Code != http.StatusOK || other.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("another request got the first client's response: %d %s", other.Code, other.Body.String())
+	}
+
+	// Authenticated clients are scoped by credentials, not address
+	postIdempotent(r, "shared-key", "203.0.113.1:1000", "Bearer alice")
+	bob := postIdempotent(r, "shared-key", "203.0.113.1:1000", "Bearer bob")
+	if *calls != 4 || bob.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("another user got the first user's response: %s", bob.Body.String())
+	}
+	alice := postIdempotent(r, "shared-key", "198.51.100.7:1000", "Bearer alice")
+	if *calls != 4 || alice.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry from the same user was not replayed: %s", alice.Body.String())
+	}
+	if reused := postIdempotentBody(r, "shared-key", "203.0.113.1:1000", "Bearer alice", `{"phone":"+15550000002"}`); reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("key reused with another body got %d, want 422", reused.Code)
+	}
+}
+
+// A response sealed under a previous secret cannot be replayed, so the
+// retry runs the request again instead of failing
+func TestIdempotencyRerunsUnreadableResponse(t *testing.T) {
+	r, calls := idempotentRouter(t)
+	postIdempotent(r, "key-1", "203.0.113.1:1000", "")
+	config.DB.Model(&models.IdempotencyRecord{}).Where("1 = 1").Update("body", []byte("sealed under another secret"))
+
+	retry := postIdempotent(r, "key-1", "203.0.113.1:1000", "")
+	if retry.Code != http.StatusOK || *calls != 2 {
+		t.Fatalf("retry got %d after %d calls: %s", retry.Code, *calls, retry.Body.String())
+	}
+	// The new response replaces the unreadable one
+	again := postIdempotent(r, "key-1", "203.0.113.1:1000", "")
+	if *calls != 2 || again.Body.String() != retry.Body.String() {
+		t.Errorf("second retry was not replayed: %s", again.Body.String())
+	}
+}
+
+func TestIdempotencyLimitsBodySize(t *testing.T) {
+	r, calls := idempotentRouter(t)
+	w := postIdempotentBody(r, "key-1", "203.0.113.1:1000", "", `{"phone":"`+strings.Repeat("1", 128<<10)+`"}`)
+	if w.Code != http.StatusRequestEntityTooLarge || *calls != 0 {
+		t.Errorf("got %d after %d calls, want 413", w.Code, *calls)
+	}
+}
+
+func TestIdempotencyStoresSealedResponse(t *testing.T) {
+	r, _ := idempotentRouter(t)
+	postIdempotent(r, "key-1", "203.0.113.1:1000", "")
+
+	var record models.IdempotencyRecord
+	if err := config.DB.First(&record).Error; err != nil {
+		t.Fatalf("load record: %v", err)
+	}
+	if bytes.Contains(record.Body, []byte("secret-status-token")) {
+		t.Error("stored response is not encrypted")
+	}
+}
+
+func TestPurgeIdempotencyRecords(t *testing.T) {
+	r, _ := idempotentRouter(t)
+	postIdempotent(r, "live", "203.0.113.1:1000", "")
+	postIdempotent(r, "expired", "203.0.113.1:1000", "")
+	var expired models.IdempotencyRecord
+	config.DB.First(&expired)
+	config.DB.Model(&expired).Update("expires_at", time.Now().Add(-time.Minute))
+
+	purged, err := PurgeIdempotencyRecords()
+	if err != nil {
+		t.Fatalf("PurgeIdempotencyRecords: %v", err)
+	}
+	var remaining int64
+	config.DB.Model(&models.IdempotencyRecord{}).Count(&remaining)
+	if purged != 1 || remaining != 1 {
+		t.Errorf("purged %d, %d remaining; want 1 and 1", purged, remaining)
+	}
+}
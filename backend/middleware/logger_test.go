@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/api/v1/otp/abc/events", "/api/v1/otp/abc/events"},
+		{"/api/v1/otp/abc/events?status_token=s3cret", "/api/v1/otp/abc/events?status_token=REDACTED"},
+		{"/api/otp/magic?token=s3cret&x=1", "/api/otp/magic?token=REDACTED&x=1"},
+		{"/webhooks/deliveries?limit=10", "/webhooks/deliveries?limit=10"},
+		{"/callback?code=s3cret;bad", "/callback?REDACTED"},
+	}
+	for _, tt := range tests {
+		if got := RedactQuery(tt.path); got != tt.want {
+			t.Errorf("RedactQuery(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRequestLoggerRedactsStatusToken(t *testing.T) {
+	var log bytes.Buffer
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = &log
+	t.Cleanup(func() { gin.DefaultWriter = defaultWriter })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestLogger())
+	r.GET("/api/v1/otp/:id/events", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/otp/abc/events?status_token=s3cret", nil))
+	if strings.Contains(log.String(), "s3cret") || !strings.Contains(log.String(), "status_token=REDACTED") {
+		t.Errorf("access log: %s", log.String())
+	}
+}
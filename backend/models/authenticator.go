@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Authenticator is an authenticator-app second factor (TOTP or HOTP)
+// enrolled by a user. It is unusable until ConfirmedAt is set.
+type Authenticator struct {
+	ID             string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID         string     `gorm:"type:varchar(36);not null;uniqueIndex" json:"user_id"`
+	Type           string     `gorm:"type:varchar(8);not null" json:"type"` // totp or hotp
+	Secret         string     `gorm:"type:varchar(64);not null" json:"-"`
+	Digits         int        `gorm:"default:6" json:"digits"`
+	Period         int        `gorm:"default:30" json:"period"`
+	Counter        uint64     `gorm:"default:0" json:"-"`
+	LastUsedStep   int64      `gorm:"default:0" json:"-"`
+	FailedAttempts int        `gorm:"default:0" json:"-"`
+	LockedUntil    *time.Time `json:"-"`
+	ConfirmedAt    *time.Time `json:"confirmed_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
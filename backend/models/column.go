@@ -0,0 +1,19 @@
+package models
+
+import "unicode/utf8"
+
+// Truncate shortens s to at most n characters, the width of a varchar(n)
+// column, without splitting a multi-byte character
+func Truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos]
+		}
+		i++
+	}
+	return s
+}
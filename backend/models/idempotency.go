@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyRecord stores the response to a request sent with an
+// Idempotency-Key header so retries get the same answer instead of
+// repeating the side effects
+type IdempotencyRecord struct {
+	ID          string     `gorm:"primaryKey;type:char(64)"` // sha256 of method, route, client and key
+	RequestHash string     `gorm:"type:char(64);not null"`   // sha256 of the request body
+	StatusCode  int        `gorm:"default:0"`
+	ContentType string     `gorm:"type:varchar(128)"`
+	Body        []byte     `gorm:"type:blob"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	ExpiresAt   time.Time  `gorm:"not null;index"`
+	CompletedAt *time.Time // nil while the first request is still running
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OAuthClient is an application allowed to use this service as its
+// OpenID Connect provider
+type OAuthClient struct {
+	ID           string    `gorm:"primaryKey;type:varchar(36)" json:"client_id"`
+	Name         string    `gorm:"type:varchar(128);not null" json:"name"`
+	SecretHash   string    `gorm:"type:char(64)" json:"-"`
+	RedirectURIs string    `gorm:"type:text;not null" json:"-"` // newline separated
+	Public       bool      `gorm:"default:false" json:"public"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// RedirectURIList returns the registered redirect URIs
+func (c *OAuthClient) RedirectURIList() []string {
+	return strings.Split(c.RedirectURIs, "\n")
+}
+
+// AllowsRedirectURI reports whether uri exactly matches a registered URI
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	for _, allowed := range c.RedirectURIList() {
+		if allowed == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationRequest holds a validated /authorize request while the user
+// proves ownership of their email or phone on the OTP page
+type AuthorizationRequest struct {
+	ID            string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ClientID      string    `gorm:"type:varchar(36);not null" json:"client_id"`
+	RedirectURI   string    `gorm:"type:varchar(512);not null" json:"redirect_uri"`
+	Scope         string    `gorm:"type:varchar(255)" json:"scope"`
+	State         string    `gorm:"type:varchar(512)" json:"state"`
+	Nonce         string    `gorm:"type:varchar(255)" json:"nonce"`
+	CodeChallenge string    `gorm:"type:varchar(128);not null" json:"-"`
+	OTPID         string    `gorm:"type:varchar(36)" json:"otp_id"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt     time.Time `gorm:"not null" json:"expires_at"`
+}
+
+// AuthorizationCode is a single-use code exchanged at the token endpoint.
+// Only its hash is stored.
+type AuthorizationCode struct {
+	ID            string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	CodeHash      string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	ClientID      string     `gorm:"type:varchar(36);not null" json:"client_id"`
+	UserID        string     `gorm:"type:varchar(36);not null" json:"user_id"`
+	RedirectURI   string     `gorm:"type:varchar(512);not null" json:"redirect_uri"`
+	Scope         string     `gorm:"type:varchar(255)" json:"scope"`
+	Nonce         string     `gorm:"type:varchar(255)" json:"nonce"`
+	CodeChallenge string     `gorm:"type:varchar(128);not null" json:"-"`
+	AuthTime      time.Time  `json:"auth_time"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt        *time.Time `json:"used_at"`
+	// SessionID is the session issued when the code was redeemed; a code
+	// presented again is revoked along with that session
+	SessionID string     `gorm:"type:varchar(36)" json:"-"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
@@ -8,12 +8,67 @@ type OTP struct {
 	ID           string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
 	Email        string     `gorm:"type:varchar(255)" json:"email"`
 	Phone        string     `gorm:"type:varchar(20)" json:"phone"`
-	OTPCode      string     `gorm:"type:varchar(6);not null" json:"otp_code"`
+	OTPCode      string     `gorm:"type:varchar(16);not null" json:"otp_code"`
+	Purpose      string     `gorm:"type:varchar(32);default:login;index" json:"purpose"`
 	IsVerified   bool       `gorm:"default:false" json:"is_verified"`
 	AttemptCount int        `gorm:"default:0" json:"attempt_count"`
+	MaxAttempts  int        `gorm:"default:3" json:"max_attempts"`
+	ClientIP     string     `gorm:"type:varchar(45);index" json:"-"`
 	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
 	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
 	VerifiedAt   *time.Time `json:"verified_at"`
+
+	// Action a step-up code authorizes, e.g. "DELETE /account"; empty for
+	// codes that are not step-up codes
+	Action string `gorm:"type:varchar(128)" json:"-"`
+
+	// Transaction binding for purpose "transaction" (dynamic linking)
+	TransactionHash    string `gorm:"type:varchar(64)" json:"transaction_hash,omitempty"`
+	TransactionSummary string `gorm:"type:varchar(255)" json:"transaction_summary,omitempty"`
+
+	// Magic link sent alongside the code; the ID is cleared once consumed
+	MagicLinkID string `gorm:"type:varchar(36);index" json:"-"`
+	ReturnURL   string `gorm:"type:varchar(512)" json:"-"`
+
+	// Status lookups: only the client holding the status token may read them
+	StatusTokenHash string `gorm:"type:char(64)" json:"-"`
+	DeliveryStatus  string `gorm:"type:varchar(32)" json:"delivery_status"`
+	SupersededBy    string `gorm:"type:varchar(36)" json:"-"` // set when a resend replaces this OTP
+
+	// Revocation by the client (cancel) or an administrator
+	RevokedAt    *time.Time `json:"revoked_at"`
+	RevokeReason string     `gorm:"type:varchar(64)" json:"-"`
+
+	// Set once otp.expired has been reported for an unused code
+	ExpiryReported bool `gorm:"default:false;index" json:"-"`
+}
+
+// OTP states reported by the status endpoint
+const (
+	OTPStatePending    = "pending"
+	OTPStateVerified   = "verified"
+	OTPStateExpired    = "expired"
+	OTPStateLocked     = "locked"
+	OTPStateSuperseded = "superseded"
+	OTPStateRevoked    = "revoked"
+)
+
+// State returns the lifecycle state of the OTP at now
+func (o *OTP) State(now time.Time) string {
+	switch {
+	case o.IsVerified:
+		return OTPStateVerified
+	case o.RevokedAt != nil:
+		return OTPStateRevoked
+	case o.SupersededBy != "":
+		return OTPStateSuperseded
+	case o.AttemptCount >= o.MaxAttempts:
+		return OTPStateLocked
+	case now.After(o.ExpiresAt):
+		return OTPStateExpired
+	default:
+		return OTPStatePending
+	}
 }
 
 type User struct {
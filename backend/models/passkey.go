@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Passkey is a WebAuthn credential registered by a verified user
+type Passkey struct {
+	ID              string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID          string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	CredentialID    string     `gorm:"type:varchar(255);not null;uniqueIndex" json:"credential_id"` // base64url
+	PublicKey       []byte     `gorm:"type:blob;not null" json:"-"`
+	AttestationType string     `gorm:"type:varchar(32)" json:"attestation_type"`
+	AAGUID          []byte     `gorm:"type:blob" json:"-"`
+	SignCount       uint32     `json:"-"`
+	Transports      string     `gorm:"type:varchar(128)" json:"transports"` // comma separated
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	Name            string     `gorm:"type:varchar(128)" json:"name"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+}
+
+// WebAuthnCeremony stores the server side of a registration or login
+// ceremony between its begin and finish requests
+type WebAuthnCeremony struct {
+	ID          string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID      string    `gorm:"type:varchar(36)" json:"user_id"` // empty for discoverable logins
+	Kind        string    `gorm:"type:varchar(16);not null" json:"kind"`
+	SessionData string    `gorm:"type:text;not null" json:"-"` // JSON encoded webauthn.SessionData
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt   time.Time `gorm:"not null" json:"expires_at"`
+}
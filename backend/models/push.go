@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// PushDevice is a mobile device that can approve sign-ins. It proves
+// possession of its Ed25519 key by signing every request.
+type PushDevice struct {
+	ID         string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID     string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	Name       string     `gorm:"type:varchar(128)" json:"name"`
+	PublicKey  string     `gorm:"type:varchar(64);not null" json:"public_key"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Push challenge states
+const (
+	PushPending  = "pending"
+	PushApproved = "approved"
+	PushDenied   = "denied"
+)
+
+// PushChallenge asks the user's devices to approve an OTP request
+type PushChallenge struct {
+	ID          string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID      string     `gorm:"type:varchar(36);not null;index" json:"-"`
+	OTPID       string     `gorm:"type:varchar(36);not null;uniqueIndex" json:"-"`
+	Nonce       string     `gorm:"type:varchar(64);not null" json:"nonce"`
+	Status      string     `gorm:"type:varchar(16);not null;default:pending" json:"status"`
+	Purpose     string     `gorm:"type:varchar(32)" json:"purpose"`
+	Summary     string     `gorm:"type:varchar(255)" json:"summary"`
+	ClientIP    string     `gorm:"type:varchar(45)" json:"client_ip"`
+	DeviceID    string     `gorm:"type:varchar(36)" json:"device_id,omitempty"` // device that answered
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	CompletedAt *time.Time `json:"-"` // when the requesting client collected the result
+}
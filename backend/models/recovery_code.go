@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode is a single-use backup code. Codes are generated in sets;
+// generating a new set deletes the previous one.
+type RecoveryCode struct {
+	ID        string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID    string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(72);not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// RecoveryAttempt is a failed attempt to sign in with a recovery code. The
+// recent attempts for an email or phone, and from a client IP, are counted
+// to rate-limit guessing and to lock the account's codes.
+type RecoveryAttempt struct {
+	ID         string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Identifier string    `gorm:"type:varchar(255);not null;index" json:"identifier"`
+	ClientIP   string    `gorm:"type:varchar(45);index" json:"client_ip"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RiskDecisionLog is the audit record of a step-up decision
+type RiskDecisionLog struct {
+	ID                string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID            string    `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	Action            string    `gorm:"type:varchar(64)" json:"action"`
+	ClientIP          string    `gorm:"type:varchar(45)" json:"client_ip"`
+	Country           string    `gorm:"type:varchar(2)" json:"country"`
+	UserAgent         string    `gorm:"type:varchar(255)" json:"user_agent"`
+	ChallengeRequired bool      `json:"challenge_required"`
+	Score             int       `json:"score"`
+	Reasons           string    `gorm:"type:text" json:"reasons"` // JSON encoded []utils.RiskReason
+	CreatedAt         time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
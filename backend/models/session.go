@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is a durable login created after a successful verification
+type Session struct {
+	ID         string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID     string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	UserAgent  string     `gorm:"type:varchar(255)" json:"user_agent"`
+	ClientIP   string     `gorm:"type:varchar(45)" json:"client_ip"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// RefreshToken is one link in a session's rotation chain. Only the hash of
+// the token is stored; a token presented after it was rotated is a reuse.
+type RefreshToken struct {
+	ID        string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	SessionID string     `gorm:"type:varchar(36);not null;index" json:"session_id"`
+	TokenHash string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
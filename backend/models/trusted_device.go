@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// TrustedDevice lets a user skip OTP on a device they verified before.
+// Only a hash of the device token is stored.
+type TrustedDevice struct {
+	ID          string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID      string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	TokenHash   string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	Fingerprint string     `gorm:"type:char(64);not null" json:"-"`
+	Name        string     `gorm:"type:varchar(255)" json:"name"`
+	ClientIP    string     `gorm:"type:varchar(45)" json:"client_ip"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastUsedAt  time.Time  `json:"last_used_at"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IsActive reports whether the device is still trusted at now, given the
+// idle timeout (0 disables it)
+func (d *TrustedDevice) IsActive(now time.Time, idle time.Duration) bool {
+	if d.RevokedAt != nil || now.After(d.ExpiresAt) {
+		return false
+	}
+	return idle == 0 || now.Sub(d.LastUsedAt) <= idle
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// WebhookSubscription sends events to an endpoint of another system
+type WebhookSubscription struct {
+	ID  string `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	URL string `gorm:"type:varchar(512);not null" json:"url"`
+	// Secret signs the payloads, so it is stored as is, not hashed
+	Secret      string     `gorm:"type:varchar(64);not null" json:"-"`
+	Events      string     `gorm:"type:text;not null" json:"-"` // comma separated, "*" for all
+	Description string     `gorm:"type:varchar(255)" json:"description"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+}
+
+// EventList returns the subscribed event types
+func (s *WebhookSubscription) EventList() []string {
+	return strings.Split(s.Events, ",")
+}
+
+// Wants reports whether the subscription receives an event type
+func (s *WebhookSubscription) Wants(eventType string) bool {
+	for _, event := range s.EventList() {
+		if event == "*" || event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery states
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent to one subscription, with the outcome
+// of its latest attempt
+type WebhookDelivery struct {
+	ID             string `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	SubscriptionID string `gorm:"type:varchar(36);not null;index" json:"subscription_id"`
+	// EventID is shared by every delivery and replay of the same event, so
+	// receivers can drop duplicates
+	EventID       string     `gorm:"type:varchar(36);not null;index" json:"event_id"`
+	EventType     string     `gorm:"type:varchar(32);not null" json:"event_type"`
+	Payload       string     `gorm:"type:text;not null" json:"-"`
+	Status        string     `gorm:"type:varchar(16);not null;default:pending;index" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index" json:"next_attempt_at"`
+	LastStatus    int        `json:"last_status_code,omitempty"` // HTTP status of the latest attempt
+	LastError     string     `gorm:"type:varchar(512)" json:"last_error,omitempty"`
+	ReplayOf      string     `gorm:"type:varchar(36)" json:"replay_of,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime;index" json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,307 @@
+// Package otpclient is a Go client for the OTP HTTP API. It calls the
+// versioned /api/v1/otp endpoints and returns API failures as *Error.
+//
+//	client := otpclient.New(otpclient.Config{BaseURL: "https://otp.example.com"})
+//
+//	issued, err := client.Generate(ctx, otpclient.GenerateRequest{Phone: "+919876543210"})
+//	result, err := client.Verify(ctx, otpclient.VerifyRequest{OTPID: issued.OTPID, OTPCode: code})
+//	if errors.Is(err, otpclient.ErrOTPInvalid) {
+//		// ask the user to try again
+//	}
+package otpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures a Client
+type Config struct {
+	// BaseURL is the server's root URL, e.g. "https://otp.example.com"
+	BaseURL string
+	// HTTPClient defaults to a client with a 30 second timeout
+	HTTPClient *http.Client
+	// AdminKey is sent as X-Admin-Key. Only Revoke needs it.
+	AdminKey string
+	// MaxRetries is how many times a request that failed with a 5xx status
+	// or a network error is retried. Defaults to 2; negative disables
+	// retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry. It doubles for each
+	// further retry and defaults to 200ms. A Retry-After header takes
+	// precedence.
+	RetryBackoff time.Duration
+}
+
+// Client calls the OTP API. It is safe for concurrent use.
+type Client struct {
+	config Config
+}
+
+// New returns a client for the server at cfg.BaseURL
+func New(cfg Config) *Client {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 200 * time.Millisecond
+	}
+	return &Client{config: cfg}
+}
+
+// Generate sends a new code
+func (c *Client) Generate(ctx context.Context, req GenerateRequest) (*OTPIssued, error) {
+	var out OTPIssued
+	call := request{
+		method:         http.MethodPost,
+		path:           "/api/v1/otp/generate",
+		body:           req,
+		idempotencyKey: idempotencyKey(req.IdempotencyKey),
+		retry:          true,
+	}
+	if err := c.do(ctx, call, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Verify checks a code. It is never retried: the server may have counted
+// the attempt before failing.
+func (c *Client) Verify(ctx context.Context, req VerifyRequest) (*VerifyResult, error) {
+	var out VerifyResult
+	call := request{
+		method: http.MethodPost,
+		path:   "/api/v1/otp/verify",
+		body:   req,
+	}
+	if err := c.do(ctx, call, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Resend replaces an OTP with a new code
+func (c *Client) Resend(ctx context.Context, req ResendRequest) (*OTPIssued, error) {
+	var out OTPIssued
+	call := request{
+		method:         http.MethodPost,
+		path:           "/api/v1/otp/resend",
+		body:           req,
+		idempotencyKey: idempotencyKey(req.IdempotencyKey),
+		retry:          true,
+	}
+	if err := c.do(ctx, call, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Status returns the state of an OTP. statusToken is the one returned
+// when the OTP was issued.
+func (c *Client) Status(ctx context.Context, otpID, statusToken string) (*OTPStatus, error) {
+	var out OTPStatus
+	call := request{
+		method:      http.MethodGet,
+		path:        "/api/v1/otp/" + url.PathEscape(otpID),
+		statusToken: statusToken,
+		retry:       true,
+	}
+	if err := c.do(ctx, call, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Cancel revokes a pending OTP, e.g. when the user abandons the flow.
+// Cancelling an OTP twice succeeds.
+func (c *Client) Cancel(ctx context.Context, otpID, statusToken string) (*OTPCancelled, error) {
+	var out OTPCancelled
+	call := request{
+		method:      http.MethodPost,
+		path:        "/api/v1/otp/" + url.PathEscape(otpID) + "/cancel",
+		statusToken: statusToken,
+		retry:       true,
+	}
+	if err := c.do(ctx, call, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Revoke revokes every pending OTP for an email or phone number and
+// returns how many were revoked. It requires Config.AdminKey.
+func (c *Client) Revoke(ctx context.Context, req RevokeRequest) (int64, error) {
+	var out struct {
+		Revoked int64 `json:"revoked"`
+	}
+	call := request{
+		method: http.MethodPost,
+		path:   "/api/v1/otp/revoke",
+		body:   req,
+		admin:  true,
+		retry:  true,
+	}
+	if err := c.do(ctx, call, &out); err != nil {
+		return 0, err
+	}
+	return out.Revoked, nil
+}
+
+// request describes one API call
+type request struct {
+	method         string
+	path           string
+	body           interface{}
+	idempotencyKey string
+	statusToken    string
+	admin          bool
+	// retry is set for calls that are safe to repeat
+	retry bool
+}
+
+// envelope is the body of every versioned API response
+type envelope struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Error   *struct {
+		Code    ErrorCode              `json:"code"`
+		Message string                 `json:"message"`
+		Details map[string]interface{} `json:"details"`
+	} `json:"error"`
+}
+
+// do sends the call, retrying server errors, and decodes the response data
+// into out
+func (c *Client) do(ctx context.Context, call request, out interface{}) error {
+	var body []byte
+	if call.body != nil {
+		var err error
+		if body, err = json.Marshal(call.body); err != nil {
+			return err
+		}
+	}
+
+	backoff := c.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, call, body, out)
+		if err == nil || !call.retry || attempt >= c.config.MaxRetries || !retryable(err) {
+			return err
+		}
+
+		wait := backoff
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			wait = time.Duration(apiErr.RetryAfter) * time.Second
+		}
+		backoff *= 2
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one HTTP request
+func (c *Client) send(ctx context.Context, call request, body []byte, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, call.method, c.config.BaseURL+call.path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if call.idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", call.idempotencyKey)
+	}
+	if call.statusToken != "" {
+		req.Header.Set("X-OTP-Status-Token", call.statusToken)
+	}
+	if call.admin && c.config.AdminKey != "" {
+		req.Header.Set("X-Admin-Key", c.config.AdminKey)
+	}
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var env envelope
+	decodeErr := json.Unmarshal(raw, &env)
+
+	if resp.StatusCode >= 300 || decodeErr != nil || !env.Success {
+		apiErr := &Error{
+			StatusCode: resp.StatusCode,
+			Message:    http.StatusText(resp.StatusCode),
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = seconds
+		}
+		if decodeErr == nil && env.Error != nil {
+			apiErr.Code = env.Error.Code
+			apiErr.Message = env.Error.Message
+			apiErr.Details = env.Error.Details
+		}
+		return apiErr
+	}
+
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("otp api: decoding response: %w", err)
+	}
+	return nil
+}
+
+// retryable reports whether a failed request may succeed if repeated
+func retryable(err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	// Network errors, but not a cancelled or expired context
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// idempotencyKey returns key, or a random one if it is empty
+func idempotencyKey(key string) string {
+	if key != "" {
+		return key
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
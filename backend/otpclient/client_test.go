@@ -0,0 +1,293 @@
+package otpclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/config"
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/otpclient"
+	"github.com/Avinashkr000/otp-verification-system/backend/routes"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestRouter returns the server's OTP routes on an in-memory database
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := config.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	config.DB = db
+	if err := utils.InitTokenSigner(); err != nil {
+		t.Fatalf("token signer: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterOTPRoutes(r)
+	return r
+}
+
+func newTestClient(t *testing.T, cfg otpclient.Config) *otpclient.Client {
+	t.Helper()
+	srv := httptest.NewServer(newTestRouter(t))
+	t.Cleanup(srv.Close)
+	cfg.BaseURL = srv.URL + "/"
+	return otpclient.New(cfg)
+}
+
+func TestGenerateAndVerify(t *testing.T) {
+	c := newTestClient(t, otpclient.Config{})
+	ctx := context.Background()
+
+	issued, err := c.Generate(ctx, otpclient.GenerateRequest{Phone: "+15550000001", Purpose: "signup"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if issued.OTPID == "" || issued.StatusToken == "" || issued.OTPCode == "" {
+		t.Fatalf("incomplete OTP: %+v", issued)
+	}
+	if issued.CodeFormat.Length != len(issued.OTPCode) {
+		t.Errorf("code format length %d, code %q", issued.CodeFormat.Length, issued.OTPCode)
+	}
+
+	status, err := c.Status(ctx, issued.OTPID, issued.StatusToken)
+	if err != nil || status.State != "pending" {
+		t.Fatalf("Status: %+v, %v", status, err)
+	}
+
+	result, err := c.Verify(ctx, otpclient.VerifyRequest{OTPID: issued.OTPID, OTPCode: issued.OTPCode, Purpose: "signup"})
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !result.Verified || result.Token == "" || result.UserID == "" || result.Phone != "+15550000001" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	_, err = c.Verify(ctx, otpclient.VerifyRequest{OTPID: issued.OTPID, OTPCode: issued.OTPCode, Purpose: "signup"})
+	if !errors.Is(err, otpclient.ErrOTPAlreadyVerified) {
+		t.Errorf("second Verify: got %v, want %s", err, otpclient.CodeOTPAlreadyVerified)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := newTestClient(t, otpclient.Config{})
+	ctx := context.Background()
+
+	_, err := c.Generate(ctx, otpclient.GenerateRequest{Email: "not-an-email"})
+	var apiErr *otpclient.Error
+	if !errors.Is(err, otpclient.ErrValidationFailed) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid email: got %v", err)
+	}
+
+	issued, err := c.Generate(ctx, otpclient.GenerateRequest{Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	_, err = c.Resend(ctx, otpclient.ResendRequest{OTPID: issued.OTPID})
+	if !errors.Is(err, otpclient.ErrResendCooldown) || !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+		t.Errorf("immediate resend: got %v", err)
+	}
+
+	wrong := []byte(issued.OTPCode)
+	wrong[0] = '0' + (wrong[0]-'0'+1)%10
+	_, err = c.Verify(ctx, otpclient.VerifyRequest{OTPID: issued.OTPID, OTPCode: string(wrong)})
+	if !errors.Is(err, otpclient.ErrOTPInvalid) || !errors.As(err, &apiErr) || apiErr.Details["attempts_remaining"] == nil {
+		t.Errorf("wrong code: got %v", err)
+	}
+
+	_, err = c.Status(ctx, issued.OTPID, "wrong-token")
+	if !errors.Is(err, otpclient.ErrOTPNotFound) {
+		t.Errorf("wrong status token: got %v", err)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	c := newTestClient(t, otpclient.Config{})
+	ctx := context.Background()
+
+	tx := &otpclient.Transaction{Amount: "250.00", Currency: "usd", Payee: "ACME Corp"}
+	issued, err := c.Generate(ctx, otpclient.GenerateRequest{Phone: "+15550000002", Transaction: tx})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	other := *tx
+	other.Amount = "2500.00"
+	_, err = c.Verify(ctx, otpclient.VerifyRequest{OTPID: issued.OTPID, OTPCode: issued.OTPCode, Purpose: "transaction", Transaction: &other})
+	if !errors.Is(err, otpclient.ErrTransactionMismatch) {
+		t.Errorf("other amount: got %v", err)
+	}
+	if _, err := c.Verify(ctx, otpclient.VerifyRequest{OTPID: issued.OTPID, OTPCode: issued.OTPCode, Purpose: "transaction", Transaction: tx}); err != nil {
+		t.Errorf("same transaction: %v", err)
+	}
+}
+
+func TestCancelAndRevoke(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "admin-key")
+	c := newTestClient(t, otpclient.Config{AdminKey: "admin-key"})
+	ctx := context.Background()
+
+	issued, err := c.Generate(ctx, otpclient.GenerateRequest{Email: "cancel@example.com"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		cancelled, err := c.Cancel(ctx, issued.OTPID, issued.StatusToken)
+		if err != nil || cancelled.State != "revoked" {
+			t.Fatalf("Cancel %d: %+v, %v", i+1, cancelled, err)
+		}
+	}
+
+	if _, err := c.Generate(ctx, otpclient.GenerateRequest{Email: "revoke@example.com"}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	revoked, err := c.Revoke(ctx, otpclient.RevokeRequest{Email: "revoke@example.com"})
+	if err != nil || revoked != 1 {
+		t.Errorf("Revoke: %d, %v", revoked, err)
+	}
+}
+
+func TestRevokeRequiresAdminKey(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "admin-key")
+	c := newTestClient(t, otpclient.Config{})
+
+	_, err := c.Revoke(context.Background(), otpclient.RevokeRequest{Email: "revoke@example.com"})
+	if !errors.Is(err, otpclient.ErrUnauthorized) {
+		t.Errorf("got %v, want %s", err, otpclient.CodeUnauthorized)
+	}
+}
+
+// flakyServer fails the first failures requests with a gateway error and
+// passes the rest to the router
+func flakyServer(t *testing.T, failures int) (*httptest.Server, func() []string) {
+	t.Helper()
+	router := newTestRouter(t)
+	var mu sync.Mutex
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		fail := len(keys) <= failures
+		mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>bad gateway</html>"))
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), keys...)
+	}
+}
+
+func TestGenerateRetriesWithSameIdempotencyKey(t *testing.T) {
+	srv, keys := flakyServer(t, 2)
+	c := otpclient.New(otpclient.Config{BaseURL: srv.URL, RetryBackoff: time.Millisecond})
+
+	if _, err := c.Generate(context.Background(), otpclient.GenerateRequest{Email: "flaky@example.com"}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	sent := keys()
+	if len(sent) != 3 || sent[0] == "" || sent[0] != sent[1] || sent[1] != sent[2] {
+		t.Errorf("Idempotency-Key per attempt: %q", sent)
+	}
+}
+
+func TestVerifyIsNotRetried(t *testing.T) {
+	srv, keys := flakyServer(t, 1)
+	c := otpclient.New(otpclient.Config{BaseURL: srv.URL, RetryBackoff: time.Millisecond})
+
+	_, err := c.Verify(context.Background(), otpclient.VerifyRequest{OTPID: "otp", OTPCode: "123456"})
+	var apiErr *otpclient.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || apiErr.Code != "" {
+		t.Errorf("got %v, want the gateway error", err)
+	}
+	if n := len(keys()); n != 1 {
+		t.Errorf("%d requests sent, want 1", n)
+	}
+}
+
+func TestRetryStopsWithContext(t *testing.T) {
+	srv, _ := flakyServer(t, 100)
+	c := otpclient.New(otpclient.Config{BaseURL: srv.URL, RetryBackoff: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.Status(ctx, "otp", "token"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("returned after %s", elapsed)
+	}
+}
+
+// The client keeps its own copy of the error codes so it does not import
+// the server; they must stay in sync with the server's catalog
+func TestErrorCodesMatchServer(t *testing.T) {
+	client := map[string]bool{}
+	for _, code := range []otpclient.ErrorCode{
+		otpclient.CodeValidationFailed,
+		otpclient.CodeIdentifierRequired,
+		otpclient.CodePurposeInvalid,
+		otpclient.CodePurposeMismatch,
+		otpclient.CodeTransactionRequired,
+		otpclient.CodeTransactionMismatch,
+		otpclient.CodeMagicLinkUnavailable,
+		otpclient.CodeReturnURLNotAllowed,
+		otpclient.CodeCaptchaRequired,
+		otpclient.CodeCaptchaFailed,
+		otpclient.CodeRateLimited,
+		otpclient.CodeResendCooldown,
+		otpclient.CodeIdempotencyKeyInvalid,
+		otpclient.CodeIdempotencyKeyReused,
+		otpclient.CodeIdempotencyInProgress,
+		otpclient.CodeOTPNotFound,
+		otpclient.CodeOTPAlreadyVerified,
+		otpclient.CodeOTPRevoked,
+		otpclient.CodeOTPSuperseded,
+		otpclient.CodeOTPExpired,
+		otpclient.CodeOTPLocked,
+		otpclient.CodeOTPInvalid,
+		otpclient.CodeUnauthorized,
+		otpclient.CodeStepUpRequired,
+		otpclient.CodeAdminDisabled,
+		otpclient.CodeInternal,
+	} {
+		client[string(code)] = true
+	}
+
+	server := map[string]bool{}
+	for _, info := range engine.ErrorCatalog {
+		server[string(info.Code)] = true
+		if !client[string(info.Code)] {
+			t.Errorf("client is missing %s", info.Code)
+		}
+	}
+	for code := range client {
+		if !server[code] {
+			t.Errorf("server does not return %s", code)
+		}
+	}
+}
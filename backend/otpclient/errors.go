@@ -0,0 +1,95 @@
+package otpclient
+
+import "fmt"
+
+// ErrorCode is a stable error identifier returned by the API
+type ErrorCode string
+
+// Error codes returned by the API
+const (
+	CodeValidationFailed      ErrorCode = "VALIDATION_FAILED"
+	CodeIdentifierRequired    ErrorCode = "IDENTIFIER_REQUIRED"
+	CodePurposeInvalid        ErrorCode = "PURPOSE_INVALID"
+	CodePurposeMismatch       ErrorCode = "PURPOSE_MISMATCH"
+	CodeTransactionRequired   ErrorCode = "TRANSACTION_REQUIRED"
+	CodeTransactionMismatch   ErrorCode = "TRANSACTION_MISMATCH"
+	CodeMagicLinkUnavailable  ErrorCode = "MAGIC_LINK_UNAVAILABLE"
+	CodeReturnURLNotAllowed   ErrorCode = "RETURN_URL_NOT_ALLOWED"
+	CodeCaptchaRequired       ErrorCode = "CAPTCHA_REQUIRED"
+	CodeCaptchaFailed         ErrorCode = "CAPTCHA_FAILED"
+	CodeRateLimited           ErrorCode = "RATE_LIMITED"
+	CodeResendCooldown        ErrorCode = "RESEND_COOLDOWN"
+	CodeIdempotencyKeyInvalid ErrorCode = "IDEMPOTENCY_KEY_INVALID"
+	CodeIdempotencyKeyReused  ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInProgress ErrorCode = "IDEMPOTENCY_IN_PROGRESS"
+	CodeOTPNotFound           ErrorCode = "OTP_NOT_FOUND"
+	CodeOTPAlreadyVerified    ErrorCode = "OTP_ALREADY_VERIFIED"
+	CodeOTPRevoked            ErrorCode = "OTP_REVOKED"
+	CodeOTPSuperseded         ErrorCode = "OTP_SUPERSEDED"
+	CodeOTPExpired            ErrorCode = "OTP_EXPIRED"
+	CodeOTPLocked             ErrorCode = "OTP_LOCKED"
+	CodeOTPInvalid            ErrorCode = "OTP_INVALID"
+	CodeUnauthorized          ErrorCode = "UNAUTHORIZED"
+	CodeStepUpRequired        ErrorCode = "STEP_UP_REQUIRED"
+	CodeAdminDisabled         ErrorCode = "ADMIN_API_DISABLED"
+	CodeInternal              ErrorCode = "INTERNAL_ERROR"
+)
+
+// Error is a failed API call. Match it by code with errors.Is and the Err*
+// values below, or read the code with errors.As:
+//
+//	if errors.Is(err, otpclient.ErrOTPInvalid) { ... }
+type Error struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Code is empty when the response was not from the OTP API, e.g. a
+	// proxy error page
+	Code    ErrorCode
+	Message string
+	Details map[string]interface{}
+	// RetryAfter is the Retry-After header in seconds, if any
+	RetryAfter int
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("otp api: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("otp api: %s: %s", e.Code, e.Message)
+}
+
+// Is reports whether target is an *Error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// Errors to match with errors.Is, one per API error code
+var (
+	ErrValidationFailed      = &Error{Code: CodeValidationFailed}
+	ErrIdentifierRequired    = &Error{Code: CodeIdentifierRequired}
+	ErrPurposeInvalid        = &Error{Code: CodePurposeInvalid}
+	ErrPurposeMismatch       = &Error{Code: CodePurposeMismatch}
+	ErrTransactionRequired   = &Error{Code: CodeTransactionRequired}
+	ErrTransactionMismatch   = &Error{Code: CodeTransactionMismatch}
+	ErrMagicLinkUnavailable  = &Error{Code: CodeMagicLinkUnavailable}
+	ErrReturnURLNotAllowed   = &Error{Code: CodeReturnURLNotAllowed}
+	ErrCaptchaRequired       = &Error{Code: CodeCaptchaRequired}
+	ErrCaptchaFailed         = &Error{Code: CodeCaptchaFailed}
+	ErrRateLimited           = &Error{Code: CodeRateLimited}
+	ErrResendCooldown        = &Error{Code: CodeResendCooldown}
+	ErrIdempotencyKeyInvalid = &Error{Code: CodeIdempotencyKeyInvalid}
+	ErrIdempotencyKeyReused  = &Error{Code: CodeIdempotencyKeyReused}
+	ErrIdempotencyInProgress = &Error{Code: CodeIdempotencyInProgress}
+	ErrOTPNotFound           = &Error{Code: CodeOTPNotFound}
+	ErrOTPAlreadyVerified    = &Error{Code: CodeOTPAlreadyVerified}
+	ErrOTPRevoked            = &Error{Code: CodeOTPRevoked}
+	ErrOTPSuperseded         = &Error{Code: CodeOTPSuperseded}
+	ErrOTPExpired            = &Error{Code: CodeOTPExpired}
+	ErrOTPLocked             = &Error{Code: CodeOTPLocked}
+	ErrOTPInvalid            = &Error{Code: CodeOTPInvalid}
+	ErrUnauthorized          = &Error{Code: CodeUnauthorized}
+	ErrStepUpRequired        = &Error{Code: CodeStepUpRequired}
+	ErrAdminDisabled         = &Error{Code: CodeAdminDisabled}
+	ErrInternal              = &Error{Code: CodeInternal}
+)
@@ -0,0 +1,149 @@
+package otpclient
+
+import "time"
+
+// Transaction is the payment or action a transaction OTP approves. Verify
+// must present the same details the code was generated for.
+type Transaction struct {
+	Amount    string `json:"amount"`
+	Currency  string `json:"currency"`
+	Payee     string `json:"payee"`
+	Reference string `json:"reference,omitempty"`
+}
+
+// GenerateRequest asks for a new OTP. Set Email or Phone.
+type GenerateRequest struct {
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	// Purpose defaults to "login" on the server
+	Purpose     string       `json:"purpose,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	// MagicLink also sends a one-click link (email only)
+	MagicLink bool   `json:"magic_link,omitempty"`
+	ReturnURL string `json:"return_url,omitempty"`
+	// Push also asks the user's registered devices to approve the login
+	Push         bool   `json:"push,omitempty"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
+	// IdempotencyKey is sent as the Idempotency-Key header. The client
+	// generates one if it is empty, so retries never send a second code.
+	IdempotencyKey string `json:"-"`
+}
+
+// VerifyRequest checks a code
+type VerifyRequest struct {
+	OTPID   string `json:"otp_id"`
+	OTPCode string `json:"otp_code"`
+	// Purpose must match the purpose the OTP was generated for
+	Purpose string `json:"purpose,omitempty"`
+	// TransactionHash or Transaction must match the transaction the OTP
+	// was issued for
+	TransactionHash string       `json:"transaction_hash,omitempty"`
+	Transaction     *Transaction `json:"transaction,omitempty"`
+	// RememberDevice asks for a device token to skip OTP next time
+	RememberDevice bool   `json:"remember_device,omitempty"`
+	DeviceName     string `json:"device_name,omitempty"`
+}
+
+// ResendRequest asks for a new code in place of an earlier OTP
+type ResendRequest struct {
+	OTPID        string `json:"otp_id"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
+	// IdempotencyKey works as in GenerateRequest
+	IdempotencyKey string `json:"-"`
+}
+
+// RevokeRequest revokes every pending OTP for an email or phone number
+type RevokeRequest struct {
+	Email   string `json:"email,omitempty"`
+	Phone   string `json:"phone,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// CodeFormat describes the code so clients can render a matching input
+type CodeFormat struct {
+	Format    string `json:"format"`
+	Length    int    `json:"length"`
+	GroupSize int    `json:"group_size"`
+}
+
+// OTPIssued is returned by Generate and Resend
+type OTPIssued struct {
+	OTPID string `json:"otp_id"`
+	// StatusToken authorizes Status and Cancel for this OTP
+	StatusToken     string     `json:"status_token"`
+	Purpose         string     `json:"purpose"`
+	CodeFormat      CodeFormat `json:"code_format"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	SMSStatus       string     `json:"sms_status"`
+	TransactionHash string     `json:"transaction_hash,omitempty"`
+	MagicLinkSent   bool       `json:"magic_link_sent,omitempty"`
+	PushStatus      string     `json:"push_status,omitempty"`
+	// OTPCode and MagicLink are only returned outside production
+	OTPCode   string `json:"otp_code,omitempty"`
+	MagicLink string `json:"magic_link,omitempty"`
+}
+
+// SessionTokens is a signed-in session
+type SessionTokens struct {
+	SessionID        string    `json:"session_id"`
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	TokenType        string    `json:"token_type"`
+}
+
+// TrustedDevice is a remembered device
+type TrustedDevice struct {
+	DeviceID    string    `json:"device_id"`
+	DeviceToken string    `json:"device_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// VerifyResult is returned by Verify. Session and Device are only set for
+// login and signup codes.
+type VerifyResult struct {
+	Verified  bool      `json:"verified"`
+	Purpose   string    `json:"purpose"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Timestamp time.Time `json:"timestamp"`
+	// Token is a signed verification token, checkable with the server's JWKS
+	Token          string         `json:"token"`
+	TokenType      string         `json:"token_type"`
+	TokenExpiresAt time.Time      `json:"token_expires_at"`
+	Session        *SessionTokens `json:"session,omitempty"`
+	Device         *TrustedDevice `json:"device,omitempty"`
+}
+
+// Delivery is how a code was sent
+type Delivery struct {
+	Channel string `json:"channel"`
+	Status  string `json:"status"`
+}
+
+// OTPStatus is the state of an OTP
+type OTPStatus struct {
+	OTPID string `json:"otp_id"`
+	// State is pending, verified, expired, locked, superseded or revoked
+	State             string     `json:"state"`
+	Purpose           string     `json:"purpose"`
+	AttemptsRemaining int        `json:"attempts_remaining"`
+	MaxAttempts       int        `json:"max_attempts"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+	ResendAvailableAt *time.Time `json:"resend_available_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	SupersededBy      string     `json:"superseded_by,omitempty"`
+	Delivery          Delivery   `json:"delivery"`
+}
+
+// OTPCancelled is returned by Cancel
+type OTPCancelled struct {
+	OTPID     string     `json:"otp_id"`
+	State     string     `json:"state"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
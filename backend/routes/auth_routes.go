@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAuthRoutes registers session and second-factor routes
+func RegisterAuthRoutes(router *gin.Engine) {
+	auth := router.Group("/api/auth")
+	{
+		auth.POST("/refresh", controllers.RefreshSession)
+		auth.POST("/logout", controllers.Logout)
+
+		// Passwordless login for existing users, signup for new ones
+		auth.POST("/login/start", controllers.StartLogin)
+		auth.POST("/login/complete", controllers.CompleteLogin)
+		auth.POST("/signup/start", controllers.StartSignup)
+		auth.POST("/signup/complete", controllers.CompleteSignup)
+
+		sessions := auth.Group("/sessions", middleware.RequireAccessToken())
+		{
+			sessions.GET("", controllers.ListSessions)
+			sessions.DELETE("", controllers.RevokeAllSessions)
+			sessions.DELETE("/:id", controllers.RevokeSession)
+		}
+
+		// Remembered devices skip OTP until they expire or are revoked
+		auth.POST("/devices/check", controllers.CheckTrustedDevice)
+		devices := auth.Group("/devices", middleware.RequireAccessToken())
+		{
+			devices.GET("", controllers.ListTrustedDevices)
+			devices.DELETE("", controllers.RevokeAllTrustedDevices)
+			devices.DELETE("/:id", controllers.RevokeTrustedDevice)
+		}
+
+		// Authenticator-app second factor (TOTP/HOTP)
+		auth.POST("/totp/verify", controllers.VerifyAuthenticator)
+		totp := auth.Group("/totp", middleware.RequireAccessToken())
+		{
+			totp.POST("/enroll", controllers.EnrollAuthenticator)
+			totp.GET("/qr", controllers.GetAuthenticatorQR)
+			totp.POST("/confirm", controllers.ConfirmAuthenticator)
+			totp.POST("/resync", controllers.ResyncHOTP)
+			totp.DELETE("", controllers.RemoveAuthenticator)
+		}
+
+		// Single-use recovery codes
+		auth.POST("/recovery-codes/verify", controllers.UseRecoveryCode)
+		auth.POST("/recovery-codes", middleware.RequireAccessToken(), controllers.GenerateRecoveryCodes)
+
+		// Passkeys (WebAuthn) for users who verified with an OTP
+		auth.POST("/passkeys/login/begin", controllers.BeginPasskeyLogin)
+		auth.POST("/passkeys/login/finish", controllers.FinishPasskeyLogin)
+		passkeys := auth.Group("/passkeys", middleware.RequireAccessToken())
+		{
+			passkeys.POST("/register/begin", controllers.BeginPasskeyRegistration)
+			passkeys.POST("/register/finish", controllers.FinishPasskeyRegistration)
+			passkeys.GET("", controllers.ListPasskeys)
+			passkeys.DELETE("/:id", controllers.RemovePasskey)
+		}
+
+		auth.GET("/me", middleware.RequireAccessToken(), controllers.GetProfile)
+	}
+}
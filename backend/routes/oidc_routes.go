@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterOIDCRoutes registers the OpenID Connect provider endpoints
+func RegisterOIDCRoutes(router *gin.Engine) {
+	router.GET("/.well-known/openid-configuration", controllers.OpenIDConfiguration)
+
+	oauth := router.Group("/oauth")
+	{
+		oauth.GET("/authorize", controllers.Authorize)
+		oauth.POST("/authorize", controllers.AuthorizeSendCode)
+		oauth.POST("/authorize/verify", controllers.AuthorizeVerifyCode)
+		oauth.POST("/token", controllers.Token)
+		oauth.GET("/userinfo", middleware.RequireAccessToken(), controllers.UserInfo)
+
+		oauth.POST("/clients", middleware.RequireAdminKey(), controllers.RegisterClient)
+	}
+}
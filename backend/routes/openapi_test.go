@@ -0,0 +1,112 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/routes"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+var ginParam = regexp.MustCompile(`[:*](\w+)`)
+
+// Every route of the versioned API must be in the OpenAPI document, and
+// the document must not describe routes that do not exist
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterOTPRoutes(r)
+
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/v1/") {
+			continue
+		}
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		registered[route.Method+" "+path] = true
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json: %d %s", w.Code, w.Body.String())
+	}
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+	documented := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for _, route := range sortedKeys(registered) {
+		if !documented[route] {
+			t.Errorf("%s is not in the OpenAPI document", route)
+		}
+	}
+	for _, route := range sortedKeys(documented) {
+		if !registered[route] {
+			t.Errorf("%s is documented but not registered", route)
+		}
+	}
+}
+
+func TestDocsLoadRedocSafely(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterOTPRoutes(r)
+
+	docs := func() string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+		return w.Body.String()
+	}
+
+	t.Setenv("API_DOCS_REDOC_FILE", filepath.Join(t.TempDir(), "missing.js"))
+	if page := docs(); strings.Contains(page, "<script") || !strings.Contains(page, `href="/openapi.json"`) {
+		t.Errorf("docs without a bundle do not fall back to the spec:\n%s", page)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "redoc.standalone.js")
+	if err := os.WriteFile(bundle, []byte("// redoc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("API_DOCS_REDOC_FILE", bundle)
+	if page := docs(); !strings.Contains(page, `<script src="/docs/redoc.standalone.js"></script>`) {
+		t.Errorf("docs do not load the local bundle:\n%s", page)
+	}
+
+	t.Setenv("API_DOCS_SCRIPT_URL", "https://cdn.example.com/redoc.js")
+	if err := utils.GetDocsConfig().Validate(); err == nil {
+		t.Error("CDN script without an integrity hash was accepted")
+	}
+	t.Setenv("API_DOCS_SCRIPT_INTEGRITY", "sha384-abc")
+	if err := utils.GetDocsConfig().Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	if page := docs(); !strings.Contains(page, `integrity="sha384-abc" crossorigin="anonymous"`) {
+		t.Errorf("docs script has no integrity attribute:\n%s", page)
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,20 +1,43 @@
 package routes
 
 import (
-	"otp-backend/controllers"
-
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterOTPRoutes registers all OTP-related routes
+// RegisterOTPRoutes registers all OTP-related routes. They are served
+// under /api/v1 with the typed envelope and error codes, and under the
+// original /api prefix as a compatibility alias.
 func RegisterOTPRoutes(router *gin.Engine) {
+	// Public keys for verifying tokens issued by VerifyOTP
+	router.GET("/.well-known/jwks.json", controllers.JWKS)
+
+	// Machine-readable contract of the versioned API and a browsable reference
+	router.GET("/openapi.json", controllers.OpenAPISpec)
+	router.GET("/docs", controllers.APIDocs)
+	router.GET("/docs/redoc.standalone.js", controllers.APIDocsScript)
+
 	api := router.Group("/api")
 	{
-		otp := api.Group("/otp")
-		{
-			otp.POST("/generate", controllers.GenerateOTP)
-			otp.POST("/verify", controllers.VerifyOTP)
-			otp.POST("/resend", controllers.ResendOTP)
-		}
+		registerOTPEndpoints(api.Group("/otp"))
+	}
+
+	v1 := router.Group("/api/v1", middleware.APIVersion("v1"))
+	{
+		v1.GET("/errors", controllers.ListErrorCodes)
+		registerOTPEndpoints(v1.Group("/otp"))
 	}
 }
+
+func registerOTPEndpoints(otp *gin.RouterGroup) {
+	otp.POST("/generate", middleware.Idempotency(), controllers.GenerateOTP)
+	otp.POST("/verify", controllers.VerifyOTP)
+	otp.POST("/resend", middleware.Idempotency(), controllers.ResendOTP)
+	otp.GET("/challenge", controllers.GetChallenge)
+	otp.GET("/magic", controllers.ConsumeMagicLink)
+	otp.GET("/:id", controllers.GetOTPStatus)
+	otp.GET("/:id/events", controllers.StreamOTPEvents)
+	otp.POST("/:id/cancel", controllers.CancelOTP)
+	otp.POST("/revoke", middleware.RequireAdminKey(), controllers.RevokeOTPs)
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPushRoutes registers push-approval routes for users, their
+// devices and the clients waiting on an approval
+func RegisterPushRoutes(router *gin.Engine) {
+	devices := router.Group("/api/auth/push/devices", middleware.RequireAccessToken())
+	{
+		devices.POST("", controllers.RegisterPushDevice)
+		devices.GET("", controllers.ListPushDevices)
+		devices.DELETE("/:id", controllers.RemovePushDevice)
+	}
+
+	// Called by devices, authenticated with their signing key
+	push := router.Group("/api/push")
+	{
+		push.GET("/challenges", controllers.ListPushChallenges)
+		push.POST("/challenges/:id", controllers.RespondPushChallenge)
+	}
+
+	router.POST("/api/otp/push/complete", controllers.CompletePushVerification)
+}
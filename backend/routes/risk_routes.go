@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRiskRoutes registers the step-up decision endpoints. They are
+// called by other services, so they require the admin key.
+func RegisterRiskRoutes(router *gin.Engine) {
+	risk := router.Group("/api/risk", middleware.RequireAdminKey())
+	{
+		risk.POST("/evaluate", controllers.EvaluateRisk)
+		risk.GET("/decisions", controllers.ListRiskDecisions)
+	}
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/Avinashkr000/otp-verification-system/backend/controllers"
+	"github.com/Avinashkr000/otp-verification-system/backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebhookRoutes registers the webhook subscription and delivery
+// log endpoints. They are for operators, so they require the admin key.
+func RegisterWebhookRoutes(router *gin.Engine) {
+	webhooks := router.Group("/api/webhooks", middleware.RequireAdminKey())
+	{
+		webhooks.POST("", controllers.CreateWebhook)
+		webhooks.GET("", controllers.ListWebhooks)
+		webhooks.DELETE("/:id", controllers.DeleteWebhook)
+		webhooks.GET("/deliveries", controllers.ListWebhookDeliveries)
+		webhooks.POST("/deliveries/:id/replay", controllers.ReplayWebhookDelivery)
+	}
+}
@@ -0,0 +1,26 @@
+// Package securetoken creates random tokens and hashes them for storage.
+// Only hashes are stored, so a leaked database does not leak the tokens.
+package securetoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// New returns a URL-safe random string built from n random bytes
+func New(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns the hex SHA-256 of a secret token for storage.
+// Tokens are high-entropy random values so a plain hash is sufficient.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,50 @@
+package templates
+
+import (
+	"embed"
+	"html/template"
+)
+
+//go:embed *.html
+var files embed.FS
+
+// Authorize renders the OpenID Connect sign-in page
+var Authorize = template.Must(template.ParseFS(files, "authorize.html"))
+
+// AuthorizePage is the data for the Authorize template. Step is
+// "identify", "code" or "error".
+type AuthorizePage struct {
+	Step       string
+	ClientName string
+	RequestID  string
+	SentTo     string
+	CodeFormat string
+	Error      string
+	// Email and Phone refill the identify form after a rejected attempt
+	Email string
+	Phone string
+	// Captcha, if set, must be solved before a code is sent
+	Captcha *AuthorizeCaptcha
+}
+
+// AuthorizeCaptcha is the bot-protection challenge shown on the sign-in
+// page. Hosted providers need SiteKey; "pow" needs Challenge and Difficulty.
+type AuthorizeCaptcha struct {
+	Provider   string
+	SiteKey    string
+	Challenge  string
+	Difficulty int
+}
+
+// Docs renders the API reference from the OpenAPI document
+var Docs = template.Must(template.ParseFS(files, "docs.html"))
+
+// DocsPage is the data for the Docs template
+type DocsPage struct {
+	Title   string
+	SpecURL string
+	// ScriptURL loads Redoc; ScriptIntegrity, if set, is its SRI hash.
+	// Without ScriptURL the page links to the spec instead.
+	ScriptURL       string
+	ScriptIntegrity string
+}
@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChallengeVerifier verifies a bot-protection token submitted by the client
+type ChallengeVerifier interface {
+	// Provider returns the provider name reported to clients
+	Provider() string
+	// Verify checks the token, returning an error if it is not acceptable
+	Verify(token, remoteIP string) error
+}
+
+// CaptchaConfig holds bot-protection settings
+type CaptchaConfig struct {
+	Provider      string // none, hcaptcha, recaptcha, turnstile, pow or fake
+	Mode          string // off, always or risk
+	SiteKey       string
+	SecretKey     string
+	RiskThreshold int // prior requests in the last hour before a challenge is required
+	PowDifficulty int // leading zero bits required by the proof-of-work challenge
+}
+
+// GetCaptchaConfig reads bot-protection configuration from environment variables
+func GetCaptchaConfig() *CaptchaConfig {
+	config := &CaptchaConfig{
+		Provider:      strings.ToLower(os.Getenv("CAPTCHA_PROVIDER")),
+		Mode:          strings.ToLower(os.Getenv("CAPTCHA_MODE")),
+		SiteKey:       os.Getenv("CAPTCHA_SITE_KEY"),
+		SecretKey:     os.Getenv("CAPTCHA_SECRET_KEY"),
+		RiskThreshold: 1,
+		PowDifficulty: 20,
+	}
+
+	if config.Provider == "" {
+		config.Provider = "none"
+	}
+	if config.Mode == "" {
+		config.Mode = "risk"
+	}
+	if v, err := strconv.Atoi(os.Getenv("CAPTCHA_RISK_THRESHOLD")); err == nil && v >= 0 {
+		config.RiskThreshold = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("CAPTCHA_POW_DIFFICULTY")); err == nil && v > 0 && v <= 32 {
+		config.PowDifficulty = v
+	}
+
+	return config
+}
+
+// Enabled reports whether any challenge can be enforced
+func (c *CaptchaConfig) Enabled() bool {
+	return c.Provider != "none" && c.Mode != "off"
+}
+
+// NewChallengeVerifier returns the verifier for the configured provider,
+// or nil when bot protection is disabled
+func NewChallengeVerifier(config *CaptchaConfig) (ChallengeVerifier, error) {
+	switch config.Provider {
+	case "none", "":
+		return nil, nil
+	case "hcaptcha":
+		return newSiteVerifier("hcaptcha", "https://api.hcaptcha.com/siteverify", config.SecretKey)
+	case "recaptcha":
+		return newSiteVerifier("recaptcha", "https://www.google.com/recaptcha/api/siteverify", config.SecretKey)
+	case "turnstile":
+		return newSiteVerifier("turnstile", "https://challenges.cloudflare.com/turnstile/v0/siteverify", config.SecretKey)
+	case "pow":
+		key, err := captchaSigningKey(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewProofOfWorkVerifier(key, config.PowDifficulty), nil
+	case "fake":
+		// It accepts a fixed token, so it would turn bot protection off
+		if os.Getenv("ENVIRONMENT") == "production" {
+			return nil, fmt.Errorf("captcha provider fake is not allowed in production")
+		}
+		return &FakeChallengeVerifier{AcceptToken: "pass"}, nil
+	default:
+		return nil, fmt.Errorf("unknown captcha provider: %s", config.Provider)
+	}
+}
+
+// captchaSigningKey returns the HMAC key for self-issued challenges
+func captchaSigningKey(config *CaptchaConfig) ([]byte, error) {
+	if config.SecretKey != "" {
+		return []byte(config.SecretKey), nil
+	}
+	// No secret configured: challenges are only valid for this process
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate captcha signing key: %w", err)
+	}
+	return key, nil
+}
+
+// SiteVerifier checks tokens against a hosted siteverify endpoint.
+// hCaptcha, reCAPTCHA and Turnstile all share the same request format.
+type SiteVerifier struct {
+	name      string
+	verifyURL string
+	secret    string
+	client    *http.Client
+}
+
+// siteVerifyResponse represents the siteverify API response
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+func newSiteVerifier(name, verifyURL, secret string) (*SiteVerifier, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("%s secret key not configured", name)
+	}
+	return &SiteVerifier{
+		name:      name,
+		verifyURL: verifyURL,
+		secret:    secret,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Provider returns the provider name
+func (v *SiteVerifier) Provider() string {
+	return v.name
+}
+
+// Verify posts the token to the provider's siteverify endpoint
+func (v *SiteVerifier) Verify(token, remoteIP string) error {
+	if token == "" {
+		return fmt.Errorf("captcha token missing")
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.secret)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	resp, err := v.client.PostForm(v.verifyURL, form)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", v.name, err)
+	}
+	defer resp.Body.Close()
+
+	var result siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse %s response: %v", v.name, err)
+	}
+
+	if !result.Success {
+		return fmt.Errorf("%s rejected token: %s", v.name, strings.Join(result.ErrorCodes, ","))
+	}
+
+	return nil
+}
+
+// ProofOfWorkVerifier is a self-hosted challenge that needs no outside service.
+// The server issues a signed challenge and the client must find a nonce such
+// that sha256(challenge + ":" + nonce) starts with Difficulty zero bits.
+// Each challenge is accepted once; spent challenges are remembered in
+// memory until they expire.
+type ProofOfWorkVerifier struct {
+	key        []byte
+	Difficulty int
+	TTL        time.Duration
+
+	mu    sync.Mutex
+	spent map[string]time.Time // challenge -> expiry
+}
+
+// PowChallenge is handed to clients that must solve a proof-of-work
+type PowChallenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// NewProofOfWorkVerifier creates a proof-of-work verifier
+func NewProofOfWorkVerifier(key []byte, difficulty int) *ProofOfWorkVerifier {
+	return &ProofOfWorkVerifier{
+		key:        key,
+		Difficulty: difficulty,
+		TTL:        5 * time.Minute,
+		spent:      make(map[string]time.Time),
+	}
+}
+
+// Provider returns the provider name
+func (v *ProofOfWorkVerifier) Provider() string {
+	return "pow"
+}
+
+// NewChallenge issues a signed challenge of the form "<expiry>.<random>.<mac>"
+func (v *ProofOfWorkVerifier) NewChallenge() (*PowChallenge, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(v.TTL)
+	payload := fmt.Sprintf("%d.%d.%s", expiresAt.Unix(), v.Difficulty, hex.EncodeToString(random))
+
+	return &PowChallenge{
+		Challenge:  payload + "." + v.sign(payload),
+		Difficulty: v.Difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// Verify checks a token of the form "<challenge>:<nonce>"
+func (v *ProofOfWorkVerifier) Verify(token, remoteIP string) error {
+	idx := strings.LastIndex(token, ":")
+	if idx <= 0 {
+		return fmt.Errorf("malformed proof-of-work token")
+	}
+	challenge := token[:idx]
+
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 {
+		return fmt.Errorf("malformed proof-of-work challenge")
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(v.sign(payload)), []byte(parts[3])) {
+		return fmt.Errorf("proof-of-work challenge signature invalid")
+	}
+
+	expiry, err := strconv.ParseInt(parts[0], 10, 64)
+	now := time.Now()
+	if err != nil || now.Unix() > expiry {
+		return fmt.Errorf("proof-of-work challenge expired")
+	}
+
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil || difficulty < v.Difficulty {
+		return fmt.Errorf("proof-of-work difficulty too low")
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	if leadingZeroBits(sum[:]) < difficulty {
+		return fmt.Errorf("proof-of-work solution invalid")
+	}
+
+	return v.spend(challenge, time.Unix(expiry, 0), now)
+}
+
+// spend marks a solved challenge as used so another nonce for it, or the
+// same token, cannot be replayed before it expires
+func (v *ProofOfWorkVerifier) spend(challenge string, expiresAt, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, used := v.spent[challenge]; used {
+		return fmt.Errorf("proof-of-work challenge already used")
+	}
+	for c, exp := range v.spent {
+		if now.After(exp) {
+			delete(v.spent, c)
+		}
+	}
+	v.spent[challenge] = expiresAt
+	return nil
+}
+
+func (v *ProofOfWorkVerifier) sign(payload string) string {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SolveProofOfWork brute-forces a nonce for the challenge. It is what a
+// client does in the browser and is provided for tooling and tests.
+func SolveProofOfWork(challenge string, difficulty int) string {
+	for nonce := 0; ; nonce++ {
+		token := challenge + ":" + strconv.Itoa(nonce)
+		sum := sha256.Sum256([]byte(token))
+		if leadingZeroBits(sum[:]) >= difficulty {
+			return token
+		}
+	}
+}
+
+func leadingZeroBits(b []byte) int {
+	count := 0
+	for _, x := range b {
+		if x == 0 {
+			count += 8
+			continue
+		}
+		return count + bits.LeadingZeros8(x)
+	}
+	return count
+}
+
+// FakeChallengeVerifier accepts a single fixed token. It is meant for local
+// development and tests and must never be used in production.
+type FakeChallengeVerifier struct {
+	AcceptToken string
+	calls       atomic.Int64
+}
+
+// Provider returns the provider name
+func (v *FakeChallengeVerifier) Provider() string {
+	return "fake"
+}
+
+// Verify accepts only the configured token
+func (v *FakeChallengeVerifier) Verify(token, remoteIP string) error {
+	v.calls.Add(1)
+	if token == "" || token != v.AcceptToken {
+		return fmt.Errorf("fake verifier rejected token")
+	}
+	return nil
+}
+
+// Calls returns how many tokens have been checked
+func (v *FakeChallengeVerifier) Calls() int {
+	return int(v.calls.Load())
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProofOfWorkAcceptsEachChallengeOnce(t *testing.T) {
+	v := NewProofOfWorkVerifier([]byte("test-key"), 8)
+	challenge, err := v.NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+
+	token := SolveProofOfWork(challenge.Challenge, challenge.Difficulty)
+	if err := v.Verify(token, ""); err != nil {
+		t.Fatalf("solved token rejected: %v", err)
+	}
+	if err := v.Verify(token, ""); err == nil {
+		t.Error("replayed token accepted")
+	}
+
+	// A different nonce for the same challenge is still the same challenge
+	var other string
+	for nonce := 0; other == ""; nonce++ {
+		candidate := challenge.Challenge + ":x" + strconv.Itoa(nonce)
+		if sum := sha256.Sum256([]byte(candidate)); leadingZeroBits(sum[:]) >= challenge.Difficulty {
+			other = candidate
+		}
+	}
+	if err := v.Verify(other, ""); err == nil {
+		t.Error("second solution for a spent challenge accepted")
+	}
+}
+
+func TestProofOfWorkRejectsBadTokens(t *testing.T) {
+	v := NewProofOfWorkVerifier([]byte("test-key"), 8)
+	challenge, _ := v.NewChallenge()
+	solved := SolveProofOfWork(challenge.Challenge, challenge.Difficulty)
+
+	easy := NewProofOfWorkVerifier([]byte("test-key"), 1)
+	easyChallenge, _ := easy.NewChallenge()
+
+	expired := NewProofOfWorkVerifier([]byte("test-key"), 8)
+	expired.TTL = -time.Minute
+	expiredChallenge, _ := expired.NewChallenge()
+
+	for name, token := range map[string]string{
+		"empty":          "",
+		"no nonce":       challenge.Challenge,
+		"wrong key":      SolveProofOfWork(mustChallenge(t, NewProofOfWorkVerifier([]byte("other-key"), 8)), 8),
+		"tampered":       strings.Replace(solved, ".", ".9", 1),
+		"low difficulty": SolveProofOfWork(easyChallenge.Challenge, 1),
+		"expired":        SolveProofOfWork(expiredChallenge.Challenge, 8),
+		"unsolved":       challenge.Challenge + ":unsolved",
+	} {
+		if err := v.Verify(token, ""); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+}
+
+func mustChallenge(t *testing.T, v *ProofOfWorkVerifier) string {
+	t.Helper()
+	challenge, err := v.NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge: %v", err)
+	}
+	return challenge.Challenge
+}
+
+// Concurrent requests share one verifier; run with -race
+func TestVerifiersAreSafeForConcurrentUse(t *testing.T) {
+	fake := &FakeChallengeVerifier{AcceptToken: "pass"}
+	pow := NewProofOfWorkVerifier([]byte("test-key"), 4)
+	tokens := make([]string, 20)
+	for i := range tokens {
+		tokens[i] = SolveProofOfWork(mustChallenge(t, pow), 4)
+	}
+
+	var wg sync.WaitGroup
+	for _, token := range tokens {
+		wg.Add(1)
+		go func(token string) {
+			defer wg.Done()
+			fake.Verify("pass", "")
+			if err := pow.Verify(token, ""); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+		}(token)
+	}
+	wg.Wait()
+
+	if fake.Calls() != len(tokens) {
+		t.Errorf("fake verifier counted %d calls, want %d", fake.Calls(), len(tokens))
+	}
+}
+
+func TestFakeVerifierRefusedInProduction(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "production")
+	if _, err := NewChallengeVerifier(&CaptchaConfig{Provider: "fake"}); err == nil {
+		t.Error("fake verifier configured in production")
+	}
+
+	t.Setenv("ENVIRONMENT", "development")
+	if v, err := NewChallengeVerifier(&CaptchaConfig{Provider: "fake"}); err != nil || v == nil {
+		t.Errorf("fake verifier refused in development: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeviceConfig holds the expiry policy for trusted devices
+type DeviceConfig struct {
+	TTL        time.Duration // absolute lifetime from when the device was trusted
+	IdleTTL    time.Duration // trust lapses after this long unused; 0 disables
+	MaxPerUser int           // oldest devices are revoked beyond this
+}
+
+// GetDeviceConfig reads trusted device configuration from environment variables
+func GetDeviceConfig() *DeviceConfig {
+	config := &DeviceConfig{
+		TTL:        30 * 24 * time.Hour,
+		IdleTTL:    14 * 24 * time.Hour,
+		MaxPerUser: 10,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("TRUSTED_DEVICE_TTL_DAYS")); err == nil && v > 0 {
+		config.TTL = time.Duration(v) * 24 * time.Hour
+	}
+	if v, err := strconv.Atoi(os.Getenv("TRUSTED_DEVICE_IDLE_DAYS")); err == nil && v >= 0 {
+		config.IdleTTL = time.Duration(v) * 24 * time.Hour
+	}
+	if v, err := strconv.Atoi(os.Getenv("TRUSTED_DEVICE_MAX_PER_USER")); err == nil && v > 0 {
+		config.MaxPerUser = v
+	}
+
+	return config
+}
+
+// DeviceFingerprint binds a device token to the client's browser family
+// and operating system so a token copied to another browser is rejected.
+// Versions are left out, so trust survives browser and OS updates.
+func DeviceFingerprint(userAgent string) string {
+	browser, system := UserAgentFamily(userAgent)
+	sum := sha256.Sum256([]byte(browser + "/" + system))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeviceFingerprintMatches reports whether a stored fingerprint belongs to
+// userAgent. Devices trusted before fingerprints left out versions still
+// match their exact user agent.
+func DeviceFingerprintMatches(fingerprint, userAgent string) bool {
+	if fingerprint == DeviceFingerprint(userAgent) {
+		return true
+	}
+	legacy := sha256.Sum256([]byte(strings.TrimSpace(userAgent)))
+	return fingerprint == hex.EncodeToString(legacy[:])
+}
+
+// userAgentBrowsers and userAgentSystems map user agent tokens to families.
+// Order matters: Edge and Opera also claim to be Chrome, Chrome claims to
+// be Safari, and Android claims to be Linux.
+var (
+	userAgentBrowsers = []struct{ token, family string }{
+		{"Edg/", "edge"}, {"EdgA/", "edge"}, {"EdgiOS/", "edge"}, {"Edge/", "edge"},
+		{"OPR/", "opera"}, {"Opera", "opera"},
+		{"SamsungBrowser/", "samsung"},
+		{"Firefox/", "firefox"}, {"FxiOS/", "firefox"},
+		{"Chrome/", "chrome"}, {"CriOS/", "chrome"}, {"Chromium/", "chrome"},
+		{"Safari/", "safari"},
+	}
+	userAgentSystems = []struct{ token, family string }{
+		{"Windows", "windows"},
+		{"Android", "android"},
+		{"iPhone", "ios"}, {"iPad", "ios"}, {"iPod", "ios"},
+		{"CrOS", "chromeos"},
+		{"Macintosh", "macos"}, {"Mac OS X", "macos"},
+		{"Linux", "linux"},
+	}
+)
+
+// UserAgentFamily returns the browser family and operating system of a
+// user agent. Other clients are identified by their product name, such as
+// "curl" or "okhttp".
+func UserAgentFamily(userAgent string) (browser, system string) {
+	for _, b := range userAgentBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.family
+			break
+		}
+	}
+	if browser == "" {
+		product := strings.TrimSpace(userAgent)
+		if i := strings.IndexAny(product, "/ "); i >= 0 {
+			product = product[:i]
+		}
+		browser = strings.ToLower(product)
+	}
+
+	system = "other"
+	for _, s := range userAgentSystems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.family
+			break
+		}
+	}
+	return browser, system
+}
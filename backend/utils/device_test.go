@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+const (
+	chromeWindows  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36"
+	chromeWindows2 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.6723.59 Safari/537.36"
+	chromeMac      = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36"
+	edgeWindows    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.2792.65"
+	firefoxLinux   = "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0"
+	safariIPhone   = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Mobile/15E148 Safari/604.1"
+	chromeIPhone   = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/129.0.6668.69 Mobile/15E148 Safari/604.1"
+	chromeAndroid  = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36"
+)
+
+func TestUserAgentFamily(t *testing.T) {
+	for userAgent, want := range map[string][2]string{
+		chromeWindows: {"chrome", "windows"},
+		chromeMac:     {"chrome", "macos"},
+		edgeWindows:   {"edge", "windows"},
+		firefoxLinux:  {"firefox", "linux"},
+		safariIPhone:  {"safari", "ios"},
+		chromeIPhone:  {"chrome", "ios"},
+		chromeAndroid: {"chrome", "android"},
+		"curl/8.5.0":  {"curl", "other"},
+	} {
+		if browser, system := UserAgentFamily(userAgent); browser != want[0] || system != want[1] {
+			t.Errorf("%s: %s/%s, want %s/%s", userAgent, browser, system, want[0], want[1])
+		}
+	}
+}
+
+func TestDeviceFingerprintIgnoresVersions(t *testing.T) {
+	if DeviceFingerprint(chromeWindows) != DeviceFingerprint(chromeWindows2) {
+		t.Error("browser update changed the fingerprint")
+	}
+	for _, other := range []string{chromeMac, edgeWindows, firefoxLinux, "curl/8.5.0"} {
+		if DeviceFingerprint(chromeWindows) == DeviceFingerprint(other) {
+			t.Errorf("%s has the same fingerprint as Chrome on Windows", other)
+		}
+	}
+}
+
+func TestDeviceFingerprintMatchesLegacy(t *testing.T) {
+	legacy := sha256.Sum256([]byte(chromeWindows))
+	fingerprint := hex.EncodeToString(legacy[:])
+
+	if !DeviceFingerprintMatches(fingerprint, chromeWindows) {
+		t.Error("legacy fingerprint rejected for its own user agent")
+	}
+	if DeviceFingerprintMatches(fingerprint, chromeWindows2) {
+		t.Error("legacy fingerprint accepted for another user agent")
+	}
+	if !DeviceFingerprintMatches(DeviceFingerprint(chromeWindows), chromeWindows2) {
+		t.Error("fingerprint rejected after a browser update")
+	}
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// LocalRedocURL is where this server serves its own copy of Redoc
+const LocalRedocURL = "/docs/redoc.standalone.js"
+
+// DocsConfig controls where the API reference page loads Redoc from
+type DocsConfig struct {
+	// ScriptURL is the Redoc bundle loaded by /docs. Defaults to the copy
+	// this server serves from RedocFile.
+	ScriptURL string
+	// ScriptIntegrity is the Subresource Integrity hash of ScriptURL,
+	// e.g. "sha384-...". Required when ScriptURL is on another host.
+	ScriptIntegrity string
+	// RedocFile is the local Redoc bundle served at /docs/redoc.standalone.js
+	RedocFile string
+}
+
+// GetDocsConfig reads API docs configuration from environment variables
+func GetDocsConfig() *DocsConfig {
+	config := &DocsConfig{
+		ScriptURL:       os.Getenv("API_DOCS_SCRIPT_URL"),
+		ScriptIntegrity: os.Getenv("API_DOCS_SCRIPT_INTEGRITY"),
+		RedocFile:       os.Getenv("API_DOCS_REDOC_FILE"),
+	}
+
+	if config.ScriptURL == "" {
+		config.ScriptURL = LocalRedocURL
+	}
+	if config.RedocFile == "" {
+		config.RedocFile = "static/redoc.standalone.js"
+	}
+
+	return config
+}
+
+// Validate rejects a script from another host without an integrity hash,
+// which would let whoever controls that host run code on the docs page
+func (c *DocsConfig) Validate() error {
+	u, err := url.Parse(c.ScriptURL)
+	if err != nil {
+		return fmt.Errorf("API_DOCS_SCRIPT_URL: %w", err)
+	}
+	if u.Host != "" && c.ScriptIntegrity == "" {
+		return fmt.Errorf("API_DOCS_SCRIPT_INTEGRITY is required when API_DOCS_SCRIPT_URL is on another host")
+	}
+	return nil
+}
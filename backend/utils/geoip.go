@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoLocation is where an IP address is located
+type GeoLocation struct {
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// GeoLocator looks up the location of an IP address
+type GeoLocator interface {
+	Locate(ip string) (*GeoLocation, error)
+}
+
+// MaxMindLocator reads an offline MaxMind/DB-IP .mmdb city database
+type MaxMindLocator struct {
+	db *geoip2.Reader
+}
+
+// NewMaxMindLocator opens a GeoLite2-City compatible database file
+func NewMaxMindLocator(path string) (*MaxMindLocator, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MaxMindLocator{db: db}, nil
+}
+
+// Locate returns the country and coordinates of ip
+func (l *MaxMindLocator) Locate(ip string) (*GeoLocation, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", ip)
+	}
+
+	record, err := l.db.City(parsed)
+	if err != nil {
+		return nil, err
+	}
+	if record.Country.IsoCode == "" {
+		return nil, fmt.Errorf("no location for %s", ip)
+	}
+
+	return &GeoLocation{
+		Country:   record.Country.IsoCode,
+		Latitude:  record.Location.Latitude,
+		Longitude: record.Location.Longitude,
+	}, nil
+}
+
+// StaticLocator maps IPs to fixed locations, e.g. for tests and private networks
+type StaticLocator map[string]GeoLocation
+
+// Locate returns the configured location of ip
+func (l StaticLocator) Locate(ip string) (*GeoLocation, error) {
+	loc, ok := l[ip]
+	if !ok {
+		return nil, fmt.Errorf("no location for %s", ip)
+	}
+	return &loc, nil
+}
+
+// DistanceKm returns the great-circle distance between two locations
+func DistanceKm(a, b *GeoLocation) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(b.Latitude - a.Latitude)
+	dLon := toRad(b.Longitude - a.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(a.Latitude))*math.Cos(toRad(b.Latitude))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
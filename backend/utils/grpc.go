@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GRPCConfig controls the gRPC server
+type GRPCConfig struct {
+	// Port is the TCP port of the gRPC listener; empty disables gRPC
+	Port string
+	// APIKeys are the keys callers send as "authorization: Bearer <key>".
+	// Every RPC except the health check needs one.
+	APIKeys []string
+	// TLSCertFile and TLSKeyFile serve gRPC over TLS
+	TLSCertFile string
+	TLSKeyFile  string
+	// AllowPlaintext permits serving without TLS, for a listener that is
+	// only reachable through a TLS-terminating proxy
+	AllowPlaintext bool
+	// Reflection lets tools such as grpcurl discover the services
+	Reflection bool
+}
+
+// GetGRPCConfig reads gRPC configuration from environment variables.
+// gRPC and reflection are off unless enabled.
+func GetGRPCConfig() *GRPCConfig {
+	config := &GRPCConfig{
+		Port:        os.Getenv("GRPC_PORT"),
+		TLSCertFile: os.Getenv("GRPC_TLS_CERT_FILE"),
+		TLSKeyFile:  os.Getenv("GRPC_TLS_KEY_FILE"),
+	}
+
+	for _, key := range strings.Split(os.Getenv("GRPC_API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			config.APIKeys = append(config.APIKeys, key)
+		}
+	}
+	if v, err := strconv.ParseBool(os.Getenv("GRPC_ALLOW_PLAINTEXT")); err == nil {
+		config.AllowPlaintext = v
+	}
+	if v, err := strconv.ParseBool(os.Getenv("GRPC_REFLECTION")); err == nil {
+		config.Reflection = v
+	}
+
+	return config
+}
+
+// TLS reports whether the server has a certificate to serve TLS with
+func (c *GRPCConfig) TLS() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// Validate refuses to start an enabled gRPC server that anyone could call
+// or that would carry codes and tokens in plaintext
+func (c *GRPCConfig) Validate() error {
+	if c.Port == "" {
+		return nil
+	}
+	if len(c.APIKeys) == 0 {
+		return fmt.Errorf("GRPC_API_KEYS is required when GRPC_PORT is set")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE must be set together")
+	}
+	if !c.TLS() && !c.AllowPlaintext {
+		return fmt.Errorf("gRPC needs GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE, or GRPC_ALLOW_PLAINTEXT=true behind a TLS proxy")
+	}
+	return nil
+}
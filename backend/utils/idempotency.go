@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/securetoken"
+)
+
+// IdempotencyConfig controls how long responses are kept for replay
+type IdempotencyConfig struct {
+	TTL time.Duration
+	// InFlightTimeout is how long an unfinished request blocks its key.
+	// After that the request is assumed to have crashed and may be retried.
+	InFlightTimeout time.Duration
+	MaxKeyLength    int
+	// MaxBodyBytes caps the request body read to fingerprint the request
+	MaxBodyBytes int64
+}
+
+// GetIdempotencyConfig reads idempotency configuration from environment variables
+func GetIdempotencyConfig() *IdempotencyConfig {
+	config := &IdempotencyConfig{
+		TTL:             24 * time.Hour,
+		InFlightTimeout: time.Minute,
+		MaxKeyLength:    255,
+		MaxBodyBytes:    64 << 10,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_TTL_HOURS")); err == nil && v > 0 {
+		config.TTL = time.Duration(v) * time.Hour
+	}
+
+	return config
+}
+
+// IdempotencyRecordID derives the storage ID for a key sent by a client on
+// a route. client identifies the caller, so two clients that pick the same
+// key never see each other's responses. The key itself is never stored.
+// Header values cannot contain newlines, so the fields cannot run together.
+func IdempotencyRecordID(method, route, client, key string) string {
+	return securetoken.Hash(method + " " + route + "\n" + client + "\n" + key)
+}
+
+// SealIdempotentResponse encrypts a stored response under the server's
+// idempotency secret and the client's key. Responses contain secrets such
+// as status tokens: reading them back needs both the key, which is never
+// stored, and the secret, which is not in the database. recordID is
+// authenticated with the response so it cannot be moved to another record.
+func SealIdempotentResponse(key, recordID string, plaintext []byte) ([]byte, error) {
+	aead, err := idempotencyAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(recordID)), nil
+}
+
+// OpenIdempotentResponse decrypts a response sealed by SealIdempotentResponse
+func OpenIdempotentResponse(key, recordID string, sealed []byte) ([]byte, error) {
+	aead, err := idempotencyAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed response too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(recordID))
+}
+
+var (
+	idempotencySecretOnce sync.Once
+	idempotencySecretKey  []byte
+)
+
+// idempotencySecret returns IDEMPOTENCY_SECRET, or a random secret for
+// this process if it is not set. With a random secret, responses stored
+// before a restart can no longer be replayed.
+func idempotencySecret() []byte {
+	idempotencySecretOnce.Do(func() {
+		if secret := os.Getenv("IDEMPOTENCY_SECRET"); secret != "" {
+			idempotencySecretKey = []byte(secret)
+			return
+		}
+		idempotencySecretKey = make([]byte, 32)
+		rand.Read(idempotencySecretKey)
+	})
+	return idempotencySecretKey
+}
+
+func idempotencyAEAD(key string) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, idempotencySecret())
+	mac.Write([]byte("idempotency-response:" + key))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
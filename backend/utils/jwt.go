@@ -0,0 +1,399 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// DefaultVerificationAudience is the audience of verification tokens when
+// JWT_AUDIENCE is not set
+const DefaultVerificationAudience = "otp-verification"
+
+// TokenConfig holds settings for signed tokens issued by this service
+type TokenConfig struct {
+	Algorithm      string // EdDSA or RS256
+	PrivateKeyFile string
+	KeyID          string
+	Issuer         string
+	// Audience are the services that accept verification tokens. Access
+	// tokens are only for this service and carry the issuer as audience.
+	Audience []string
+	TTL      time.Duration
+}
+
+// GetTokenConfig reads token signing configuration from environment variables
+func GetTokenConfig() *TokenConfig {
+	config := &TokenConfig{
+		Algorithm:      os.Getenv("JWT_ALGORITHM"),
+		PrivateKeyFile: os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		KeyID:          os.Getenv("JWT_KEY_ID"),
+		Issuer:         os.Getenv("JWT_ISSUER"),
+		TTL:            10 * time.Minute,
+	}
+
+	if config.Algorithm == "" {
+		config.Algorithm = "EdDSA"
+	}
+	if config.Issuer == "" {
+		config.Issuer = "otp-verification-system"
+	}
+	for _, aud := range strings.Split(os.Getenv("JWT_AUDIENCE"), ",") {
+		if aud = strings.TrimSpace(aud); aud != "" {
+			config.Audience = append(config.Audience, aud)
+		}
+	}
+	if len(config.Audience) == 0 {
+		config.Audience = []string{DefaultVerificationAudience}
+	}
+	if v, err := strconv.Atoi(os.Getenv("JWT_TTL_MINUTES")); err == nil && v > 0 {
+		config.TTL = time.Duration(v) * time.Minute
+	}
+
+	return config
+}
+
+// TokenSigner signs and verifies JWTs with a single asymmetric key
+type TokenSigner struct {
+	Config *TokenConfig
+	method jwt.SigningMethod
+	key    crypto.Signer
+}
+
+// VerificationClaims are carried by the token VerifyOTP issues
+type VerificationClaims struct {
+	TokenUse   string `json:"token_use"`
+	Channel    string `json:"channel"`
+	Identifier string `json:"identifier"`
+	Purpose    string `json:"purpose,omitempty"`
+	// TransactionHash binds a transaction approval to the approved details
+	TransactionHash string   `json:"txn,omitempty"`
+	AMR             []string `json:"amr"`
+	jwt.RegisteredClaims
+}
+
+// AccessClaims are carried by short-lived session access tokens
+type AccessClaims struct {
+	SessionID string   `json:"sid"`
+	TokenUse  string   `json:"token_use"`
+	AMR       []string `json:"amr"`
+	jwt.RegisteredClaims
+}
+
+// MagicLinkClaims are carried by single-use magic link tokens
+type MagicLinkClaims struct {
+	OTPID    string `json:"otp_id"`
+	TokenUse string `json:"token_use"`
+	jwt.RegisteredClaims
+}
+
+// IDTokenClaims are carried by OpenID Connect id_tokens
+type IDTokenClaims struct {
+	Nonce               string   `json:"nonce,omitempty"`
+	AuthTime            int64    `json:"auth_time"`
+	AMR                 []string `json:"amr"`
+	Email               string   `json:"email,omitempty"`
+	EmailVerified       *bool    `json:"email_verified,omitempty"`
+	PhoneNumber         string   `json:"phone_number,omitempty"`
+	PhoneNumberVerified *bool    `json:"phone_number_verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
+var tokenSigner *TokenSigner
+
+// InitTokenSigner loads the signing key and installs the global signer.
+// Without JWT_PRIVATE_KEY_FILE an ephemeral key is generated, which means
+// tokens stop validating when the process restarts.
+func InitTokenSigner() error {
+	config := GetTokenConfig()
+
+	var key crypto.Signer
+	var err error
+	if config.PrivateKeyFile != "" {
+		key, err = loadPrivateKey(config.PrivateKeyFile)
+	} else {
+		key, err = generatePrivateKey(config.Algorithm)
+	}
+	if err != nil {
+		return err
+	}
+
+	signer, err := NewTokenSigner(config, key)
+	if err != nil {
+		return err
+	}
+
+	tokenSigner = signer
+	return nil
+}
+
+// GetTokenSigner returns the global token signer
+func GetTokenSigner() *TokenSigner {
+	return tokenSigner
+}
+
+// NewTokenSigner creates a signer for an Ed25519 or RSA private key
+func NewTokenSigner(config *TokenConfig, key crypto.Signer) (*TokenSigner, error) {
+	signer := &TokenSigner{Config: config, key: key}
+
+	switch key.(type) {
+	case ed25519.PrivateKey:
+		if config.Algorithm != "EdDSA" {
+			return nil, fmt.Errorf("ed25519 key cannot be used with %s", config.Algorithm)
+		}
+		signer.method = jwt.SigningMethodEdDSA
+	case *rsa.PrivateKey:
+		if config.Algorithm != "RS256" {
+			return nil, fmt.Errorf("rsa key cannot be used with %s", config.Algorithm)
+		}
+		signer.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+
+	if config.KeyID == "" {
+		config.KeyID = keyThumbprint(key.Public())
+	}
+
+	return signer, nil
+}
+
+// IssueVerificationToken signs a token proving the subject verified an OTP.
+// The caller fills the verification details; registered claims are set here.
+func (s *TokenSigner) IssueVerificationToken(subject string, claims VerificationClaims) (string, time.Time, error) {
+	if subject == "" {
+		return "", time.Time{}, fmt.Errorf("verification token needs a subject")
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.Config.TTL)
+
+	claims.TokenUse = "verification"
+	claims.AMR = []string{"otp"}
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Issuer:    s.Config.Issuer,
+		Subject:   subject,
+		Audience:  s.Config.Audience,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	signed, err := s.Sign(claims)
+	return signed, expiresAt, err
+}
+
+// ParseVerificationToken verifies a token issued by IssueVerificationToken
+// for one of the configured audiences and returns its claims
+func (s *TokenSigner) ParseVerificationToken(tokenString string) (*VerificationClaims, error) {
+	if len(s.Config.Audience) == 0 {
+		return nil, fmt.Errorf("no verification token audience configured")
+	}
+	var claims VerificationClaims
+	if err := s.Parse(tokenString, &claims, jwt.WithAudience(s.Config.Audience[0])); err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != "verification" {
+		return nil, fmt.Errorf("not a verification token")
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("verification token has no subject")
+	}
+	return &claims, nil
+}
+
+// IssueAccessToken signs a short-lived access token bound to a session.
+// Its audience is this service, so services that accept verification
+// tokens reject it.
+func (s *TokenSigner) IssueAccessToken(subject, sessionID string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	claims := AccessClaims{
+		SessionID: sessionID,
+		TokenUse:  "access",
+		AMR:       []string{"otp"},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    s.Config.Issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{s.Config.Issuer},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	signed, err := s.Sign(claims)
+	return signed, expiresAt, err
+}
+
+// ParseAccessToken verifies an access token and returns its claims
+func (s *TokenSigner) ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	var claims AccessClaims
+	if err := s.Parse(tokenString, &claims, jwt.WithAudience(s.Config.Issuer)); err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != "access" {
+		return nil, fmt.Errorf("not an access token")
+	}
+	return &claims, nil
+}
+
+// IssueMagicLinkToken signs a token bound to an OTP record. jti is stored
+// on the OTP so the link can only be consumed once.
+func (s *TokenSigner) IssueMagicLinkToken(otpID, jti string, expiresAt time.Time) (string, error) {
+	claims := MagicLinkClaims{
+		OTPID:    otpID,
+		TokenUse: "magic_link",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    s.Config.Issuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	return s.Sign(claims)
+}
+
+// ParseMagicLinkToken verifies a magic link token and returns its claims
+func (s *TokenSigner) ParseMagicLinkToken(tokenString string) (*MagicLinkClaims, error) {
+	var claims MagicLinkClaims
+	if err := s.Parse(tokenString, &claims); err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != "magic_link" {
+		return nil, fmt.Errorf("not a magic link token")
+	}
+	return &claims, nil
+}
+
+// IssueIDToken signs an OpenID Connect id_token for a client. The issuer is
+// the provider's public URL rather than JWT_ISSUER, as OIDC requires.
+func (s *TokenSigner) IssueIDToken(issuer, subject, clientID string, claims IDTokenClaims) (string, error) {
+	now := time.Now()
+
+	claims.AMR = []string{"otp"}
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{clientID},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(s.Config.TTL)),
+	}
+
+	return s.Sign(claims)
+}
+
+// Sign signs arbitrary claims with the service key
+func (s *TokenSigner) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header["kid"] = s.Config.KeyID
+	return token.SignedString(s.key)
+}
+
+// Parse verifies a token signed by this service and fills claims. opts
+// add checks such as the expected audience.
+func (s *TokenSigner) Parse(tokenString string, claims jwt.Claims, opts ...jwt.ParserOption) error {
+	opts = append([]jwt.ParserOption{
+		jwt.WithValidMethods([]string{s.method.Alg()}),
+		jwt.WithIssuer(s.Config.Issuer),
+	}, opts...)
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return s.key.Public(), nil
+	}, opts...)
+	return err
+}
+
+// JWKS returns the public key set for /.well-known/jwks.json
+func (s *TokenSigner) JWKS() map[string]interface{} {
+	return map[string]interface{}{
+		"keys": []map[string]string{s.publicJWK()},
+	}
+}
+
+func (s *TokenSigner) publicJWK() map[string]string {
+	jwk := map[string]string{
+		"kid": s.Config.KeyID,
+		"use": "sig",
+		"alg": s.method.Alg(),
+	}
+
+	switch pub := s.key.Public().(type) {
+	case ed25519.PublicKey:
+		jwk["kty"] = "OKP"
+		jwk["crv"] = "Ed25519"
+		jwk["x"] = base64.RawURLEncoding.EncodeToString(pub)
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	}
+
+	return jwk
+}
+
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key is not PEM encoded")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %v", err)
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key type %T", parsed)
+	}
+	return signer, nil
+}
+
+func generatePrivateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case "RS256":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", algorithm)
+	}
+}
+
+// keyThumbprint derives a stable key ID from the public key
+func keyThumbprint(pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return uuid.New().String()
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestSigner(t *testing.T, audience ...string) *TokenSigner {
+	t.Helper()
+	t.Setenv("JWT_AUDIENCE", "")
+	config := GetTokenConfig()
+	if len(audience) > 0 {
+		config.Audience = audience
+	}
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := NewTokenSigner(config, key)
+	if err != nil {
+		t.Fatalf("NewTokenSigner: %v", err)
+	}
+	return signer
+}
+
+// A verification token must not work as an access token, or the other way
+// round, even though both are signed with the same key
+func TestTokensAreNotInterchangeable(t *testing.T) {
+	signer := newTestSigner(t)
+
+	verification, _, err := signer.IssueVerificationToken("user-1", VerificationClaims{Channel: "email", Identifier: "user@example.com"})
+	if err != nil {
+		t.Fatalf("IssueVerificationToken: %v", err)
+	}
+	access, _, err := signer.IssueAccessToken("user-1", "session-1", signer.Config.TTL)
+	if err != nil {
+		t.Fatalf("IssueAccessToken: %v", err)
+	}
+
+	claims, err := signer.ParseVerificationToken(verification)
+	if err != nil {
+		t.Fatalf("ParseVerificationToken: %v", err)
+	}
+	if claims.TokenUse != "verification" || len(claims.Audience) != 1 || claims.Audience[0] != DefaultVerificationAudience {
+		t.Errorf("verification token claims: token_use %q, aud %v", claims.TokenUse, claims.Audience)
+	}
+	if _, err := signer.ParseAccessToken(access); err != nil {
+		t.Errorf("ParseAccessToken: %v", err)
+	}
+
+	if _, err := signer.ParseAccessToken(verification); err == nil {
+		t.Error("verification token accepted as an access token")
+	}
+	if _, err := signer.ParseVerificationToken(access); err == nil {
+		t.Error("access token accepted as a verification token")
+	}
+}
+
+func TestVerificationTokenAudience(t *testing.T) {
+	issuing := newTestSigner(t, "service-a")
+	other := &TokenSigner{Config: &TokenConfig{Issuer: issuing.Config.Issuer, Audience: []string{"service-b"}}, method: issuing.method, key: issuing.key}
+
+	token, _, err := issuing.IssueVerificationToken("user-1", VerificationClaims{Channel: "sms", Identifier: "+15550000001"})
+	if err != nil {
+		t.Fatalf("IssueVerificationToken: %v", err)
+	}
+	if _, err := issuing.ParseVerificationToken(token); err != nil {
+		t.Errorf("intended audience rejected the token: %v", err)
+	}
+	if _, err := other.ParseVerificationToken(token); err == nil {
+		t.Error("token accepted by another audience")
+	}
+}
+
+// Downstream services may only check the signature and token_use, so a
+// token must always name the user it verified
+func TestVerificationTokenNeedsSubject(t *testing.T) {
+	signer := newTestSigner(t)
+
+	if _, _, err := signer.IssueVerificationToken("", VerificationClaims{Channel: "email", Identifier: "user@example.com"}); err == nil {
+		t.Error("token issued without a subject")
+	}
+
+	now := time.Now()
+	token, err := signer.Sign(VerificationClaims{
+		Channel:  "email",
+		TokenUse: "verification",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    signer.Config.Issuer,
+			Audience:  signer.Config.Audience,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := signer.ParseVerificationToken(token); err == nil {
+		t.Error("token without a subject accepted")
+	}
+}
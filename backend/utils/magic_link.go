@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+// MagicLinkConfig holds settings for email magic links
+type MagicLinkConfig struct {
+	BaseURL           string   // public URL of this API, used to build links
+	DefaultReturnURL  string   // where users land when no return URL is given
+	AllowedReturnURLs []string // allowed return URL prefixes
+}
+
+// GetMagicLinkConfig reads magic link configuration from environment variables
+func GetMagicLinkConfig() *MagicLinkConfig {
+	config := &MagicLinkConfig{
+		BaseURL:          strings.TrimRight(os.Getenv("MAGIC_LINK_BASE_URL"), "/"),
+		DefaultReturnURL: os.Getenv("MAGIC_LINK_RETURN_URL"),
+	}
+
+	if config.BaseURL == "" {
+		config.BaseURL = "http://localhost:8080"
+	}
+	if config.DefaultReturnURL == "" {
+		config.DefaultReturnURL = "http://localhost:5173/"
+	}
+	for _, u := range strings.Split(os.Getenv("MAGIC_LINK_ALLOWED_RETURN_URLS"), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			config.AllowedReturnURLs = append(config.AllowedReturnURLs, u)
+		}
+	}
+	config.AllowedReturnURLs = append(config.AllowedReturnURLs, config.DefaultReturnURL)
+
+	return config
+}
+
+// IsAllowedReturnURL reports whether u has the scheme and host of an
+// allowed URL and a path at or below its path, preventing open redirects
+func (c *MagicLinkConfig) IsAllowedReturnURL(u string) bool {
+	target, err := url.Parse(u)
+	if err != nil || target.Host == "" || target.User != nil || hasDotSegments(target.Path) {
+		return false
+	}
+
+	for _, allowed := range c.AllowedReturnURLs {
+		prefix, err := url.Parse(allowed)
+		if err != nil {
+			continue
+		}
+		if target.Scheme == prefix.Scheme &&
+			strings.EqualFold(target.Host, prefix.Host) &&
+			pathWithin(target.Path, prefix.Path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pathWithin reports whether p is base or below it, matching whole segments
+// so that "/app" allows "/app/done" but not "/app-evil"
+func pathWithin(p, base string) bool {
+	base = strings.TrimSuffix(base, "/")
+	return p == base || strings.HasPrefix(p, base+"/")
+}
+
+// hasDotSegments reports whether a browser could resolve the path to a
+// different directory than it appears to name
+func hasDotSegments(p string) bool {
+	if strings.Contains(p, `\`) {
+		return true
+	}
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkURL builds the link that consumes a magic link token
+func (c *MagicLinkConfig) LinkURL(token string) string {
+	return c.BaseURL + "/api/otp/magic?token=" + url.QueryEscape(token)
+}
+
+// ReturnURLWith appends query parameters to a return URL
+func ReturnURLWith(returnURL string, params map[string]string) string {
+	target, err := url.Parse(returnURL)
+	if err != nil {
+		return returnURL
+	}
+	query := target.Query()
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+func TestIsAllowedReturnURL(t *testing.T) {
+	c := &MagicLinkConfig{AllowedReturnURLs: []string{"https://app.example.com/app", "http://localhost:5173/"}}
+
+	for u, want := range map[string]bool{
+		"https://app.example.com/app":              true,
+		"https://app.example.com/app/":             true,
+		"https://app.example.com/app/done?x=1":     true,
+		"https://APP.example.com/app/done":         true,
+		"http://localhost:5173/":                   true,
+		"http://localhost:5173/anything":           true,
+		"https://app.example.com/app-evil":         false,
+		"https://app.example.com/application":      false,
+		"https://app.example.com/":                 false,
+		"https://app.example.com/app/../admin":     false,
+		"https://app.example.com/app/%2e%2e/admin": false,
+		`https://app.example.com/app\..\admin`:     false,
+		"http://app.example.com/app":               false,
+		"https://evil.com/app":                     false,
+		"https://app.example.com@evil.com/app":     false,
+		"https://user@app.example.com/app":         false,
+		"//app.example.com/app":                    false,
+		"/app":                                     false,
+	} {
+		if got := c.IsAllowedReturnURL(u); got != want {
+			t.Errorf("IsAllowedReturnURL(%q) = %t, want %t", u, got, want)
+		}
+	}
+}
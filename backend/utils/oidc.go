@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"os"
+	"strings"
+	"time"
+)
+
+// OIDCConfig holds settings for OpenID Connect provider mode
+type OIDCConfig struct {
+	Issuer     string // public URL of this service; endpoints live below it
+	CodeTTL    time.Duration
+	RequestTTL time.Duration
+}
+
+// Scopes this provider understands
+var SupportedScopes = []string{"openid", "email", "phone"}
+
+// GetOIDCConfig reads OpenID Connect configuration from environment variables
+func GetOIDCConfig() *OIDCConfig {
+	config := &OIDCConfig{
+		Issuer:     strings.TrimRight(os.Getenv("OIDC_ISSUER"), "/"),
+		CodeTTL:    time.Minute,
+		RequestTTL: 10 * time.Minute,
+	}
+	if config.Issuer == "" {
+		config.Issuer = "http://localhost:8080"
+	}
+	return config
+}
+
+// VerifyPKCE checks an RFC 7636 S256 code verifier against its challenge
+func VerifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// HasScope reports whether a space-separated scope string contains scope
+func HasScope(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterScopes drops scopes this provider does not support
+func FilterScopes(scopes string) string {
+	var kept []string
+	for _, s := range strings.Fields(scopes) {
+		for _, supported := range SupportedScopes {
+			if s == supported {
+				kept = append(kept, s)
+				break
+			}
+		}
+	}
+	return strings.Join(kept, " ")
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI 3.0 schema object
+type Schema map[string]interface{}
+
+// SchemaRegistry builds OpenAPI schemas from Go types. Named struct types
+// are stored once under components/schemas and referenced with $ref, so
+// the document always matches the types the handlers bind and return.
+type SchemaRegistry struct {
+	Schemas map[string]Schema
+}
+
+// NewSchemaRegistry returns an empty registry
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{Schemas: map[string]Schema{}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Ref returns a schema for the type of v, registering named structs
+func (r *SchemaRegistry) Ref(v interface{}) Schema {
+	return r.schemaFor(reflect.TypeOf(v))
+}
+
+func (r *SchemaRegistry) schemaFor(t reflect.Type) Schema {
+	if t == nil {
+		return Schema{}
+	}
+
+	switch {
+	case t == timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Pointer:
+		schema := r.schemaFor(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return Schema{"allOf": []Schema{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := r.Schemas[t.Name()]; !ok {
+			r.Schemas[t.Name()] = Schema{} // placeholder for recursive types
+			r.Schemas[t.Name()] = r.structSchema(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + t.Name()}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return r.structSchema(t)
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": r.schemaFor(t.Elem())}
+	default:
+		// interface{} and anything else: any value
+		return Schema{}
+	}
+}
+
+// structSchema describes a struct from its json, binding and enum tags.
+// Embedded structs without a json name are flattened, as encoding/json does.
+func (r *SchemaRegistry) structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	var required []string
+
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || (!field.IsExported() && !field.Anonymous) {
+				continue
+			}
+			if name == "" && field.Anonymous {
+				embedded := field.Type
+				if embedded.Kind() == reflect.Pointer {
+					embedded = embedded.Elem()
+				}
+				if embedded.Kind() == reflect.Struct {
+					addFields(embedded)
+					continue
+				}
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			schema := r.schemaFor(field.Type)
+			if applyBindingRules(schema, field.Tag.Get("binding")) {
+				required = append(required, name)
+			}
+			// Response types list their values in an enum tag
+			if enum := field.Tag.Get("enum"); enum != "" {
+				schema["enum"] = strings.Split(enum, ",")
+			}
+			properties[name] = schema
+		}
+	}
+	addFields(t)
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// applyBindingRules maps gin binding (validator) rules onto the schema and
+// reports whether the field is required
+func applyBindingRules(schema Schema, binding string) bool {
+	if binding == "" {
+		return false
+	}
+	// Rules cannot be added next to a $ref in OpenAPI 3.0
+	if _, isRef := schema["$ref"]; isRef {
+		return strings.Contains(","+binding+",", ",required,")
+	}
+
+	isString := schema["type"] == "string"
+	limit := func(keyword string, param string) {
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		if isString {
+			schema[keyword+"Length"] = n
+		} else if keyword == "min" {
+			schema["minimum"] = n
+		} else {
+			schema["maximum"] = n
+		}
+	}
+
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema["format"] = "email"
+		case "url":
+			schema["format"] = "uri"
+		case "ip":
+			schema["format"] = "ip"
+		case "min", "max":
+			limit(name, param)
+		case "len":
+			limit("min", param)
+			limit("max", param)
+		case "oneof":
+			schema["enum"] = strings.Fields(param)
+		}
+	}
+	return required
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+)
+
+// GetOTPPolicy returns the engine's policy for a purpose. Each field can be
+// overridden with OTP_<PURPOSE>_LENGTH, _FORMAT, _GROUP_SIZE,
+// _EXPIRY_MINUTES, _MAX_ATTEMPTS, _TEMPLATE and _RESEND_COOLDOWN_SECONDS
+// environment variables.
+func GetOTPPolicy(purpose string) (*engine.OTPPolicy, error) {
+	policy, err := engine.DefaultPolicy(purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := "OTP_" + strings.ToUpper(policy.Purpose) + "_"
+	if v, err := strconv.Atoi(os.Getenv(prefix + "LENGTH")); err == nil && v >= 4 && v <= 16 {
+		policy.Length = v
+	}
+	if v := strings.ToLower(os.Getenv(prefix + "FORMAT")); v == engine.FormatNumeric || v == engine.FormatAlphanumeric {
+		policy.Format = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(prefix + "GROUP_SIZE")); err == nil && v >= 0 {
+		policy.GroupSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(prefix + "EXPIRY_MINUTES")); err == nil && v > 0 {
+		policy.Expiry = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv(prefix + "MAX_ATTEMPTS")); err == nil && v > 0 {
+		policy.MaxAttempts = v
+	}
+	if v := os.Getenv(prefix + "TEMPLATE"); v != "" {
+		policy.Template = strings.ReplaceAll(v, `\n`, "\n")
+	}
+	if v, err := strconv.Atoi(os.Getenv(prefix + "RESEND_COOLDOWN_SECONDS")); err == nil && v >= 0 {
+		policy.ResendCooldown = time.Duration(v) * time.Second
+	}
+
+	return policy, nil
+}
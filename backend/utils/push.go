@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Push approval decisions a device can sign
+const (
+	PushApprove = "approve"
+	PushDeny    = "deny"
+)
+
+// PushClockSkew is how far a device's request timestamp may drift
+const PushClockSkew = 5 * time.Minute
+
+// ParsePushPublicKey decodes a base64 (standard or URL-safe) Ed25519 public key
+func ParsePushPublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		raw, err = base64.RawURLEncoding.DecodeString(encoded)
+	}
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be a base64 encoded %d-byte Ed25519 key", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// EncodePushPublicKey returns the canonical base64 form of a public key
+func EncodePushPublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// PushChallengeMessage is what a device signs to answer a challenge
+func PushChallengeMessage(challengeID, nonce, decision string) []byte {
+	return []byte("push-challenge:v1\n" + challengeID + "\n" + nonce + "\n" + decision)
+}
+
+// PushFetchMessage is what a device signs to list its pending challenges
+func PushFetchMessage(deviceID string, timestamp int64) []byte {
+	return []byte("push-fetch:v1\n" + deviceID + "\n" + strconv.FormatInt(timestamp, 10))
+}
+
+// VerifyPushSignature checks a base64url signature made by the device key
+func VerifyPushSignature(publicKey, signature string, message []byte) bool {
+	key, err := ParsePushPublicKey(publicKey)
+	if err != nil {
+		return false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(key, message, sig)
+}
+
+// SignPushMessage signs a message as a device would, base64url encoded.
+// Useful for device SDKs and test clients written in Go.
+func SignPushMessage(privateKey ed25519.PrivateKey, message []byte) string {
+	return base64.RawURLEncoding.EncodeToString(ed25519.Sign(privateKey, message))
+}
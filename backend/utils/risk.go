@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+)
+
+// RiskConfig holds settings for step-up decisions
+type RiskConfig struct {
+	Threshold      int      // challenge when the total score reaches this
+	StepUpActions  []string // actions that always require an OTP
+	MaxTravelKmh   float64  // faster implied travel is impossible
+	FailedAttempts int      // failed OTP attempts in 24h that count as risky
+	GeoIPFile      string
+}
+
+// GetRiskConfig reads risk engine configuration from environment variables
+func GetRiskConfig() *RiskConfig {
+	config := &RiskConfig{
+		Threshold:      50,
+		StepUpActions:  []string{engine.PurposeTransaction, engine.PurposePasswordReset},
+		MaxTravelKmh:   900,
+		FailedAttempts: 3,
+		GeoIPFile:      os.Getenv("GEOIP_DATABASE_FILE"),
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("RISK_THRESHOLD")); err == nil && v > 0 {
+		config.Threshold = v
+	}
+	if v, ok := os.LookupEnv("RISK_STEP_UP_ACTIONS"); ok {
+		config.StepUpActions = nil
+		for _, action := range strings.Split(v, ",") {
+			if action = strings.TrimSpace(action); action != "" {
+				config.StepUpActions = append(config.StepUpActions, action)
+			}
+		}
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("RISK_MAX_TRAVEL_KMH"), 64); err == nil && v > 0 {
+		config.MaxTravelKmh = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("RISK_FAILED_ATTEMPTS")); err == nil && v > 0 {
+		config.FailedAttempts = v
+	}
+
+	return config
+}
+
+// PastSignIn is a previous sign-in used to judge the current request
+type PastSignIn struct {
+	At       time.Time
+	IP       string
+	Location *GeoLocation
+}
+
+// RiskContext is everything the rules know about a request
+type RiskContext struct {
+	UserID    string
+	IP        string
+	UserAgent string
+	Action    string
+	Now       time.Time
+
+	// Location of IP, nil when unknown
+	Location *GeoLocation
+	// KnownDevice is true when a valid trusted device token was presented
+	KnownDevice bool
+	// History holds recent sign-ins, newest first
+	History []PastSignIn
+	// FailedAttempts counts wrong OTP guesses in the last 24 hours
+	FailedAttempts int
+}
+
+// RiskReason explains why a rule contributed to the score
+type RiskReason struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail"`
+}
+
+// RiskRule scores one aspect of a request. It returns ok=false when it
+// has nothing to say.
+type RiskRule interface {
+	Name() string
+	Evaluate(ctx *RiskContext) (score int, detail string, ok bool)
+}
+
+// RiskDecision is the outcome of evaluating every rule
+type RiskDecision struct {
+	ChallengeRequired bool         `json:"challenge_required"`
+	Score             int          `json:"score"`
+	Threshold         int          `json:"threshold"`
+	Reasons           []RiskReason `json:"reasons"`
+}
+
+// RiskEngine sums rule scores and challenges above a threshold
+type RiskEngine struct {
+	Threshold int
+	rules     []RiskRule
+}
+
+// NewRiskEngine builds an engine with the default rules
+func NewRiskEngine(config *RiskConfig) *RiskEngine {
+	risk := &RiskEngine{Threshold: config.Threshold}
+	risk.AddRule(StepUpActionRule{Actions: config.StepUpActions})
+	// An untrusted device is challenged on its own, also without GeoIP
+	risk.AddRule(NewDeviceRule{Score: config.Threshold})
+	risk.AddRule(NewCountryRule{})
+	risk.AddRule(ImpossibleTravelRule{MaxSpeedKmh: config.MaxTravelKmh})
+	risk.AddRule(FailedAttemptsRule{Limit: config.FailedAttempts})
+	return risk
+}
+
+// AddRule plugs a rule into the engine
+func (e *RiskEngine) AddRule(rule RiskRule) {
+	e.rules = append(e.rules, rule)
+}
+
+// Evaluate runs every rule against the context
+func (e *RiskEngine) Evaluate(ctx *RiskContext) *RiskDecision {
+	decision := &RiskDecision{Threshold: e.Threshold, Reasons: []RiskReason{}}
+
+	for _, rule := range e.rules {
+		score, detail, ok := rule.Evaluate(ctx)
+		if !ok {
+			continue
+		}
+		decision.Score += score
+		decision.Reasons = append(decision.Reasons, RiskReason{Rule: rule.Name(), Score: score, Detail: detail})
+	}
+
+	decision.ChallengeRequired = decision.Score >= e.Threshold
+	return decision
+}
+
+// StepUpActionRule always challenges sensitive actions
+type StepUpActionRule struct {
+	Actions []string
+}
+
+func (StepUpActionRule) Name() string { return "step_up_action" }
+
+func (r StepUpActionRule) Evaluate(ctx *RiskContext) (int, string, bool) {
+	for _, action := range r.Actions {
+		if action == ctx.Action {
+			return 100, fmt.Sprintf("action %q always requires an OTP", ctx.Action), true
+		}
+	}
+	return 0, "", false
+}
+
+// NewDeviceRule scores requests without a trusted device token
+type NewDeviceRule struct {
+	Score int
+}
+
+func (NewDeviceRule) Name() string { return "new_device" }
+
+func (r NewDeviceRule) Evaluate(ctx *RiskContext) (int, string, bool) {
+	if ctx.KnownDevice {
+		return 0, "", false
+	}
+	return r.Score, "request is not from a trusted device", true
+}
+
+// NewCountryRule scores requests from a country the user has not signed in from
+type NewCountryRule struct{}
+
+func (NewCountryRule) Name() string { return "new_country" }
+
+func (NewCountryRule) Evaluate(ctx *RiskContext) (int, string, bool) {
+	if ctx.Location == nil {
+		return 0, "", false
+	}
+
+	located := false
+	for _, past := range ctx.History {
+		if past.Location == nil {
+			continue
+		}
+		located = true
+		if past.Location.Country == ctx.Location.Country {
+			return 0, "", false
+		}
+	}
+	if !located {
+		return 0, "", false
+	}
+
+	return 40, fmt.Sprintf("first sign-in from %s", ctx.Location.Country), true
+}
+
+// ImpossibleTravelRule scores requests that imply travelling faster than
+// MaxSpeedKmh since the last sign-in
+type ImpossibleTravelRule struct {
+	MaxSpeedKmh float64
+}
+
+func (ImpossibleTravelRule) Name() string { return "impossible_travel" }
+
+func (r ImpossibleTravelRule) Evaluate(ctx *RiskContext) (int, string, bool) {
+	if ctx.Location == nil {
+		return 0, "", false
+	}
+
+	for _, past := range ctx.History {
+		if past.Location == nil {
+			continue
+		}
+
+		distance := DistanceKm(past.Location, ctx.Location)
+		hours := ctx.Now.Sub(past.At).Hours()
+		// Nearby locations are within GeoIP accuracy
+		if distance < 100 {
+			return 0, "", false
+		}
+		if hours <= 0 || distance/hours > r.MaxSpeedKmh {
+			return 80, fmt.Sprintf("%.0f km from the last sign-in in %s, %s ago",
+				distance, past.Location.Country, ctx.Now.Sub(past.At).Round(time.Minute)), true
+		}
+		return 0, "", false
+	}
+
+	return 0, "", false
+}
+
+// FailedAttemptsRule scores users with many recent wrong OTP guesses
+type FailedAttemptsRule struct {
+	Limit int
+}
+
+func (FailedAttemptsRule) Name() string { return "failed_attempts" }
+
+func (r FailedAttemptsRule) Evaluate(ctx *RiskContext) (int, string, bool) {
+	if ctx.FailedAttempts < r.Limit {
+		return 0, "", false
+	}
+	return 40, fmt.Sprintf("%d failed OTP attempts in the last 24 hours", ctx.FailedAttempts), true
+}
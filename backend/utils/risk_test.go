@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	london = &GeoLocation{Country: "GB", Latitude: 51.51, Longitude: -0.13}
+	paris  = &GeoLocation{Country: "FR", Latitude: 48.86, Longitude: 2.35}
+	sydney = &GeoLocation{Country: "AU", Latitude: -33.87, Longitude: 151.21}
+	slough = &GeoLocation{Country: "GB", Latitude: 51.51, Longitude: -0.59}
+)
+
+func TestRiskRules(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	inLondon := []PastSignIn{{At: now.Add(-2 * time.Hour), Location: london}}
+
+	for _, tt := range []struct {
+		name string
+		rule RiskRule
+		ctx  RiskContext
+		want int // score, or -1 when the rule has nothing to say
+	}{
+		{"step-up action", StepUpActionRule{Actions: []string{"transaction"}}, RiskContext{Action: "transaction"}, 100},
+		{"other action", StepUpActionRule{Actions: []string{"transaction"}}, RiskContext{Action: "login"}, -1},
+
+		{"untrusted device", NewDeviceRule{Score: 50}, RiskContext{}, 50},
+		{"trusted device", NewDeviceRule{Score: 50}, RiskContext{KnownDevice: true}, -1},
+
+		{"new country", NewCountryRule{}, RiskContext{Location: paris, History: inLondon}, 40},
+		{"known country", NewCountryRule{}, RiskContext{Location: slough, History: inLondon}, -1},
+		{"country without location", NewCountryRule{}, RiskContext{History: inLondon}, -1},
+		{"country without history", NewCountryRule{}, RiskContext{Location: paris}, -1},
+
+		{"impossible travel", ImpossibleTravelRule{MaxSpeedKmh: 900}, RiskContext{Now: now, Location: sydney, History: inLondon}, 80},
+		{"possible travel", ImpossibleTravelRule{MaxSpeedKmh: 900}, RiskContext{Now: now, Location: paris, History: inLondon}, -1},
+		{"travel within GeoIP accuracy", ImpossibleTravelRule{MaxSpeedKmh: 900}, RiskContext{Now: now, Location: slough, History: inLondon}, -1},
+		{"travel without location", ImpossibleTravelRule{MaxSpeedKmh: 900}, RiskContext{Now: now, History: inLondon}, -1},
+
+		{"failed attempts at limit", FailedAttemptsRule{Limit: 3}, RiskContext{FailedAttempts: 3}, 40},
+		{"failed attempts below limit", FailedAttemptsRule{Limit: 3}, RiskContext{FailedAttempts: 2}, -1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			score, detail, ok := tt.rule.Evaluate(&tt.ctx)
+			switch {
+			case tt.want < 0 && ok:
+				t.Errorf("scored %d (%s), want nothing", score, detail)
+			case tt.want >= 0 && (!ok || score != tt.want):
+				t.Errorf("got %d, %t, want %d", score, ok, tt.want)
+			case ok && detail == "":
+				t.Error("score without detail")
+			}
+		})
+	}
+}
+
+func TestRiskDecision(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	inLondon := []PastSignIn{{At: now.Add(-2 * time.Hour), Location: london}}
+	risk := NewRiskEngine(&RiskConfig{
+		Threshold:      50,
+		StepUpActions:  []string{"transaction"},
+		MaxTravelKmh:   900,
+		FailedAttempts: 3,
+	})
+
+	for _, tt := range []struct {
+		name      string
+		ctx       RiskContext
+		challenge bool
+		rules     []string
+	}{
+		{"trusted device at home", RiskContext{Action: "login", KnownDevice: true, Location: london, History: inLondon}, false, nil},
+		// Without GeoIP the device is all there is to go on
+		{"untrusted device without location", RiskContext{Action: "login"}, true, []string{"new_device"}},
+		{"trusted device, new country", RiskContext{Action: "login", KnownDevice: true, Location: paris, History: inLondon}, false, []string{"new_country"}},
+		{"trusted device, new country and failed attempts", RiskContext{Action: "login", KnownDevice: true, Location: paris, History: inLondon, FailedAttempts: 3}, true, []string{"new_country", "failed_attempts"}},
+		{"trusted device, impossible travel", RiskContext{Action: "login", KnownDevice: true, Location: sydney, History: inLondon}, true, []string{"new_country", "impossible_travel"}},
+		{"trusted device, step-up action", RiskContext{Action: "transaction", KnownDevice: true}, true, []string{"step_up_action"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ctx.Now = now
+			decision := risk.Evaluate(&tt.ctx)
+			if decision.ChallengeRequired != tt.challenge {
+				t.Errorf("challenge %t with score %d, want %t", decision.ChallengeRequired, decision.Score, tt.challenge)
+			}
+			if len(decision.Reasons) != len(tt.rules) {
+				t.Fatalf("reasons %+v, want rules %v", decision.Reasons, tt.rules)
+			}
+			total := 0
+			for i, reason := range decision.Reasons {
+				if reason.Rule != tt.rules[i] {
+					t.Errorf("reason %d is %s, want %s", i, reason.Rule, tt.rules[i])
+				}
+				total += reason.Score
+			}
+			if decision.Score != total || decision.Threshold != 50 {
+				t.Errorf("score %d of threshold %d, want %d of 50", decision.Score, decision.Threshold, total)
+			}
+		})
+	}
+}
+
+// Raising the threshold must not let untrusted devices through
+func TestUntrustedDeviceMeetsConfiguredThreshold(t *testing.T) {
+	risk := NewRiskEngine(&RiskConfig{Threshold: 90, MaxTravelKmh: 900, FailedAttempts: 3})
+	if decision := risk.Evaluate(&RiskContext{Action: "login"}); !decision.ChallengeRequired {
+		t.Errorf("untrusted device not challenged: %+v", decision)
+	}
+}
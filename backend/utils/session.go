@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// SessionConfig holds lifetimes for session tokens
+type SessionConfig struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// GetSessionConfig reads session configuration from environment variables
+func GetSessionConfig() *SessionConfig {
+	config := &SessionConfig{
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: 30 * 24 * time.Hour,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("SESSION_ACCESS_TTL_MINUTES")); err == nil && v > 0 {
+		config.AccessTTL = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv("SESSION_REFRESH_TTL_DAYS")); err == nil && v > 0 {
+		config.RefreshTTL = time.Duration(v) * 24 * time.Hour
+	}
+
+	return config
+}
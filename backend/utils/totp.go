@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// AuthenticatorConfig holds settings for authenticator-app second factors
+type AuthenticatorConfig struct {
+	Issuer        string
+	Digits        int
+	Period        int // TOTP step in seconds
+	Skew          int // TOTP steps accepted either side of the current one
+	HOTPLookahead int // HOTP counters accepted ahead of the stored one
+	ResyncWindow  int // HOTP counters searched during resync
+}
+
+// GetAuthenticatorConfig reads authenticator configuration from environment variables
+func GetAuthenticatorConfig() *AuthenticatorConfig {
+	config := &AuthenticatorConfig{
+		Issuer:        os.Getenv("TOTP_ISSUER"),
+		Digits:        6,
+		Period:        30,
+		Skew:          1,
+		HOTPLookahead: 10,
+		ResyncWindow:  100,
+	}
+
+	if config.Issuer == "" {
+		config.Issuer = "OTP Verification System"
+	}
+	if v, err := strconv.Atoi(os.Getenv("TOTP_SKEW")); err == nil && v >= 0 && v <= 10 {
+		config.Skew = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("HOTP_LOOKAHEAD")); err == nil && v >= 0 {
+		config.HOTPLookahead = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("HOTP_RESYNC_WINDOW")); err == nil && v > 0 {
+		config.ResyncWindow = v
+	}
+
+	return config
+}
+
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateAuthenticatorSecret returns a random 160-bit secret, base32 encoded
+func GenerateAuthenticatorSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base32NoPad.EncodeToString(secret), nil
+}
+
+// DecodeAuthenticatorSecret decodes a base32 secret, tolerating padding,
+// spaces and lower case
+func DecodeAuthenticatorSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return base32NoPad.DecodeString(strings.TrimRight(secret, "="))
+}
+
+// HOTP computes an RFC 4226 code for the counter
+func HOTP(secret []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// TOTPStep returns the RFC 6238 time step for t
+func TOTPStep(t time.Time, period int) int64 {
+	return t.Unix() / int64(period)
+}
+
+// MatchTOTP checks a code against the steps around now and returns the
+// matched step. Steps at or before lastUsedStep are rejected so a code can
+// only be used once.
+func MatchTOTP(secret []byte, code string, now time.Time, config *AuthenticatorConfig, lastUsedStep int64) (int64, bool) {
+	current := TOTPStep(now, config.Period)
+
+	for offset := -config.Skew; offset <= config.Skew; offset++ {
+		step := current + int64(offset)
+		if step <= lastUsedStep {
+			continue
+		}
+		if codesEqual(HOTP(secret, uint64(step), config.Digits), code) {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// MatchHOTP checks a code against counter..counter+lookahead and returns
+// the next counter to store
+func MatchHOTP(secret []byte, code string, counter uint64, digits, lookahead int) (uint64, bool) {
+	for i := 0; i <= lookahead; i++ {
+		if codesEqual(HOTP(secret, counter+uint64(i), digits), code) {
+			return counter + uint64(i) + 1, true
+		}
+	}
+	return counter, false
+}
+
+// ResyncHOTP searches for two consecutive codes within the window and
+// returns the counter following the second one
+func ResyncHOTP(secret []byte, code1, code2 string, counter uint64, digits, window int) (uint64, bool) {
+	for i := 0; i < window; i++ {
+		c := counter + uint64(i)
+		if codesEqual(HOTP(secret, c, digits), code1) && codesEqual(HOTP(secret, c+1, digits), code2) {
+			return c + 2, true
+		}
+	}
+	return counter, false
+}
+
+// OTPAuthURI builds the otpauth:// URI understood by authenticator apps
+func OTPAuthURI(kind, issuer, account, secret string, digits, period int, counter uint64) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", strconv.Itoa(digits))
+	if kind == "hotp" {
+		params.Set("counter", strconv.FormatUint(counter, 10))
+	} else {
+		params.Set("period", strconv.Itoa(period))
+	}
+
+	label := url.PathEscape(issuer + ":" + account)
+	return fmt.Sprintf("otpauth://%s/%s?%s", kind, label, params.Encode())
+}
+
+// QRCodePNG renders content as a PNG QR code
+func QRCodePNG(content string, size int) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, size)
+}
+
+func codesEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
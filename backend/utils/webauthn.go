@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// WebAuthnCeremonyTTL is how long a passkey ceremony may take
+const WebAuthnCeremonyTTL = 5 * time.Minute
+
+// NewWebAuthn builds the relying party from WEBAUTHN_RP_ID,
+// WEBAUTHN_RP_NAME and WEBAUTHN_RP_ORIGINS
+func NewWebAuthn() (*webauthn.WebAuthn, error) {
+	config := &webauthn.Config{
+		RPID:          os.Getenv("WEBAUTHN_RP_ID"),
+		RPDisplayName: os.Getenv("WEBAUTHN_RP_NAME"),
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: WebAuthnCeremonyTTL, TimeoutUVD: WebAuthnCeremonyTTL},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: WebAuthnCeremonyTTL, TimeoutUVD: WebAuthnCeremonyTTL},
+		},
+	}
+
+	if config.RPID == "" {
+		config.RPID = "localhost"
+	}
+	if config.RPDisplayName == "" {
+		config.RPDisplayName = "OTP Verification System"
+	}
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_RP_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.RPOrigins = append(config.RPOrigins, origin)
+		}
+	}
+	if len(config.RPOrigins) == 0 {
+		config.RPOrigins = []string{"http://localhost:5173", "http://localhost:3000"}
+	}
+
+	return webauthn.New(config)
+}
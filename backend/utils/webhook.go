@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every webhook request
+const (
+	WebhookIDHeader        = "X-Webhook-ID"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookConfig controls webhook delivery
+type WebhookConfig struct {
+	Timeout time.Duration
+	// MaxAttempts is how often a delivery is tried before it is marked failed
+	MaxAttempts int
+	// RetryBase is the wait after the first failed attempt. It doubles
+	// with every further attempt, up to an hour.
+	RetryBase    time.Duration
+	PollInterval time.Duration
+	// AllowPrivateNetworks lets endpoints use http and loopback, private
+	// or link-local addresses. It is meant for local development only.
+	AllowPrivateNetworks bool
+}
+
+// GetWebhookConfig reads webhook configuration from environment variables
+func GetWebhookConfig() *WebhookConfig {
+	config := &WebhookConfig{
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+		RetryBase:    30 * time.Second,
+		PollInterval: 5 * time.Second,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("WEBHOOK_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		config.Timeout = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && v > 0 {
+		config.MaxAttempts = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("WEBHOOK_RETRY_BASE_SECONDS")); err == nil && v > 0 {
+		config.RetryBase = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS")); err == nil {
+		config.AllowPrivateNetworks = v
+	}
+
+	return config
+}
+
+// RetryDelay returns the wait before the next try after a failed attempt
+func (c *WebhookConfig) RetryDelay(attempt int) time.Duration {
+	delay := c.RetryBase
+	for i := 1; i < attempt && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// CheckURL reports why an endpoint URL cannot receive webhooks. Endpoints
+// must use https, and a host given as an IP address must be public. Host
+// names are checked again when a request is sent, since they can resolve
+// to an internal address later.
+func (c *WebhookConfig) CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("webhook URL must be absolute")
+	}
+	if u.User != nil {
+		return errors.New("webhook URL must not contain credentials")
+	}
+	if c.AllowPrivateNetworks {
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return errors.New("webhook URL must use https")
+		}
+		return nil
+	}
+	if u.Scheme != "https" {
+		return errors.New("webhook URL must use https")
+	}
+	if host := u.Hostname(); host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("webhook URL must not point to a private address")
+	} else if ip := net.ParseIP(host); ip != nil && !c.AllowedAddress(ip) {
+		return errors.New("webhook URL must not point to a private address")
+	}
+	return nil
+}
+
+// AllowedAddress reports whether webhooks may be sent to ip. Loopback,
+// private, link-local (including cloud metadata services), unspecified
+// and multicast addresses are refused.
+func (c *WebhookConfig) AllowedAddress(ip net.IP) bool {
+	if c.AllowPrivateNetworks {
+		return true
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip))
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// net.IP.IsPrivate does not cover
+var sharedAddressSpace = &net.IPNet{IP: net.IP{100, 64, 0, 0}, Mask: net.CIDRMask(10, 32)}
+
+// SignWebhook signs a webhook body: "sha256=" and the hex HMAC-SHA256 of
+// the timestamp, a dot and the body, keyed with the subscription secret
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature and timestamp headers of a
+// received webhook. Timestamps further than tolerance from now are
+// rejected so captured requests cannot be replayed later.
+func VerifyWebhookSignature(secret, timestamp, signature string, body []byte, tolerance time.Duration) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := time.Since(time.Unix(ts, 0))
+	if age > tolerance || age < -tolerance {
+		return false
+	}
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(SignWebhook(secret, ts, body)), []byte(signature))
+}
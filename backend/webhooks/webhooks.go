@@ -0,0 +1,314 @@
+// Package webhooks sends events to the endpoints of webhook subscriptions.
+// Events are stored as one delivery per subscription before they are sent,
+// so they survive restarts. Failed deliveries are retried with exponential
+// backoff; every request is signed with the subscription's secret.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/engine"
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventUserCreated is sent when a verification creates a new user
+const EventUserCreated = "user.created"
+
+// EventTypes lists the events a subscription can receive
+var EventTypes = []string{
+	engine.EventGenerated,
+	engine.EventDelivered,
+	engine.EventVerified,
+	engine.EventFailed,
+	engine.EventExpired,
+	EventUserCreated,
+}
+
+// IsEventType reports whether a subscription can ask for eventType. "*"
+// subscribes to every event.
+func IsEventType(eventType string) bool {
+	if eventType == "*" {
+		return true
+	}
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body of a webhook request
+type Payload struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Dispatcher stores and sends webhook deliveries
+type Dispatcher struct {
+	db     *gorm.DB
+	config *utils.WebhookConfig
+	client *http.Client
+	wake   chan struct{}
+}
+
+// NewDispatcher returns a dispatcher for the subscriptions in db
+func NewDispatcher(db *gorm.DB, config *utils.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		config: config,
+		client: newClient(config),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// newClient returns an HTTP client that only connects to addresses the
+// config allows. The address is checked after DNS resolution, so a host
+// name that resolves to an internal address is refused too. Redirects
+// are not followed.
+func newClient(config *utils.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: config.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !config.AllowedAddress(ip) {
+				return fmt.Errorf("webhook endpoint resolves to a private address (%s)", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: config.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: config.Timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Publish queues an event for every active subscription that wants it
+func (d *Dispatcher) Publish(eventType string, data map[string]interface{}) error {
+	var subscriptions []models.WebhookSubscription
+	if err := d.db.Where("disabled_at IS NULL").Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	payload := Payload{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscription.Wants(eventType) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: subscription.ID,
+			EventID:        payload.ID,
+			EventType:      eventType,
+			Payload:        string(body),
+			Status:         models.WebhookPending,
+			NextAttemptAt:  payload.CreatedAt,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := d.db.Create(&deliveries).Error; err != nil {
+		return err
+	}
+	d.notify()
+	return nil
+}
+
+// Replay sends a delivery's event again as a new delivery with the same
+// event ID
+func (d *Dispatcher) Replay(id string) (*models.WebhookDelivery, error) {
+	var original models.WebhookDelivery
+	if err := d.db.Where("id = ?", id).First(&original).Error; err != nil {
+		return nil, err
+	}
+
+	replay := models.WebhookDelivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         models.WebhookPending,
+		NextAttemptAt:  time.Now(),
+		ReplayOf:       original.ID,
+	}
+	if err := d.db.Create(&replay).Error; err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🔁 Webhook delivery %s replayed as %s\n", original.ID, replay.ID)
+	d.notify()
+	return &replay, nil
+}
+
+// Run sends due deliveries until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// notify wakes Run without waiting for the next poll
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sendDue sends a batch of deliveries whose next attempt is due
+func (d *Dispatcher) sendDue(ctx context.Context) {
+	var due []models.WebhookDelivery
+	d.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookPending, time.Now()).
+		Order("next_attempt_at").
+		Limit(20).
+		Find(&due)
+
+	var wg sync.WaitGroup
+	for i := range due {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}(&due[i])
+	}
+	wg.Wait()
+}
+
+// attempt sends one delivery and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	// Claim the attempt so another server polling the same table skips it
+	// until this one has had time to finish
+	claim := d.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.WebhookPending, delivery.Attempts).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": time.Now().Add(2 * d.config.Timeout),
+		})
+	if claim.Error != nil || claim.RowsAffected != 1 {
+		return
+	}
+	delivery.Attempts++
+
+	var subscription models.WebhookSubscription
+	err := d.db.WithContext(ctx).Where("id = ?", delivery.SubscriptionID).First(&subscription).Error
+	if err != nil || subscription.DisabledAt != nil {
+		d.db.Model(delivery).Updates(map[string]interface{}{
+			"status":     models.WebhookFailed,
+			"last_error": "subscription disabled",
+		})
+		return
+	}
+
+	status, err := d.send(ctx, &subscription, delivery)
+	now := time.Now()
+
+	if err == nil {
+		d.db.Model(delivery).Updates(map[string]interface{}{
+			"status":       models.WebhookDelivered,
+			"last_status":  status,
+			"last_error":   "",
+			"delivered_at": now,
+		})
+		fmt.Printf("📨 Webhook %s delivered to %s (attempt %d)\n", delivery.EventType, subscription.URL, delivery.Attempts)
+		return
+	}
+
+	updates := map[string]interface{}{
+		"last_status": status,
+		"last_error":  models.Truncate(err.Error(), 512),
+	}
+	if delivery.Attempts >= d.config.MaxAttempts {
+		updates["status"] = models.WebhookFailed
+		fmt.Printf("❌ Webhook %s to %s failed after %d attempts: %v\n", delivery.EventType, subscription.URL, delivery.Attempts, err)
+	} else {
+		updates["next_attempt_at"] = now.Add(d.config.RetryDelay(delivery.Attempts))
+		fmt.Printf("⚠️  Webhook %s to %s failed (attempt %d), retrying: %v\n", delivery.EventType, subscription.URL, delivery.Attempts, err)
+	}
+	d.db.Model(delivery).Updates(updates)
+}
+
+// send posts the signed payload and returns the response status. Any
+// status other than 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	// Subscriptions created before URLs were checked may not pass
+	if err := d.config.CheckURL(subscription.URL); err != nil {
+		return 0, err
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "otp-backend-webhooks/1.0")
+	req.Header.Set(utils.WebhookIDHeader, delivery.EventID)
+	req.Header.Set(utils.WebhookEventHeader, delivery.EventType)
+	req.Header.Set(utils.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(utils.WebhookSignatureHeader, utils.SignWebhook(subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,175 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Avinashkr000/otp-verification-system/backend/models"
+	"github.com/Avinashkr000/otp-verification-system/backend/utils"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDispatcher returns a running dispatcher on a fresh in-memory
+// database with one subscription to every event at url
+func newTestDispatcher(t *testing.T, config *utils.WebhookConfig, url string) (*Dispatcher, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	// Every connection to :memory: is a separate database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := db.Create(&models.WebhookSubscription{ID: "sub-1", URL: url, Secret: "secret", Events: "*"}).Error; err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+
+	d := NewDispatcher(db, config)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return d, db
+}
+
+// waitForStatus waits until the only delivery has the given status
+func waitForStatus(t *testing.T, db *gorm.DB, status string) models.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var delivery models.WebhookDelivery
+		db.First(&delivery)
+		if delivery.Status == status {
+			return delivery
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery status %q after %d attempts, want %q", delivery.Status, delivery.Attempts, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func testConfig(maxAttempts int) *utils.WebhookConfig {
+	return &utils.WebhookConfig{
+		Timeout:              2 * time.Second,
+		MaxAttempts:          maxAttempts,
+		RetryBase:            10 * time.Millisecond,
+		PollInterval:         10 * time.Millisecond,
+		AllowPrivateNetworks: true,
+	}
+}
+
+func TestDeliveriesAreSignedAndRetried(t *testing.T) {
+	var requests, unsigned atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !utils.VerifyWebhookSignature("secret", r.Header.Get(utils.WebhookTimestampHeader), r.Header.Get(utils.WebhookSignatureHeader), body, time.Minute) ||
+			r.Header.Get(utils.WebhookEventHeader) != "otp.verified" {
+			unsigned.Add(1)
+		}
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d, db := newTestDispatcher(t, testConfig(5), server.URL)
+	if err := d.Publish("otp.verified", map[string]interface{}{"otp_id": "otp-1"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	delivery := waitForStatus(t, db, models.WebhookDelivered)
+	if delivery.Attempts != 3 || delivery.LastStatus != http.StatusNoContent {
+		t.Errorf("delivered after %d attempts with status %d, want 3 and %d", delivery.Attempts, delivery.LastStatus, http.StatusNoContent)
+	}
+	if unsigned.Load() != 0 {
+		t.Errorf("%d requests without a valid signature", unsigned.Load())
+	}
+}
+
+func TestDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	d, db := newTestDispatcher(t, testConfig(2), server.URL)
+	d.Publish("otp.failed", map[string]interface{}{"otp_id": "otp-1"})
+
+	delivery := waitForStatus(t, db, models.WebhookFailed)
+	if delivery.Attempts != 2 || delivery.LastStatus != http.StatusBadGateway {
+		t.Errorf("failed after %d attempts with status %d, want 2 and %d", delivery.Attempts, delivery.LastStatus, http.StatusBadGateway)
+	}
+	// No attempt is made after the delivery failed
+	time.Sleep(50 * time.Millisecond)
+	if requests.Load() != 2 {
+		t.Errorf("%d requests, want 2", requests.Load())
+	}
+}
+
+// A host name is checked after it is resolved, so it cannot point the
+// dispatcher at an internal service
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a private address")
+	}))
+	defer server.Close()
+
+	client := newClient(&utils.WebhookConfig{Timeout: time.Second})
+	for _, url := range []string{server.URL, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)} {
+		resp, err := client.Post(url, "application/json", nil)
+		if err == nil {
+			resp.Body.Close()
+			t.Errorf("%s: request sent", url)
+		} else if !strings.Contains(err.Error(), "private address") {
+			t.Errorf("%s: %v", url, err)
+		}
+	}
+}
+
+func TestRedirectsAreNotFollowed(t *testing.T) {
+	var followed atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed.Store(true)
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	d, db := newTestDispatcher(t, testConfig(1), server.URL)
+	d.Publish("otp.generated", map[string]interface{}{"otp_id": "otp-1"})
+
+	if delivery := waitForStatus(t, db, models.WebhookFailed); delivery.LastStatus != http.StatusTemporaryRedirect {
+		t.Errorf("last status %d, want %d", delivery.LastStatus, http.StatusTemporaryRedirect)
+	}
+	if followed.Load() {
+		t.Error("redirect followed")
+	}
+}
@@ -3,6 +3,23 @@ CREATE DATABASE IF NOT EXISTS otp_system;
 USE otp_system;
 
 -- Drop tables if they exist (for clean setup)
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS idempotency_records;
+DROP TABLE IF EXISTS web_authn_ceremonies;
+DROP TABLE IF EXISTS passkeys;
+DROP TABLE IF EXISTS push_challenges;
+DROP TABLE IF EXISTS push_devices;
+DROP TABLE IF EXISTS risk_decision_logs;
+DROP TABLE IF EXISTS trusted_devices;
+DROP TABLE IF EXISTS authorization_codes;
+DROP TABLE IF EXISTS authorization_requests;
+DROP TABLE IF EXISTS o_auth_clients;
+DROP TABLE IF EXISTS recovery_attempts;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS authenticators;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
 DROP TABLE IF EXISTS otps;
 DROP TABLE IF EXISTS users;
 
@@ -11,18 +28,36 @@ CREATE TABLE otps (
     id VARCHAR(36) PRIMARY KEY,
     email VARCHAR(255) DEFAULT NULL,
     phone VARCHAR(20) DEFAULT NULL,
-    otp_code VARCHAR(6) NOT NULL,
+    otp_code VARCHAR(16) NOT NULL,
+    purpose VARCHAR(32) DEFAULT 'login',
     is_verified BOOLEAN DEFAULT FALSE,
     attempt_count INT DEFAULT 0,
+    max_attempts INT DEFAULT 3,
+    client_ip VARCHAR(45) DEFAULT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     expires_at TIMESTAMP NOT NULL,
     verified_at TIMESTAMP NULL,
+    transaction_hash VARCHAR(64) DEFAULT NULL,
+    transaction_summary VARCHAR(255) DEFAULT NULL,
+    magic_link_id VARCHAR(36) DEFAULT NULL,
+    return_url VARCHAR(512) DEFAULT NULL,
+    status_token_hash CHAR(64) DEFAULT NULL,
+    delivery_status VARCHAR(32) DEFAULT NULL,
+    superseded_by VARCHAR(36) DEFAULT NULL,
+    revoked_at TIMESTAMP NULL,
+    revoke_reason VARCHAR(64) DEFAULT NULL,
+    action VARCHAR(128) DEFAULT NULL,
+    expiry_reported BOOLEAN DEFAULT FALSE,
     
     -- Indexes for better query performance
     INDEX idx_email (email),
     INDEX idx_phone (phone),
     INDEX idx_created_at (created_at),
-    INDEX idx_is_verified (is_verified)
+    INDEX idx_is_verified (is_verified),
+    INDEX idx_purpose (purpose),
+    INDEX idx_client_ip (client_ip),
+    INDEX idx_magic_link_id (magic_link_id),
+    INDEX idx_expiry_reported (expiry_reported)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- Create Users table
@@ -40,6 +75,264 @@ CREATE TABLE users (
     INDEX idx_phone (phone)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
+-- Create Sessions table
+CREATE TABLE sessions (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    user_agent VARCHAR(255) DEFAULT NULL,
+    client_ip VARCHAR(45) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL,
+
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Refresh Tokens table (one row per rotation; only hashes are stored)
+CREATE TABLE refresh_tokens (
+    id VARCHAR(36) PRIMARY KEY,
+    session_id VARCHAR(36) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+
+    UNIQUE INDEX idx_token_hash (token_hash),
+    INDEX idx_session_id (session_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Authenticators table (TOTP/HOTP apps, one per user)
+CREATE TABLE authenticators (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    type VARCHAR(8) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    digits INT DEFAULT 6,
+    period INT DEFAULT 30,
+    counter BIGINT UNSIGNED DEFAULT 0,
+    last_used_step BIGINT DEFAULT 0,
+    failed_attempts INT DEFAULT 0,
+    locked_until TIMESTAMP NULL,
+    confirmed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    UNIQUE INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Recovery Codes table
+CREATE TABLE recovery_codes (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    code_hash VARCHAR(72) NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Recovery Attempts table (failed recovery code sign-ins)
+CREATE TABLE recovery_attempts (
+    id VARCHAR(36) PRIMARY KEY,
+    identifier VARCHAR(255) NOT NULL,
+    client_ip VARCHAR(45) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_identifier (identifier),
+    INDEX idx_client_ip (client_ip),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create OAuth Clients table (OpenID Connect relying parties)
+CREATE TABLE o_auth_clients (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(128) NOT NULL,
+    secret_hash CHAR(64) DEFAULT NULL,
+    redirect_uris TEXT NOT NULL,
+    public BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Authorization Requests table
+CREATE TABLE authorization_requests (
+    id VARCHAR(36) PRIMARY KEY,
+    client_id VARCHAR(36) NOT NULL,
+    redirect_uri VARCHAR(512) NOT NULL,
+    scope VARCHAR(255) DEFAULT NULL,
+    state VARCHAR(512) DEFAULT NULL,
+    nonce VARCHAR(255) DEFAULT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    otp_id VARCHAR(36) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Authorization Codes table (only hashes are stored)
+CREATE TABLE authorization_codes (
+    id VARCHAR(36) PRIMARY KEY,
+    code_hash CHAR(64) NOT NULL,
+    client_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    redirect_uri VARCHAR(512) NOT NULL,
+    scope VARCHAR(255) DEFAULT NULL,
+    nonce VARCHAR(255) DEFAULT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    auth_time TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    session_id VARCHAR(36) DEFAULT NULL,
+    revoked_at TIMESTAMP NULL,
+
+    UNIQUE INDEX idx_code_hash (code_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Trusted Devices table (only token hashes are stored)
+CREATE TABLE trusted_devices (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    name VARCHAR(255) DEFAULT NULL,
+    client_ip VARCHAR(45) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL,
+
+    UNIQUE INDEX idx_token_hash (token_hash),
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Risk Decision Logs table (step-up audit log)
+CREATE TABLE risk_decision_logs (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    action VARCHAR(64) DEFAULT NULL,
+    client_ip VARCHAR(45) DEFAULT NULL,
+    country VARCHAR(2) DEFAULT NULL,
+    user_agent VARCHAR(255) DEFAULT NULL,
+    challenge_required BOOLEAN DEFAULT FALSE,
+    score INT DEFAULT 0,
+    reasons TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_user_id (user_id),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Push Devices table
+CREATE TABLE push_devices (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    name VARCHAR(128) DEFAULT NULL,
+    public_key VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Push Challenges table
+CREATE TABLE push_challenges (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    otp_id VARCHAR(36) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    purpose VARCHAR(32) DEFAULT NULL,
+    summary VARCHAR(255) DEFAULT NULL,
+    client_ip VARCHAR(45) DEFAULT NULL,
+    device_id VARCHAR(36) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    responded_at TIMESTAMP NULL,
+    completed_at TIMESTAMP NULL,
+
+    UNIQUE INDEX idx_otp_id (otp_id),
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Passkeys table (WebAuthn credentials)
+CREATE TABLE passkeys (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    credential_id VARCHAR(255) NOT NULL,
+    public_key BLOB NOT NULL,
+    attestation_type VARCHAR(32) DEFAULT NULL,
+    aa_guid BLOB,
+    sign_count INT UNSIGNED DEFAULT 0,
+    transports VARCHAR(128) DEFAULT NULL,
+    backup_eligible BOOLEAN DEFAULT FALSE,
+    backup_state BOOLEAN DEFAULT FALSE,
+    name VARCHAR(128) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NULL,
+
+    UNIQUE INDEX idx_credential_id (credential_id),
+    INDEX idx_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create WebAuthn Ceremonies table (state between begin and finish)
+CREATE TABLE web_authn_ceremonies (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) DEFAULT NULL,
+    kind VARCHAR(16) NOT NULL,
+    session_data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Idempotency Records table (sealed responses replayed for Idempotency-Key retries)
+CREATE TABLE idempotency_records (
+    id CHAR(64) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT DEFAULT 0,
+    content_type VARCHAR(128) DEFAULT NULL,
+    body BLOB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP NULL,
+
+    INDEX idx_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Webhook Subscriptions table
+CREATE TABLE webhook_subscriptions (
+    id VARCHAR(36) PRIMARY KEY,
+    url VARCHAR(512) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT NOT NULL,
+    description VARCHAR(255) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    disabled_at TIMESTAMP NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create Webhook Deliveries table
+CREATE TABLE webhook_deliveries (
+    id VARCHAR(36) PRIMARY KEY,
+    subscription_id VARCHAR(36) NOT NULL,
+    event_id VARCHAR(36) NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT DEFAULT 0,
+    next_attempt_at TIMESTAMP NULL,
+    last_status INT DEFAULT NULL,
+    last_error VARCHAR(512) DEFAULT NULL,
+    replay_of VARCHAR(36) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP NULL,
+
+    INDEX idx_subscription_id (subscription_id),
+    INDEX idx_event_id (event_id),
+    INDEX idx_status (status),
+    INDEX idx_next_attempt_at (next_attempt_at),
+    INDEX idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
 -- Insert some sample data for testing (optional)
 -- INSERT INTO users (id, email, phone, is_email_verified, is_phone_verified) 
 -- VALUES 
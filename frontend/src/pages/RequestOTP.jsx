@@ -16,10 +16,12 @@ const RequestOTP = ({ onSuccess }) => {
     setLoading(true);
 
     try {
+      // Signup codes create the account on first verification and sign in
+      // returning users
       const payload =
         method === 'email'
-          ? { email: formData.email }
-          : { phone: formData.phone };
+          ? { email: formData.email, purpose: 'signup' }
+          : { phone: formData.phone, purpose: 'signup' };
 
       const response = await axios.post(
         'http://localhost:8080/api/otp/generate',
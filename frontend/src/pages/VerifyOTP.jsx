@@ -72,6 +72,7 @@ const VerifyOTP = ({ otpData, onSuccess, onBack }) => {
       const response = await axios.post('http://localhost:8080/api/otp/verify', {
         otp_id: otpData.otp_id,
         otp_code: otpCode,
+        purpose: otpData.purpose,
       });
 
       if (response.data.success) {